@@ -0,0 +1,208 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.aporeto.io/elemental"
+	"go.uber.org/zap"
+)
+
+// The headers that are always redacted in the access logs.
+var accessLogDefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-API-Key"}
+
+// The headers, attributes and parameters whose names contain
+// one of these words are always redacted in the access logs.
+var accessLogDefaultRedactedWords = []string{"token", "password", "secret", "key"}
+
+// an accessLogger logs the requests handled by the rest server.
+type accessLogger struct {
+	sampleRate         float64
+	slowThreshold      time.Duration
+	slowThresholds     map[string]time.Duration
+	redactedHeaders    map[string]struct{}
+	redactedAttributes map[string]struct{}
+	logAttributes      bool
+	random             func() float64
+}
+
+// newAccessLogger returns a new accessLogger configured from the given config.
+func newAccessLogger(cfg config) *accessLogger {
+
+	l := &accessLogger{
+		sampleRate:         cfg.accessLog.sampleRate,
+		slowThreshold:      cfg.accessLog.slowThreshold,
+		slowThresholds:     make(map[string]time.Duration, len(cfg.accessLog.slowThresholds)),
+		redactedHeaders:    map[string]struct{}{},
+		redactedAttributes: make(map[string]struct{}, len(cfg.accessLog.redactedAttributes)),
+		logAttributes:      cfg.accessLog.attributes,
+		random:             rand.Float64,
+	}
+
+	for identity, threshold := range cfg.accessLog.slowThresholds {
+		l.slowThresholds[identity.Name] = threshold
+	}
+
+	for _, h := range append(accessLogDefaultRedactedHeaders, cfg.accessLog.redactedHeaders...) {
+		l.redactedHeaders[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+
+	for _, a := range cfg.accessLog.redactedAttributes {
+		l.redactedAttributes[strings.ToLower(a)] = struct{}{}
+	}
+
+	return l
+}
+
+// log logs the request held by the given context if it is slower
+// than the threshold of its identity, or if it has been sampled.
+func (l *accessLogger) log(method string, path string, ctx *bcontext, code int, size int, duration time.Duration) {
+
+	threshold := l.slowThreshold
+	if t, ok := l.slowThresholds[ctx.request.Identity.Name]; ok {
+		threshold = t
+	}
+
+	slow := threshold > 0 && duration >= threshold
+	if !slow && (l.sampleRate <= 0 || l.random() >= l.sampleRate) {
+		return
+	}
+
+	req := ctx.request
+
+	fields := []zap.Field{
+		zap.String("method", method),
+		zap.String("path", path),
+		zap.String("identity", req.Identity.Name),
+		zap.String("operation", string(req.Operation)),
+		zap.Int("version", req.Version),
+		zap.String("namespace", req.Namespace),
+		zap.String("object-id", req.ObjectID),
+		zap.String("request-id", req.RequestID),
+		zap.String("client-ip", req.ClientIP),
+		zap.Int("code", code),
+		zap.Int("size", size),
+		zap.Duration("duration", duration),
+		zap.Duration("authentication", ctx.timings.authentication),
+		zap.Duration("authorization", ctx.timings.authorization),
+		zap.Duration("processor", ctx.timings.processor),
+		zap.Duration("encoding", ctx.timings.encoding),
+		zap.Any("headers", l.headers(req.Headers)),
+		zap.Any("parameters", l.parameters(req.Parameters)),
+	}
+
+	if traceID := extractTraceID(ctx.ctx); traceID != "unknown" {
+		fields = append(fields, zap.String("trace-id", traceID))
+	}
+
+	if l.logAttributes {
+		if attributes := l.attributes(req.Data); attributes != nil {
+			fields = append(fields, zap.Any("attributes", attributes))
+		}
+	}
+
+	if slow {
+		zap.L().Warn("Slow request", append(fields, zap.Duration("threshold", threshold))...)
+		return
+	}
+
+	zap.L().Info("Request", fields...)
+}
+
+// headers returns the given headers with the redacted ones removed.
+func (l *accessLogger) headers(headers http.Header) map[string][]string {
+
+	out := make(map[string][]string, len(headers))
+
+	for k, v := range headers {
+		if _, ok := l.redactedHeaders[http.CanonicalHeaderKey(k)]; ok || hasRedactedWord(k) {
+			out[k] = []string{AuditRedacted}
+			continue
+		}
+		out[k] = v
+	}
+
+	return out
+}
+
+// parameters returns the values of the given
+// parameters with the redacted ones removed.
+func (l *accessLogger) parameters(parameters elemental.Parameters) map[string][]interface{} {
+
+	out := make(map[string][]interface{}, len(parameters))
+
+	for k, p := range parameters {
+		if l.isRedacted(k) {
+			out[k] = []interface{}{AuditRedacted}
+			continue
+		}
+		out[k] = p.Values()
+	}
+
+	return out
+}
+
+// attributes returns the top level attributes of the given
+// JSON data with the redacted ones removed, or nil if the
+// data is not a JSON object.
+func (l *accessLogger) attributes(data []byte) map[string]interface{} {
+
+	if len(data) == 0 || data[0] != '{' {
+		return nil
+	}
+
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+
+	for k := range out {
+		if l.isRedacted(k) {
+			out[k] = AuditRedacted
+		}
+	}
+
+	return out
+}
+
+// isRedacted returns true if the attribute or
+// parameter with the given name must be redacted.
+func (l *accessLogger) isRedacted(name string) bool {
+
+	name = strings.ToLower(name)
+
+	if _, ok := l.redactedAttributes[name]; ok {
+		return true
+	}
+
+	return hasRedactedWord(name)
+}
+
+// hasRedactedWord returns true if the given name contains
+// one of the words that are always redacted.
+func hasRedactedWord(name string) bool {
+
+	name = strings.ToLower(name)
+
+	for _, w := range accessLogDefaultRedactedWords {
+		if strings.Contains(name, w) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,252 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newAccessLogTestContext() *bcontext {
+
+	req := elemental.NewRequest()
+	req.Identity = elemental.MakeIdentity("list", "lists")
+	req.Operation = elemental.OperationCreate
+	req.Headers.Set("Authorization", "Bearer secret")
+	req.Headers.Set("X-Secret", "secret")
+	req.Headers.Set("X-Other", "value")
+	req.Parameters = elemental.Parameters{
+		"token": elemental.NewParameter(elemental.ParameterTypeString, "secret"),
+		"email": elemental.NewParameter(elemental.ParameterTypeString, "a@b.c"),
+		"q":     elemental.NewParameter(elemental.ParameterTypeString, "value"),
+	}
+	req.Data = []byte(`{"name":"a","password":"secret","apiToken":"secret","email":"a@b.c"}`)
+
+	ctx := newContext(context.Background(), req)
+	ctx.timings.authentication = 1 * time.Millisecond
+	ctx.timings.authorization = 2 * time.Millisecond
+	ctx.timings.processor = 3 * time.Millisecond
+	ctx.timings.encoding = 4 * time.Millisecond
+
+	return ctx
+}
+
+func TestAccessLogger_log(t *testing.T) {
+
+	Convey("Given I have an access logger", t, func() {
+
+		zc, obs := observer.New(zapcore.InfoLevel)
+		zap.ReplaceGlobals(zap.New(zc))
+		defer zap.ReplaceGlobals(zap.NewNop())
+
+		cfg := config{}
+		cfg.accessLog.sampleRate = 0.5
+		cfg.accessLog.slowThreshold = time.Second
+		cfg.accessLog.slowThresholds = map[elemental.Identity]time.Duration{
+			elemental.MakeIdentity("list", "lists"): 100 * time.Millisecond,
+		}
+		cfg.accessLog.redactedHeaders = []string{"x-secret"}
+		cfg.accessLog.redactedAttributes = []string{"Email"}
+		cfg.accessLog.attributes = true
+
+		l := newAccessLogger(cfg)
+
+		Convey("When I log a fast request that is not sampled", func() {
+
+			l.random = func() float64 { return 0.6 }
+			l.log(http.MethodPost, "/lists", newAccessLogTestContext(), http.StatusOK, 42, 10*time.Millisecond)
+
+			Convey("Then nothing should be logged", func() {
+				So(obs.Len(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I log a fast request that is sampled", func() {
+
+			l.random = func() float64 { return 0.4 }
+			l.log(http.MethodPost, "/lists", newAccessLogTestContext(), http.StatusOK, 42, 10*time.Millisecond)
+
+			Convey("Then the request should be logged", func() {
+				logs := obs.AllUntimed()
+				So(len(logs), ShouldEqual, 1)
+				So(logs[0].Level, ShouldEqual, zapcore.InfoLevel)
+				So(logs[0].Message, ShouldEqual, "Request")
+
+				fields := logs[0].ContextMap()
+				So(fields["method"], ShouldEqual, http.MethodPost)
+				So(fields["path"], ShouldEqual, "/lists")
+				So(fields["identity"], ShouldEqual, "list")
+				So(fields["operation"], ShouldEqual, "create")
+				So(fields["code"], ShouldEqual, 200)
+				So(fields["size"], ShouldEqual, 42)
+				So(fields["duration"], ShouldEqual, 10*time.Millisecond)
+				So(fields["processor"], ShouldEqual, 3*time.Millisecond)
+			})
+
+			Convey("Then the headers and the attributes should be redacted", func() {
+				fields := obs.AllUntimed()[0].ContextMap()
+
+				So(fields["headers"], ShouldResemble, map[string][]string{
+					"Authorization": {AuditRedacted},
+					"X-Secret":      {AuditRedacted},
+					"X-Other":       {"value"},
+				})
+				So(fields["parameters"], ShouldResemble, map[string][]interface{}{
+					"token": {AuditRedacted},
+					"email": {AuditRedacted},
+					"q":     {"value"},
+				})
+				So(fields["attributes"], ShouldResemble, map[string]interface{}{
+					"name":     "a",
+					"password": AuditRedacted,
+					"apiToken": AuditRedacted,
+					"email":    AuditRedacted,
+				})
+			})
+		})
+
+		Convey("When I log a slow request that is not sampled", func() {
+
+			l.random = func() float64 { return 0.6 }
+			l.log(http.MethodPost, "/lists", newAccessLogTestContext(), http.StatusOK, 42, 200*time.Millisecond)
+
+			Convey("Then the request should be logged as slow using the identity threshold", func() {
+				logs := obs.AllUntimed()
+				So(len(logs), ShouldEqual, 1)
+				So(logs[0].Level, ShouldEqual, zapcore.WarnLevel)
+				So(logs[0].Message, ShouldEqual, "Slow request")
+
+				fields := logs[0].ContextMap()
+				So(fields["threshold"], ShouldEqual, 100*time.Millisecond)
+				So(fields["authentication"], ShouldEqual, 1*time.Millisecond)
+				So(fields["authorization"], ShouldEqual, 2*time.Millisecond)
+				So(fields["processor"], ShouldEqual, 3*time.Millisecond)
+				So(fields["encoding"], ShouldEqual, 4*time.Millisecond)
+			})
+		})
+
+		Convey("When I log a request of another identity below the default threshold", func() {
+
+			ctx := newAccessLogTestContext()
+			ctx.request.Identity = elemental.MakeIdentity("task", "tasks")
+
+			l.random = func() float64 { return 0.6 }
+			l.log(http.MethodGet, "/tasks", ctx, http.StatusOK, 42, 200*time.Millisecond)
+
+			Convey("Then nothing should be logged", func() {
+				So(obs.Len(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I log a request with data that is not a JSON object", func() {
+
+			ctx := newAccessLogTestContext()
+			ctx.request.Data = []byte(`[1, 2]`)
+
+			l.random = func() float64 { return 0.4 }
+			l.log(http.MethodPost, "/lists", ctx, http.StatusOK, 42, 10*time.Millisecond)
+
+			Convey("Then the attributes should not be logged", func() {
+				_, ok := obs.AllUntimed()[0].ContextMap()["attributes"]
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When I log a request with the attributes logging disabled", func() {
+
+			l.logAttributes = false
+			l.random = func() float64 { return 0.4 }
+			l.log(http.MethodPost, "/lists", newAccessLogTestContext(), http.StatusOK, 42, 10*time.Millisecond)
+
+			Convey("Then the attributes should not be logged", func() {
+				_, ok := obs.AllUntimed()[0].ContextMap()["attributes"]
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("Then the parameters should still be logged", func() {
+				_, ok := obs.AllUntimed()[0].ContextMap()["parameters"]
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestAccessLogger_headers(t *testing.T) {
+
+	Convey("Given I have an access logger with no redaction configured", t, func() {
+
+		l := newAccessLogger(config{})
+
+		Convey("When I get the headers of a request carrying credentials", func() {
+
+			h := http.Header{}
+			h.Set("X-API-Key", "secret")
+			h.Set("Proxy-Authorization", "Basic secret")
+			h.Set("X-Auth-Token", "secret")
+			h.Set("X-Request-Id", "value")
+
+			out := l.headers(h)
+
+			Convey("Then the credentials should be redacted", func() {
+				So(out, ShouldResemble, map[string][]string{
+					"X-Api-Key":           {AuditRedacted},
+					"Proxy-Authorization": {AuditRedacted},
+					"X-Auth-Token":        {AuditRedacted},
+					"X-Request-Id":        {"value"},
+				})
+			})
+		})
+	})
+}
+
+func TestBcontext_timings(t *testing.T) {
+
+	Convey("Given I have a context", t, func() {
+
+		ctx := newContext(context.Background(), elemental.NewRequest())
+
+		Convey("When I call process", func() {
+
+			var called Context
+			err := ctx.process(func(c Context) error {
+				called = c
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+
+			Convey("Then the processor should have been called and timed", func() {
+				So(err, ShouldBeNil)
+				So(called, ShouldEqual, ctx)
+				So(ctx.timings.processor, ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+			})
+		})
+
+		Convey("When I check the authentication and the authorization", func() {
+
+			So(CheckAuthentication([]RequestAuthenticator{&mockAuth{action: AuthActionOK}}, ctx), ShouldBeNil)
+			So(CheckAuthorization([]Authorizer{&mockAuth{action: AuthActionOK}}, ctx), ShouldBeNil)
+
+			Convey("Then the timings should be recorded", func() {
+				So(ctx.timings.authentication, ShouldBeGreaterThan, 0)
+				So(ctx.timings.authorization, ShouldBeGreaterThan, 0)
+			})
+		})
+	})
+}
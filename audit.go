@@ -0,0 +1,221 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.aporeto.io/elemental"
+)
+
+// AuditRedacted is the value used to replace
+// redacted information in an AuditRecord.
+const AuditRedacted = "[redacted]"
+
+// An AuditRecord is the structured representation
+// of an audited request.
+type AuditRecord struct {
+	Time      time.Time           `json:"time" msgpack:"time"`
+	Claims    []string            `json:"claims,omitempty" msgpack:"claims,omitempty"`
+	ClientIP  string              `json:"clientIP,omitempty" msgpack:"clientIP,omitempty"`
+	Namespace string              `json:"namespace,omitempty" msgpack:"namespace,omitempty"`
+	Identity  string              `json:"identity" msgpack:"identity"`
+	Operation elemental.Operation `json:"operation" msgpack:"operation"`
+	ObjectID  string              `json:"objectID,omitempty" msgpack:"objectID,omitempty"`
+	Status    int                 `json:"status" msgpack:"status"`
+	Error     string              `json:"error,omitempty" msgpack:"error,omitempty"`
+	Duration  string              `json:"duration" msgpack:"duration"`
+	TraceID   string              `json:"traceID,omitempty" msgpack:"traceID,omitempty"`
+}
+
+// NewAuditRecord returns the AuditRecord of the request
+// held by the given Context, that ended with the given error.
+func NewAuditRecord(ctx Context, err error) AuditRecord {
+
+	now := time.Now()
+	req := ctx.Request()
+
+	r := AuditRecord{
+		Time:      now,
+		Claims:    ctx.Claims(),
+		ClientIP:  req.ClientIP,
+		Namespace: req.Namespace,
+		Identity:  req.Identity.Name,
+		Operation: req.Operation,
+		ObjectID:  req.ObjectID,
+		Status:    ctx.StatusCode(),
+	}
+
+	if bctx, ok := ctx.(*bcontext); ok && !bctx.startTime.IsZero() {
+		r.Duration = now.Sub(bctx.startTime).String()
+	}
+
+	if traceID := extractTraceID(ctx.Context()); traceID != "unknown" {
+		r.TraceID = traceID
+	}
+
+	if err != nil {
+		errs := elemental.NewErrors(err)
+		r.Status = errs.Code()
+		r.Error = errs.Error()
+	}
+
+	if r.Status == 0 {
+		if ctx.OutputData() == nil {
+			r.Status = http.StatusNoContent
+		} else {
+			r.Status = http.StatusOK
+		}
+	}
+
+	return r
+}
+
+// An AuditRedactor removes sensitive information
+// from an AuditRecord before it is written.
+type AuditRedactor func(*AuditRecord)
+
+// AuditRedactClaims returns an AuditRedactor that redacts the
+// values of the claims with one of the given keys.
+func AuditRedactClaims(keys ...string) AuditRedactor {
+
+	redacted := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		redacted[k] = struct{}{}
+	}
+
+	return func(r *AuditRecord) {
+
+		if len(r.Claims) == 0 {
+			return
+		}
+
+		claims := make([]string, len(r.Claims))
+
+		for i, claim := range r.Claims {
+
+			claims[i] = claim
+
+			parts := strings.SplitN(claim, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			if _, ok := redacted[parts[0]]; ok {
+				claims[i] = parts[0] + "=" + AuditRedacted
+			}
+		}
+
+		r.Claims = claims
+	}
+}
+
+// AuditRedactClientIP returns an AuditRedactor
+// that redacts the client IP.
+func AuditRedactClientIP() AuditRedactor {
+
+	return func(r *AuditRecord) {
+		if r.ClientIP != "" {
+			r.ClientIP = AuditRedacted
+		}
+	}
+}
+
+// AuditRedactError returns an AuditRedactor that redacts the
+// error of the records, keeping only their status code.
+func AuditRedactError() AuditRedactor {
+
+	return func(r *AuditRecord) {
+		if r.Error != "" {
+			r.Error = AuditRedacted
+		}
+	}
+}
+
+// An AuditOption represents an option to the
+// Auditers returned by NewFileAuditer and NewPubSubAuditer.
+type AuditOption func(*auditConfig)
+
+type auditConfig struct {
+	redactors      []AuditRedactor
+	fileMaxSize    int64
+	fileMaxBackups int
+	bufferSize     int
+}
+
+func newAuditConfig() auditConfig {
+	return auditConfig{
+		fileMaxSize:    100 * 1024 * 1024,
+		fileMaxBackups: 5,
+		bufferSize:     1024,
+	}
+}
+
+// AuditOptRedactors sets the redactors to apply to
+// every AuditRecord before it is written.
+func AuditOptRedactors(redactors ...AuditRedactor) AuditOption {
+	return func(c *auditConfig) {
+		c.redactors = append(c.redactors, redactors...)
+	}
+}
+
+// AuditOptFileMaxSize sets the size in bytes after which the file used by
+// the file Auditer is rotated. The default is 100MiB.
+// This function will panic if size is not positive.
+func AuditOptFileMaxSize(size int64) AuditOption {
+
+	if size <= 0 {
+		panic("size must be positive")
+	}
+
+	return func(c *auditConfig) {
+		c.fileMaxSize = size
+	}
+}
+
+// AuditOptFileMaxBackups sets the number of rotated files kept by the
+// file Auditer. The default is 5. If set to 0, all rotated files are kept.
+// This function will panic if n is negative.
+func AuditOptFileMaxBackups(n int) AuditOption {
+
+	if n < 0 {
+		panic("n must not be negative")
+	}
+
+	return func(c *auditConfig) {
+		c.fileMaxBackups = n
+	}
+}
+
+// AuditOptPubSubBufferSize sets the number of records the pubsub
+// Auditer can buffer before dropping new ones. The default is 1024.
+// This function will panic if size is not positive.
+func AuditOptPubSubBufferSize(size int) AuditOption {
+
+	if size <= 0 {
+		panic("size must be positive")
+	}
+
+	return func(c *auditConfig) {
+		c.bufferSize = size
+	}
+}
+
+// redact applies the configured redactors to the given record.
+func (c auditConfig) redact(r *AuditRecord) {
+
+	for _, redactor := range c.redactors {
+		redactor(r)
+	}
+}
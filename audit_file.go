@@ -0,0 +1,178 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// A FileAuditer is an Auditer that writes the AuditRecords
+// as JSON lines into a file, rotated when it grows too large.
+type FileAuditer struct {
+	path   string
+	cfg    auditConfig
+	file   *os.File
+	size   int64
+	closed bool
+	lock   sync.Mutex
+}
+
+// NewFileAuditer returns a new FileAuditer writing into the file at
+// the given path. The file is created if it does not exist.
+func NewFileAuditer(path string, options ...AuditOption) (*FileAuditer, error) {
+
+	cfg := newAuditConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	a := &FileAuditer{
+		path: path,
+		cfg:  cfg,
+	}
+
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Audit writes the AuditRecord of the given Context.
+func (a *FileAuditer) Audit(ctx Context, err error) {
+
+	r := NewAuditRecord(ctx, err)
+	a.cfg.redact(&r)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		zap.L().Error("Unable to encode audit record", zap.Error(err))
+		return
+	}
+
+	data = append(data, '\n')
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.closed {
+		return
+	}
+
+	if a.file != nil && a.size > 0 && a.size+int64(len(data)) > a.cfg.fileMaxSize {
+		if err := a.rotate(); err != nil {
+			zap.L().Error("Unable to rotate audit file", zap.String("path", a.path), zap.Error(err))
+		}
+	}
+
+	// The file may have been left closed by a failed rotation.
+	if a.file == nil {
+		if err := a.open(); err != nil {
+			zap.L().Error("Unable to open audit file", zap.String("path", a.path), zap.Error(err))
+			return
+		}
+	}
+
+	n, err := a.file.Write(data)
+	a.size += int64(n)
+	if err != nil {
+		zap.L().Error("Unable to write audit record", zap.String("path", a.path), zap.Error(err))
+	}
+}
+
+// Close closes the underlying file.
+// Records audited after Close are dropped.
+func (a *FileAuditer) Close() error {
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.closed = true
+
+	if a.file == nil {
+		return nil
+	}
+
+	err := a.file.Close()
+	a.file = nil
+
+	return err
+}
+
+// open opens the file for appending.
+func (a *FileAuditer) open() error {
+
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close() // nolint: errcheck
+		return err
+	}
+
+	a.file = f
+	a.size = info.Size()
+
+	return nil
+}
+
+// rotate moves the current file aside, opens a new
+// one and removes the extra rotated files.
+func (a *FileAuditer) rotate() error {
+
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	a.file = nil
+
+	backup := a.path + "." + time.Now().UTC().Format("2006-01-02T15-04-05.000000000")
+	if err := os.Rename(a.path, backup); err != nil {
+		return err
+	}
+
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	if a.cfg.fileMaxBackups == 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(a.path + ".*")
+	if err != nil {
+		return err
+	}
+
+	if len(backups) <= a.cfg.fileMaxBackups {
+		return nil
+	}
+
+	sort.Strings(backups)
+
+	for _, b := range backups[:len(backups)-a.cfg.fileMaxBackups] {
+		if err := os.Remove(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func readAuditRecords(path string) []AuditRecord {
+
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close() // nolint: errcheck
+
+	var records []AuditRecord
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := AuditRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			panic(err)
+		}
+		records = append(records, r)
+	}
+
+	return records
+}
+
+func TestFileAuditer(t *testing.T) {
+
+	Convey("Given I have a file auditer", t, func() {
+
+		dir, err := ioutil.TempDir("", "bahamut-audit")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		path := filepath.Join(dir, "audit.log")
+
+		a, err := NewFileAuditer(path, AuditOptRedactors(AuditRedactClaims("@auth:token")))
+		So(err, ShouldBeNil)
+		defer a.Close() // nolint: errcheck
+
+		Convey("When I audit some requests", func() {
+
+			a.Audit(newAuditTestContext(), nil)
+			a.Audit(newAuditTestContext(), nil)
+
+			Convey("Then the records should be written and redacted", func() {
+				records := readAuditRecords(path)
+				So(len(records), ShouldEqual, 2)
+				So(records[0].Identity, ShouldEqual, "list")
+				So(records[0].Claims, ShouldResemble, []string{"@auth:realm=certificate", "@auth:token=" + AuditRedacted})
+			})
+		})
+
+		Convey("When I close it and audit a request", func() {
+
+			So(a.Close(), ShouldBeNil)
+			a.Audit(newAuditTestContext(), nil)
+
+			Convey("Then the record should be dropped", func() {
+				So(len(readAuditRecords(path)), ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given I have a file auditer with a small max size", t, func() {
+
+		dir, err := ioutil.TempDir("", "bahamut-audit")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		path := filepath.Join(dir, "audit.log")
+
+		a, err := NewFileAuditer(path, AuditOptFileMaxSize(1), AuditOptFileMaxBackups(2))
+		So(err, ShouldBeNil)
+		defer a.Close() // nolint: errcheck
+
+		Convey("When I audit some requests", func() {
+
+			for i := 0; i < 5; i++ {
+				a.Audit(newAuditTestContext(), nil)
+			}
+
+			Convey("Then the files should have been rotated", func() {
+				So(len(readAuditRecords(path)), ShouldEqual, 1)
+
+				backups, err := filepath.Glob(path + ".*")
+				So(err, ShouldBeNil)
+				So(len(backups), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given I create a file auditer in a directory that does not exist", t, func() {
+
+		a, err := NewFileAuditer("/does/not/exist/audit.log")
+
+		Convey("Then err should not be nil", func() {
+			So(err, ShouldNotBeNil)
+			So(a, ShouldBeNil)
+		})
+	})
+}
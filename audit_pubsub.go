@@ -0,0 +1,123 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// A PubSubAuditer is an Auditer that asynchronously
+// publishes the AuditRecords to a PubSubClient topic.
+//
+// The records are buffered and new records are
+// dropped when the buffer is full.
+type PubSubAuditer struct {
+	pubsub  PubSubClient
+	topic   string
+	cfg     auditConfig
+	records chan AuditRecord
+	done    chan struct{}
+	closed  bool
+	lock    sync.RWMutex
+}
+
+// NewPubSubAuditer returns a new PubSubAuditer publishing
+// the AuditRecords to the given topic using the given PubSubClient.
+func NewPubSubAuditer(pubsub PubSubClient, topic string, options ...AuditOption) *PubSubAuditer {
+
+	if pubsub == nil {
+		panic("pubsub must not be nil")
+	}
+
+	if topic == "" {
+		panic("topic must not be empty")
+	}
+
+	cfg := newAuditConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	a := &PubSubAuditer{
+		pubsub:  pubsub,
+		topic:   topic,
+		cfg:     cfg,
+		records: make(chan AuditRecord, cfg.bufferSize),
+		done:    make(chan struct{}),
+	}
+
+	go a.publish()
+
+	return a
+}
+
+// Audit enqueues the AuditRecord of the given Context for publication.
+func (a *PubSubAuditer) Audit(ctx Context, err error) {
+
+	r := NewAuditRecord(ctx, err)
+	a.cfg.redact(&r)
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	if a.closed {
+		return
+	}
+
+	select {
+	case a.records <- r:
+	default:
+		zap.L().Warn("Audit buffer is full. Record dropped",
+			zap.String("topic", a.topic),
+			zap.String("identity", r.Identity),
+			zap.String("operation", string(r.Operation)),
+		)
+	}
+}
+
+// Close stops the PubSubAuditer, after having published the buffered records.
+// Records audited after Close are dropped.
+func (a *PubSubAuditer) Close() error {
+
+	a.lock.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.records)
+	}
+	a.lock.Unlock()
+
+	<-a.done
+
+	return nil
+}
+
+// publish publishes the records until the records channel is closed.
+func (a *PubSubAuditer) publish() {
+
+	defer close(a.done)
+
+	for r := range a.records {
+
+		publication := NewPublication(a.topic)
+
+		if err := publication.Encode(r); err != nil {
+			zap.L().Error("Unable to encode audit record", zap.Error(err))
+			continue
+		}
+
+		if err := a.pubsub.Publish(publication); err != nil {
+			zap.L().Error("Unable to publish audit record", zap.String("topic", a.topic), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type blockingPubSubServer struct {
+	mockPubSubServer
+	unblock chan struct{}
+}
+
+func (p *blockingPubSubServer) Publish(publication *Publication, opts ...PubSubOptPublish) error {
+	<-p.unblock
+	return p.mockPubSubServer.Publish(publication, opts...)
+}
+
+func TestNewPubSubAuditer(t *testing.T) {
+
+	Convey("Calling NewPubSubAuditer with a nil pubsub should panic", t, func() {
+		So(func() { NewPubSubAuditer(nil, "audit") }, ShouldPanicWith, "pubsub must not be nil")
+	})
+
+	Convey("Calling NewPubSubAuditer with an empty topic should panic", t, func() {
+		So(func() { NewPubSubAuditer(&mockPubSubServer{}, "") }, ShouldPanicWith, "topic must not be empty")
+	})
+}
+
+func TestPubSubAuditer(t *testing.T) {
+
+	Convey("Given I have a pubsub auditer", t, func() {
+
+		pubsub := &mockPubSubServer{}
+		a := NewPubSubAuditer(pubsub, "audit", AuditOptRedactors(AuditRedactClientIP()))
+
+		Convey("When I audit some requests and close it", func() {
+
+			a.Audit(newAuditTestContext(), nil)
+			a.Audit(newAuditTestContext(), fmt.Errorf("boom"))
+
+			So(a.Close(), ShouldBeNil)
+
+			Convey("Then the records should be published and redacted", func() {
+				So(len(pubsub.publications), ShouldEqual, 2)
+				So(pubsub.publications[0].Topic, ShouldEqual, "audit")
+
+				r := AuditRecord{}
+				So(pubsub.publications[1].Decode(&r), ShouldBeNil)
+				So(r.Identity, ShouldEqual, "list")
+				So(r.ClientIP, ShouldEqual, AuditRedacted)
+				So(r.Status, ShouldEqual, 500)
+			})
+
+			Convey("When I audit a request after closing it", func() {
+
+				a.Audit(newAuditTestContext(), nil)
+
+				Convey("Then it should be dropped", func() {
+					So(a.Close(), ShouldBeNil)
+					So(len(pubsub.publications), ShouldEqual, 2)
+				})
+			})
+		})
+	})
+
+	Convey("Given I have a pubsub auditer with a small buffer and a blocked pubsub", t, func() {
+
+		pubsub := &blockingPubSubServer{unblock: make(chan struct{})}
+		a := NewPubSubAuditer(pubsub, "audit", AuditOptPubSubBufferSize(1))
+
+		Convey("When I audit more requests than the buffer can hold", func() {
+
+			for i := 0; i < 5; i++ {
+				a.Audit(newAuditTestContext(), nil)
+			}
+
+			close(pubsub.unblock)
+			So(a.Close(), ShouldBeNil)
+
+			Convey("Then the extra records should be dropped", func() {
+				So(len(pubsub.publications), ShouldBeBetweenOrEqual, 1, 2)
+			})
+		})
+	})
+}
@@ -0,0 +1,203 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+)
+
+func newAuditTestContext() *bcontext {
+
+	req := elemental.NewRequest()
+	req.Identity = elemental.MakeIdentity("list", "lists")
+	req.Operation = elemental.OperationCreate
+	req.ObjectID = "xyz"
+	req.Namespace = "/ns"
+	req.ClientIP = "10.0.0.1"
+
+	ctx := newContext(context.Background(), req)
+	ctx.SetClaims([]string{"@auth:realm=certificate", "@auth:token=secret"})
+
+	return ctx
+}
+
+func TestNewAuditRecord(t *testing.T) {
+
+	Convey("Given I have a successful request", t, func() {
+
+		ctx := newAuditTestContext()
+		ctx.startTime = time.Now().Add(-time.Second)
+		ctx.SetOutputData(&struct{}{})
+
+		Convey("When I create its audit record", func() {
+
+			r := NewAuditRecord(ctx, nil)
+
+			Convey("Then the record should be correct", func() {
+				So(r.Time, ShouldNotBeZeroValue)
+				So(r.Claims, ShouldResemble, []string{"@auth:realm=certificate", "@auth:token=secret"})
+				So(r.ClientIP, ShouldEqual, "10.0.0.1")
+				So(r.Namespace, ShouldEqual, "/ns")
+				So(r.Identity, ShouldEqual, "list")
+				So(r.Operation, ShouldEqual, elemental.OperationCreate)
+				So(r.ObjectID, ShouldEqual, "xyz")
+				So(r.Status, ShouldEqual, http.StatusOK)
+				So(r.Error, ShouldBeEmpty)
+				So(r.Duration, ShouldNotBeEmpty)
+				So(r.TraceID, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given I have a successful request with no output data", t, func() {
+
+		ctx := newAuditTestContext()
+
+		Convey("Then the status should be correct", func() {
+			So(NewAuditRecord(ctx, nil).Status, ShouldEqual, http.StatusNoContent)
+		})
+	})
+
+	Convey("Given I have a successful request with a custom status code", t, func() {
+
+		ctx := newAuditTestContext()
+		ctx.SetStatusCode(http.StatusAccepted)
+
+		Convey("Then the status should be correct", func() {
+			So(NewAuditRecord(ctx, nil).Status, ShouldEqual, http.StatusAccepted)
+		})
+	})
+
+	Convey("Given I have a failed request", t, func() {
+
+		ctx := newAuditTestContext()
+
+		Convey("When I create its audit record", func() {
+
+			r := NewAuditRecord(ctx, elemental.NewError("Forbidden", "nope", "test", http.StatusForbidden))
+
+			Convey("Then the record should be correct", func() {
+				So(r.Status, ShouldEqual, http.StatusForbidden)
+				So(r.Error, ShouldContainSubstring, "nope")
+			})
+		})
+
+		Convey("When I create its audit record with a non elemental error", func() {
+
+			r := NewAuditRecord(ctx, fmt.Errorf("boom"))
+
+			Convey("Then the record should be correct", func() {
+				So(r.Status, ShouldEqual, http.StatusInternalServerError)
+				So(r.Error, ShouldNotBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given I have a traced request", t, func() {
+
+		tp, _ := newTestTracerProvider()
+		req := elemental.NewRequest()
+		tctx := traceRequestOTel(context.Background(), req, tp, nil, nil)
+		defer finishTracing(tctx)
+
+		ctx := newContext(tctx, req)
+
+		Convey("Then the trace ID should be correct", func() {
+			So(NewAuditRecord(ctx, nil).TraceID, ShouldEqual, otelSpanFromContext(tctx).SpanContext().TraceID().String())
+		})
+	})
+}
+
+func TestAuditRedactors(t *testing.T) {
+
+	Convey("Given I have an audit record", t, func() {
+
+		r := NewAuditRecord(newAuditTestContext(), fmt.Errorf("boom"))
+
+		Convey("When I apply AuditRedactClaims", func() {
+
+			claims := r.Claims
+			AuditRedactClaims("@auth:token", "@auth:other")(&r)
+
+			Convey("Then the claims should be redacted", func() {
+				So(r.Claims, ShouldResemble, []string{"@auth:realm=certificate", "@auth:token=" + AuditRedacted})
+			})
+
+			Convey("Then the original claims should not be modified", func() {
+				So(claims, ShouldResemble, []string{"@auth:realm=certificate", "@auth:token=secret"})
+			})
+		})
+
+		Convey("When I apply AuditRedactClientIP", func() {
+
+			AuditRedactClientIP()(&r)
+
+			Convey("Then the client IP should be redacted", func() {
+				So(r.ClientIP, ShouldEqual, AuditRedacted)
+			})
+		})
+
+		Convey("When I apply AuditRedactError", func() {
+
+			AuditRedactError()(&r)
+
+			Convey("Then the error should be redacted", func() {
+				So(r.Error, ShouldEqual, AuditRedacted)
+				So(r.Status, ShouldEqual, http.StatusInternalServerError)
+			})
+		})
+	})
+}
+
+func TestAuditOptions(t *testing.T) {
+
+	c := newAuditConfig()
+
+	Convey("Calling AuditOptRedactors should work", t, func() {
+		AuditOptRedactors(AuditRedactClientIP(), AuditRedactError())(&c)
+		So(len(c.redactors), ShouldEqual, 2)
+	})
+
+	Convey("Calling AuditOptFileMaxSize should work", t, func() {
+		AuditOptFileMaxSize(42)(&c)
+		So(c.fileMaxSize, ShouldEqual, 42)
+	})
+
+	Convey("Calling AuditOptFileMaxSize with an invalid size should panic", t, func() {
+		So(func() { AuditOptFileMaxSize(0) }, ShouldPanicWith, "size must be positive")
+	})
+
+	Convey("Calling AuditOptFileMaxBackups should work", t, func() {
+		AuditOptFileMaxBackups(3)(&c)
+		So(c.fileMaxBackups, ShouldEqual, 3)
+	})
+
+	Convey("Calling AuditOptFileMaxBackups with an invalid number should panic", t, func() {
+		So(func() { AuditOptFileMaxBackups(-1) }, ShouldPanicWith, "n must not be negative")
+	})
+
+	Convey("Calling AuditOptPubSubBufferSize should work", t, func() {
+		AuditOptPubSubBufferSize(12)(&c)
+		So(c.bufferSize, ShouldEqual, 12)
+	})
+
+	Convey("Calling AuditOptPubSubBufferSize with an invalid size should panic", t, func() {
+		So(func() { AuditOptPubSubBufferSize(0) }, ShouldPanicWith, "size must be positive")
+	})
+}
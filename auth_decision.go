@@ -0,0 +1,186 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// authDebugHeader is the header carrying the encoded
+// AuthDecisions when the auth debug claim is present.
+const authDebugHeader = "X-Auth-Debug"
+
+type authDecisionsKey struct{}
+type authReasonKey struct{}
+
+// An AuthStage represents the stage of the
+// request processing an AuthDecision was made at.
+type AuthStage string
+
+// Various values for AuthStage.
+const (
+	AuthStageAuthentication AuthStage = "authentication"
+	AuthStageAuthorization  AuthStage = "authorization"
+)
+
+// An AuthDecision records the decision of an authenticator
+// or an authorizer consulted during a request.
+type AuthDecision struct {
+
+	// Stage is the stage the decision was made at.
+	Stage AuthStage
+
+	// Name is the name of the authenticator or the
+	// authorizer, which is the name of its type.
+	Name string
+
+	// Action is the returned AuthAction.
+	Action AuthAction
+
+	// Err is the returned error, if any.
+	Err error
+
+	// Reason is the reason attached using SetAuthReason, if any.
+	Reason string
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d AuthDecision) MarshalJSON() ([]byte, error) {
+
+	out := struct {
+		Stage  AuthStage `json:"stage"`
+		Name   string    `json:"name"`
+		Action string    `json:"action"`
+		Error  string    `json:"error,omitempty"`
+		Reason string    `json:"reason,omitempty"`
+	}{
+		Stage:  d.Stage,
+		Name:   d.Name,
+		Action: d.Action.String(),
+		Reason: d.Reason,
+	}
+
+	if d.Err != nil {
+		out.Error = d.Err.Error()
+	}
+
+	return json.Marshal(out)
+}
+
+// SetAuthReason attaches the given reason to the decision the calling
+// authenticator or authorizer is about to return. It is meant to explain
+// a decision, like "certificate expired" or "no matching policy".
+func SetAuthReason(ctx Context, reason string) {
+
+	ctx.SetMetadata(authReasonKey{}, reason)
+}
+
+// AuthDecisions returns the decisions of the authenticators and the authorizers
+// consulted during the request held by the given Context, in order.
+//
+// This can be used by an Auditer to record why a request has been denied.
+func AuthDecisions(ctx Context) []AuthDecision {
+
+	decisions, _ := ctx.Metadata(authDecisionsKey{}).([]AuthDecision)
+
+	return append([]AuthDecision{}, decisions...)
+}
+
+// recordAuthDecision records the decision made by the
+// given authenticator or authorizer for the given Context.
+func recordAuthDecision(ctx Context, stage AuthStage, auth interface{}, action AuthAction, err error) {
+
+	reason, _ := ctx.Metadata(authReasonKey{}).(string)
+	if reason != "" {
+		ctx.SetMetadata(authReasonKey{}, nil)
+	}
+
+	d := AuthDecision{
+		Stage:  stage,
+		Name:   fmt.Sprintf("%T", auth),
+		Action: action,
+		Err:    err,
+		Reason: reason,
+	}
+
+	decisions, _ := ctx.Metadata(authDecisionsKey{}).([]AuthDecision)
+	ctx.SetMetadata(authDecisionsKey{}, append(decisions, d))
+
+	if span := opentracing.SpanFromContext(ctx.Context()); span != nil {
+
+		fields := []log.Field{
+			log.String("auth.stage", string(d.Stage)),
+			log.String("auth.name", d.Name),
+			log.String("auth.action", d.Action.String()),
+		}
+
+		if d.Reason != "" {
+			fields = append(fields, log.String("auth.reason", d.Reason))
+		}
+
+		if d.Err != nil {
+			fields = append(fields, log.Error(d.Err))
+		}
+
+		span.LogFields(fields...)
+	}
+
+	if span := otelSpanFromContext(ctx.Context()); span != nil {
+
+		attrs := []attribute.KeyValue{
+			attribute.String("auth.stage", string(d.Stage)),
+			attribute.String("auth.name", d.Name),
+			attribute.String("auth.action", d.Action.String()),
+		}
+
+		if d.Reason != "" {
+			attrs = append(attrs, attribute.String("auth.reason", d.Reason))
+		}
+
+		if d.Err != nil {
+			attrs = append(attrs, attribute.String("auth.error", d.Err.Error()))
+		}
+
+		span.AddEvent("auth.decision", trace.WithAttributes(attrs...))
+	}
+}
+
+// setAuthDebugHeader sets the X-Auth-Debug header to the encoded
+// AuthDecisions if the given Context has the given debug claim.
+func setAuthDebugHeader(w http.ResponseWriter, ctx Context, claim string) {
+
+	found := false
+	for _, c := range ctx.Claims() {
+		if c == claim {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return
+	}
+
+	data, err := json.Marshal(AuthDecisions(ctx))
+	if err != nil {
+		return
+	}
+
+	w.Header().Set(authDebugHeader, string(data))
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+)
+
+type mockReasonAuthorizer struct {
+	action AuthAction
+	reason string
+}
+
+func (a *mockReasonAuthorizer) IsAuthorized(ctx Context) (AuthAction, error) {
+
+	SetAuthReason(ctx, a.reason)
+
+	return a.action, nil
+}
+
+func TestAuthAction_String(t *testing.T) {
+
+	Convey("Given I have some AuthActions", t, func() {
+
+		Convey("Then their string representations should be correct", func() {
+			So(AuthActionOK.String(), ShouldEqual, "ok")
+			So(AuthActionKO.String(), ShouldEqual, "ko")
+			So(AuthActionContinue.String(), ShouldEqual, "continue")
+			So(AuthAction(42).String(), ShouldEqual, "unknown(42)")
+		})
+	})
+}
+
+func TestAuthDecision_MarshalJSON(t *testing.T) {
+
+	Convey("Given I have an AuthDecision with an error and a reason", t, func() {
+
+		d := AuthDecision{
+			Stage:  AuthStageAuthorization,
+			Name:   "*rbac.Authorizer",
+			Action: AuthActionKO,
+			Err:    fmt.Errorf("boom"),
+			Reason: "no matching policy",
+		}
+
+		data, err := json.Marshal(d)
+
+		Convey("Then it should be correctly encoded", func() {
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"stage":"authorization","name":"*rbac.Authorizer","action":"ko","error":"boom","reason":"no matching policy"}`)
+		})
+	})
+
+	Convey("Given I have an AuthDecision without error and reason", t, func() {
+
+		data, err := json.Marshal(AuthDecision{Stage: AuthStageAuthentication, Name: "a", Action: AuthActionOK})
+
+		Convey("Then it should be correctly encoded", func() {
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"stage":"authentication","name":"a","action":"ok"}`)
+		})
+	})
+}
+
+func TestAuthDecisions(t *testing.T) {
+
+	Convey("Given I have a context with a span", t, func() {
+
+		span := newMockSpan(&mockTracer{})
+		ctx := newContext(opentracing.ContextWithSpan(context.Background(), span), elemental.NewRequest())
+
+		Convey("When I check authentication and authorization", func() {
+
+			authn := &mockAuth{action: AuthActionOK}
+			authz1 := &mockReasonAuthorizer{action: AuthActionContinue, reason: "not my business"}
+			authz2 := &mockAuth{errored: true, err: fmt.Errorf("boom")}
+
+			So(CheckAuthentication([]RequestAuthenticator{authn}, ctx), ShouldBeNil)
+			So(CheckAuthorization([]Authorizer{authz1, authz2}, ctx), ShouldNotBeNil)
+
+			decisions := AuthDecisions(ctx)
+
+			Convey("Then the decisions should be recorded in order", func() {
+				So(decisions, ShouldResemble, []AuthDecision{
+					{Stage: AuthStageAuthentication, Name: "*bahamut.mockAuth", Action: AuthActionOK},
+					{Stage: AuthStageAuthorization, Name: "*bahamut.mockReasonAuthorizer", Action: AuthActionContinue, Reason: "not my business"},
+					{Stage: AuthStageAuthorization, Name: "*bahamut.mockAuth", Action: AuthActionKO, Err: fmt.Errorf("boom")},
+				})
+			})
+
+			Convey("Then the returned decisions should be a copy", func() {
+				decisions[0].Name = "modified"
+				So(AuthDecisions(ctx)[0].Name, ShouldEqual, "*bahamut.mockAuth")
+			})
+
+			Convey("Then the decisions should be logged in the span", func() {
+				So(len(span.fields), ShouldEqual, 11)
+				So(span.fields[0].Key(), ShouldEqual, "auth.stage")
+				So(span.fields[0].Value(), ShouldEqual, "authentication")
+				So(span.fields[5].Key(), ShouldEqual, "auth.action")
+				So(span.fields[5].Value(), ShouldEqual, "continue")
+				So(span.fields[6].Key(), ShouldEqual, "auth.reason")
+				So(span.fields[6].Value(), ShouldEqual, "not my business")
+				So(span.fields[10].Key(), ShouldEqual, "error.object")
+			})
+		})
+	})
+
+	Convey("Given I have a context without decisions", t, func() {
+
+		ctx := newContext(context.Background(), elemental.NewRequest())
+
+		Convey("Then AuthDecisions should return an empty list", func() {
+			So(AuthDecisions(ctx), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestSetAuthDebugHeader(t *testing.T) {
+
+	Convey("Given I have a context with some decisions", t, func() {
+
+		ctx := newContext(context.Background(), elemental.NewRequest())
+		So(CheckAuthorization([]Authorizer{&mockReasonAuthorizer{action: AuthActionKO, reason: "denied"}}, ctx), ShouldNotBeNil)
+
+		Convey("When the context has the debug claim", func() {
+
+			ctx.SetClaims([]string{"a=b", "@auth:debug=true"})
+			w := httptest.NewRecorder()
+			setAuthDebugHeader(w, ctx, "@auth:debug=true")
+
+			Convey("Then the header should be set", func() {
+				So(w.Header().Get("X-Auth-Debug"), ShouldEqual, `[{"stage":"authorization","name":"*bahamut.mockReasonAuthorizer","action":"ko","reason":"denied"}]`)
+			})
+		})
+
+		Convey("When the context does not have the debug claim", func() {
+
+			ctx.SetClaims([]string{"a=b"})
+			w := httptest.NewRecorder()
+			setAuthDebugHeader(w, ctx, "@auth:debug=true")
+
+			Convey("Then the header should not be set", func() {
+				So(w.Header().Get("X-Auth-Debug"), ShouldBeEmpty)
+			})
+		})
+	})
+}
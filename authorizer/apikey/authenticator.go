@@ -0,0 +1,216 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aporeto.io/bahamut"
+	"go.uber.org/zap"
+)
+
+// maxVerifiedCacheSize is the maximum number of
+// verified secrets kept by an Authenticator.
+const maxVerifiedCacheSize = 10000
+
+// An Authenticator is a bahamut.RequestAuthenticator and
+// bahamut.SessionAuthenticator that verifies the API keys
+// passed by the clients against the keys of a KeyStore.
+//
+// If the client did not pass a key, the Authenticator returns
+// bahamut.AuthActionContinue so another authenticator can handle
+// the request. If the key is malformed, unknown, expired or does not
+// match the hash, it returns bahamut.AuthActionKO. Otherwise, it records
+// the usage of the key, sets the claims of the key and returns
+// bahamut.AuthActionOK. The following claims are always added:
+//
+//	@auth:realm=apikey
+//	@auth:keyid=<id>
+//
+// To protect against timing attacks, a hash is also verified when the
+// key id is unknown. As hashing is expensive on purpose, the successfully
+// verified secrets are remembered, so valid keys are only hashed once.
+type Authenticator struct {
+	store     KeyStore
+	cfg       config
+	dummyHash string
+	verified  map[[sha256.Size]byte]struct{}
+
+	sync.Mutex
+}
+
+// NewAuthenticator returns a new Authenticator verifying
+// the keys using the given KeyStore.
+func NewAuthenticator(store KeyStore, options ...Option) *Authenticator {
+
+	if store == nil {
+		panic("store must not be nil")
+	}
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	dummyHash, err := HashSecret(base64.RawURLEncoding.EncodeToString(b))
+	if err != nil {
+		panic(err)
+	}
+
+	return &Authenticator{
+		store:     store,
+		cfg:       cfg,
+		dummyHash: dummyHash,
+		verified:  map[[sha256.Size]byte]struct{}{},
+	}
+}
+
+// AuthenticateRequest implements the bahamut.RequestAuthenticator interface.
+func (a *Authenticator) AuthenticateRequest(ctx bahamut.Context) (bahamut.AuthAction, error) {
+
+	req := ctx.Request()
+
+	var key string
+
+	if a.cfg.header != "" {
+		key = req.Headers.Get(a.cfg.header)
+	}
+
+	if key == "" && a.cfg.queryParameter != "" {
+		key = req.Parameters.Get(a.cfg.queryParameter).StringValue()
+	}
+
+	return a.authenticate(key, ctx.SetClaims), nil
+}
+
+// AuthenticateSession implements the bahamut.SessionAuthenticator interface.
+func (a *Authenticator) AuthenticateSession(session bahamut.Session) (bahamut.AuthAction, error) {
+
+	var key string
+
+	if a.cfg.header != "" {
+		key = session.Header(a.cfg.header)
+	}
+
+	if key == "" && a.cfg.queryParameter != "" {
+		key = session.Parameter(a.cfg.queryParameter)
+	}
+
+	return a.authenticate(key, session.SetClaims), nil
+}
+
+func (a *Authenticator) authenticate(key string, claimSetter func([]string)) bahamut.AuthAction {
+
+	if key == "" {
+		return bahamut.AuthActionContinue
+	}
+
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		zap.L().Debug("Unable to verify api key: malformed key")
+		return bahamut.AuthActionKO
+	}
+
+	id, secret := parts[0], parts[1]
+
+	k, err := a.store.Key(id)
+	if err != nil {
+
+		if !errors.Is(err, ErrKeyNotFound) {
+			zap.L().Error("Unable to retrieve api key", zap.String("id", id), zap.Error(err))
+			return bahamut.AuthActionKO
+		}
+
+		// We still verify a hash, so the response time
+		// does not reveal whether the key id exists.
+		_, _ = verifySecret(a.dummyHash, secret)
+
+		zap.L().Debug("Unable to verify api key: unknown key", zap.String("id", id))
+		return bahamut.AuthActionKO
+	}
+
+	ok, err := a.verify(k.Hash, secret)
+	if err != nil {
+		zap.L().Error("Unable to verify api key", zap.String("id", id), zap.Error(err))
+		return bahamut.AuthActionKO
+	}
+
+	if !ok {
+		zap.L().Debug("Unable to verify api key: secret mismatch", zap.String("id", id))
+		return bahamut.AuthActionKO
+	}
+
+	now := time.Now()
+
+	if k.IsExpired(now) {
+		zap.L().Debug("Unable to verify api key: expired key", zap.String("id", id))
+		return bahamut.AuthActionKO
+	}
+
+	if err := a.store.RecordUsage(id, now); err != nil {
+		zap.L().Warn("Unable to record api key usage", zap.String("id", id), zap.Error(err))
+	}
+
+	claimSetter(makeClaims(k))
+
+	return bahamut.AuthActionOK
+}
+
+// verify verifies the given secret against the given hash,
+// using the previously verified secrets if possible.
+func (a *Authenticator) verify(hash string, secret string) (bool, error) {
+
+	// The cache key depends on the hash, so a rotated
+	// key cannot be verified using a previous secret.
+	ck := sha256.Sum256([]byte(hash + "\x00" + secret))
+
+	a.Lock()
+	_, ok := a.verified[ck]
+	a.Unlock()
+
+	if ok {
+		return true, nil
+	}
+
+	ok, err := verifySecret(hash, secret)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	a.Lock()
+	if len(a.verified) >= maxVerifiedCacheSize {
+		a.verified = map[[sha256.Size]byte]struct{}{}
+	}
+	a.verified[ck] = struct{}{}
+	a.Unlock()
+
+	return true, nil
+}
+
+func makeClaims(k Key) []string {
+
+	out := make([]string, 0, len(k.Claims)+2)
+	out = append(out, "@auth:realm=apikey", "@auth:keyid="+k.ID)
+
+	return append(out, k.Claims...)
+}
@@ -0,0 +1,284 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apikey
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+)
+
+type mockSession struct {
+	headers    map[string]string
+	parameters map[string]string
+	claims     []string
+}
+
+func (s *mockSession) Cookie(string) (*http.Cookie, error)      { return nil, nil }
+func (s *mockSession) Identifier() string                       { return "" }
+func (s *mockSession) Parameter(k string) string                { return s.parameters[k] }
+func (s *mockSession) Header(k string) string                   { return s.headers[k] }
+func (s *mockSession) PushConfig() *elemental.PushConfig        { return nil }
+func (s *mockSession) SetClaims(c []string)                     { s.claims = c }
+func (s *mockSession) Claims() []string                         { return s.claims }
+func (s *mockSession) ClaimsMap() map[string]string             { return nil }
+func (s *mockSession) Token() string                            { return "" }
+func (s *mockSession) TLSConnectionState() *tls.ConnectionState { return nil }
+func (s *mockSession) Metadata() interface{}                    { return nil }
+func (s *mockSession) SetMetadata(interface{})                  {}
+func (s *mockSession) Context() context.Context                 { return context.Background() }
+func (s *mockSession) ClientIP() string                         { return "" }
+
+type mockKeyStore struct {
+	keys     map[string]Key
+	lastUsed map[string]time.Time
+	err      error
+}
+
+func (s *mockKeyStore) Key(id string) (Key, error) {
+
+	if s.err != nil {
+		return Key{}, s.err
+	}
+
+	k, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+
+	return k, nil
+}
+
+func (s *mockKeyStore) RecordUsage(id string, t time.Time) error {
+
+	s.lastUsed[id] = t
+
+	return nil
+}
+
+func TestNewAuthenticator(t *testing.T) {
+
+	Convey("Given I call NewAuthenticator with some options", t, func() {
+
+		store := &mockKeyStore{}
+
+		auth := NewAuthenticator(
+			store,
+			OptionHeader("X-Key"),
+			OptionQueryParameter("key"),
+		)
+
+		Convey("Then it should be correctly initialized", func() {
+			So(auth.store, ShouldEqual, store)
+			So(auth.cfg.header, ShouldEqual, "X-Key")
+			So(auth.cfg.queryParameter, ShouldEqual, "key")
+			So(validateHash(auth.dummyHash), ShouldBeNil)
+		})
+	})
+
+	Convey("Given I call NewAuthenticator without option", t, func() {
+
+		auth := NewAuthenticator(&mockKeyStore{})
+
+		Convey("Then it should use the defaults", func() {
+			So(auth.cfg.header, ShouldEqual, "X-API-Key")
+			So(auth.cfg.queryParameter, ShouldEqual, "")
+		})
+	})
+
+	Convey("Calling NewAuthenticator with a nil store should panic", t, func() {
+		So(func() { NewAuthenticator(nil) }, ShouldPanic)
+	})
+}
+
+func TestAuthenticateRequest(t *testing.T) {
+
+	Convey("Given I have an Authenticator and some keys", t, func() {
+
+		validKey, validHash, _ := GenerateKey("valid")
+		expiredKey, expiredHash, _ := GenerateKey("expired")
+
+		store := &mockKeyStore{
+			keys: map[string]Key{
+				"valid":   {ID: "valid", Hash: validHash, Claims: []string{"@auth:subject=ci"}, ExpiresAt: time.Now().Add(time.Hour)},
+				"expired": {ID: "expired", Hash: expiredHash, ExpiresAt: time.Now().Add(-time.Hour)},
+			},
+			lastUsed: map[string]time.Time{},
+		}
+
+		auth := NewAuthenticator(store, OptionQueryParameter("apikey"))
+
+		check := func(header string, parameter string) (bahamut.AuthAction, bahamut.Context) {
+
+			req := elemental.NewRequest()
+			if header != "" {
+				req.Headers.Set("X-API-Key", header)
+			}
+			if parameter != "" {
+				req.Parameters = elemental.Parameters{"apikey": elemental.NewParameter(elemental.ParameterTypeString, parameter)}
+			}
+
+			ctx := bahamut.NewContext(context.Background(), req)
+
+			action, err := auth.AuthenticateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			return action, ctx
+		}
+
+		Convey("When I pass a valid key in the header", func() {
+
+			action, ctx := check(validKey, "")
+
+			Convey("Then action should be OK", func() {
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+
+			Convey("Then the claims should be set", func() {
+				So(ctx.Claims(), ShouldResemble, []string{"@auth:realm=apikey", "@auth:keyid=valid", "@auth:subject=ci"})
+			})
+
+			Convey("Then the usage should be recorded", func() {
+				So(store.lastUsed["valid"], ShouldHappenWithin, time.Second, time.Now())
+			})
+
+			Convey("Then the secret should be remembered", func() {
+				So(len(auth.verified), ShouldEqual, 1)
+				action, _ := check(validKey, "")
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+		})
+
+		Convey("When I pass a valid key in the query parameters", func() {
+
+			action, _ := check("", validKey)
+
+			Convey("Then action should be OK", func() {
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+		})
+
+		Convey("When I pass no key", func() {
+
+			action, ctx := check("", "")
+
+			Convey("Then action should be Continue", func() {
+				So(action, ShouldEqual, bahamut.AuthActionContinue)
+				So(ctx.Claims(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I pass invalid keys", func() {
+
+			for name, key := range map[string]string{
+				"malformed":    "nope",
+				"empty secret": "valid.",
+				"empty id":     ".secret",
+				"unknown":      "unknown.secret",
+				"wrong secret": "valid.secret",
+				"expired":      expiredKey,
+			} {
+
+				action, ctx := check(key, "")
+
+				Convey("Then the "+name+" key should be rejected", func() {
+					So(action, ShouldEqual, bahamut.AuthActionKO)
+					So(ctx.Claims(), ShouldBeEmpty)
+				})
+			}
+
+			Convey("Then only the secret of the expired key should be remembered", func() {
+				So(len(auth.verified), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the store returns an error", func() {
+
+			store.err = fmt.Errorf("boom")
+
+			action, _ := check(validKey, "")
+
+			Convey("Then action should be KO", func() {
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+			})
+		})
+
+		Convey("When the key is rotated", func() {
+
+			action, _ := check(validKey, "")
+			So(action, ShouldEqual, bahamut.AuthActionOK)
+
+			_, newHash, _ := GenerateKey("valid")
+			store.keys["valid"] = Key{ID: "valid", Hash: newHash}
+
+			action, _ = check(validKey, "")
+
+			Convey("Then the previous key should be rejected", func() {
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+			})
+		})
+	})
+}
+
+func TestAuthenticateSession(t *testing.T) {
+
+	Convey("Given I have an Authenticator and a key", t, func() {
+
+		key, hash, _ := GenerateKey("valid")
+
+		store := &mockKeyStore{
+			keys:     map[string]Key{"valid": {ID: "valid", Hash: hash}},
+			lastUsed: map[string]time.Time{},
+		}
+
+		Convey("When I pass the key in the header", func() {
+
+			session := &mockSession{headers: map[string]string{"X-API-Key": key}}
+			action, err := NewAuthenticator(store).AuthenticateSession(session)
+
+			Convey("Then action should be OK", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+				So(session.claims, ShouldResemble, []string{"@auth:realm=apikey", "@auth:keyid=valid"})
+			})
+		})
+
+		Convey("When I pass the key in the parameters and query parameters are disabled", func() {
+
+			session := &mockSession{parameters: map[string]string{"apikey": key}}
+			action, err := NewAuthenticator(store).AuthenticateSession(session)
+
+			Convey("Then action should be Continue", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionContinue)
+			})
+		})
+
+		Convey("When I pass the key in the parameters and query parameters are enabled", func() {
+
+			session := &mockSession{parameters: map[string]string{"apikey": key}}
+			action, err := NewAuthenticator(store, OptionHeader(""), OptionQueryParameter("apikey")).AuthenticateSession(session)
+
+			Convey("Then action should be OK", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+		})
+	})
+}
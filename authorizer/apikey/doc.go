@@ -0,0 +1,19 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apikey provides an implementation of bahamut.RequestAuthenticator
+// and bahamut.SessionAuthenticator that verifies API keys passed by machine
+// clients against hashes held in a KeyStore.
+//
+// An API key is in the form <id>.<secret>. The id is used to retrieve the Key
+// from the KeyStore, and the secret is verified against the argon2id or bcrypt
+// hash of the Key. The secret itself is never stored.
+package apikey // import "go.aporeto.io/bahamut/authorizer/apikey"
@@ -0,0 +1,186 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aporeto.io/bahamut/authorizer/internal/filewatcher"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type keyFile struct {
+	Keys []Key `yaml:"keys"`
+}
+
+// ParseKeys parses the keys from the given YAML data and validates them.
+// The data must be in the form:
+//
+//	keys:
+//	  - id: ci
+//	    hash: $argon2id$v=19$m=19456,t=2,p=1$...
+//	    claims:
+//	      - "@auth:subject=ci"
+//	    expiresAt: 2030-01-01T00:00:00Z
+func ParseKeys(data []byte) ([]Key, error) {
+
+	f := keyFile{}
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("unable to decode keys: %s", err)
+	}
+
+	ids := make(map[string]struct{}, len(f.Keys))
+
+	for i, k := range f.Keys {
+
+		if k.ID == "" || strings.Contains(k.ID, ".") {
+			return nil, fmt.Errorf("invalid key at index %d: id must not be empty or contain '.'", i)
+		}
+
+		if _, ok := ids[k.ID]; ok {
+			return nil, fmt.Errorf("invalid key at index %d: duplicate id '%s'", i, k.ID)
+		}
+		ids[k.ID] = struct{}{}
+
+		if err := validateHash(k.Hash); err != nil {
+			return nil, fmt.Errorf("invalid key at index %d: %w", i, err)
+		}
+
+		for _, claim := range k.Claims {
+			if parts := strings.SplitN(claim, "=", 2); len(parts) != 2 || parts[0] == "" {
+				return nil, fmt.Errorf("invalid key at index %d: invalid claim '%s': must be in the form key=value", i, claim)
+			}
+		}
+	}
+
+	return f.Keys, nil
+}
+
+// A FileKeyStore is a KeyStore holding the
+// keys loaded from a YAML file.
+//
+// The last usage times are only kept in memory.
+type FileKeyStore struct {
+	path     string
+	keys     map[string]Key
+	lastUsed map[string]time.Time
+	watcher  *filewatcher.Watcher
+
+	sync.RWMutex
+}
+
+// NewFileKeyStore returns a new FileKeyStore loading the keys from
+// the file at the given path, in the format described by ParseKeys.
+// It returns an error if the keys cannot be loaded.
+//
+// If reloadInterval is greater than 0, the file is checked for changes
+// at that interval until the given context is canceled. A file with
+// invalid keys is ignored, and the FileKeyStore keeps serving the
+// keys it loaded last.
+func NewFileKeyStore(ctx context.Context, path string, reloadInterval time.Duration) (*FileKeyStore, error) {
+
+	s := &FileKeyStore{
+		path:     path,
+		lastUsed: map[string]time.Time{},
+	}
+	s.watcher = filewatcher.New("api key file", s.load, path)
+
+	if _, err := s.watcher.Reload(); err != nil {
+		return nil, err
+	}
+
+	if reloadInterval > 0 {
+		go s.watcher.Watch(ctx, reloadInterval)
+	}
+
+	return s, nil
+}
+
+// Key implements the KeyStore interface.
+func (s *FileKeyStore) Key(id string) (Key, error) {
+
+	s.RLock()
+	defer s.RUnlock()
+
+	k, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+
+	return k, nil
+}
+
+// RecordUsage implements the KeyStore interface.
+func (s *FileKeyStore) RecordUsage(id string, t time.Time) error {
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.keys[id]; !ok {
+		return ErrKeyNotFound
+	}
+
+	if t.After(s.lastUsed[id]) {
+		s.lastUsed[id] = t
+	}
+
+	return nil
+}
+
+// LastUsed returns the last time the key with the given id
+// has been used. It returns false if the key has not been
+// used since the FileKeyStore has been created.
+func (s *FileKeyStore) LastUsed(id string) (time.Time, bool) {
+
+	s.RLock()
+	defer s.RUnlock()
+
+	t, ok := s.lastUsed[id]
+
+	return t, ok
+}
+
+// load loads the keys from the key file. The usage
+// times of the removed keys are forgotten.
+func (s *FileKeyStore) load() error {
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("unable to read key file: %s", err)
+	}
+
+	keys, err := ParseKeys(data)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.keys = make(map[string]Key, len(keys))
+	for _, k := range keys {
+		s.keys[k.ID] = k
+	}
+
+	for id := range s.lastUsed {
+		if _, ok := s.keys[id]; !ok {
+			delete(s.lastUsed, id)
+		}
+	}
+
+	return nil
+}
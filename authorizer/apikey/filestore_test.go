@@ -0,0 +1,175 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseKeys(t *testing.T) {
+
+	hash, _ := HashSecret("secret")
+
+	Convey("Given I have a valid key file", t, func() {
+
+		data := []byte(fmt.Sprintf(`
+keys:
+  - id: ci
+    hash: %s
+    claims:
+      - "@auth:subject=ci"
+    expiresAt: 2030-01-02T03:04:05Z
+  - id: bot
+    hash: %s
+`, hash, hash))
+
+		keys, err := ParseKeys(data)
+
+		Convey("Then err should be nil", func() {
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Then the keys should be correct", func() {
+			So(len(keys), ShouldEqual, 2)
+			So(keys[0].ID, ShouldEqual, "ci")
+			So(keys[0].Hash, ShouldEqual, hash)
+			So(keys[0].Claims, ShouldResemble, []string{"@auth:subject=ci"})
+			So(keys[0].ExpiresAt.Equal(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)), ShouldBeTrue)
+			So(keys[1].ID, ShouldEqual, "bot")
+			So(keys[1].ExpiresAt.IsZero(), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given I have invalid key files", t, func() {
+
+		for data, expected := range map[string]string{
+			"keys: 42":                                                               "unable to decode keys: ",
+			"keys:\n  - id: a\n    nope: b":                                          "unable to decode keys: ",
+			"keys:\n  - hash: " + hash:                                               "invalid key at index 0: id must not be empty or contain '.'",
+			"keys:\n  - id: a.b\n    hash: " + hash:                                  "invalid key at index 0: id must not be empty or contain '.'",
+			"keys:\n  - id: a\n    hash: nope":                                       "invalid key at index 0: unsupported hash format: must be argon2id or bcrypt",
+			"keys:\n  - id: a\n    hash: " + hash + "\n    claims: [nope]":           "invalid key at index 0: invalid claim 'nope': must be in the form key=value",
+			"keys:\n  - id: a\n    hash: " + hash + "\n  - id: a\n    hash: " + hash: "invalid key at index 1: duplicate id 'a'",
+		} {
+
+			_, err := ParseKeys([]byte(data))
+
+			Convey("Then parsing "+data+" should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, expected)
+			})
+		}
+	})
+}
+
+func TestFileKeyStore(t *testing.T) {
+
+	hash, _ := HashSecret("secret")
+
+	Convey("Given I have a key file", t, func() {
+
+		dir, _ := ioutil.TempDir("", "apikey")
+		defer os.RemoveAll(dir) // nolint
+
+		path := filepath.Join(dir, "keys.yaml")
+		So(ioutil.WriteFile(path, []byte("keys:\n  - id: a\n    hash: "+hash), 0600), ShouldBeNil)
+
+		Convey("When I create a store from a missing file", func() {
+
+			_, err := NewFileKeyStore(context.Background(), filepath.Join(dir, "missing.yaml"), 0)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "unable to stat api key file ")
+			})
+		})
+
+		Convey("When I create a store", func() {
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			s, err := NewFileKeyStore(ctx, path, 10*time.Millisecond)
+			So(err, ShouldBeNil)
+
+			Convey("Then I should be able to retrieve the keys", func() {
+
+				k, err := s.Key("a")
+				So(err, ShouldBeNil)
+				So(k.ID, ShouldEqual, "a")
+
+				_, err = s.Key("b")
+				So(err, ShouldEqual, ErrKeyNotFound)
+			})
+
+			Convey("Then I should be able to record the usages", func() {
+
+				_, ok := s.LastUsed("a")
+				So(ok, ShouldBeFalse)
+
+				now := time.Now()
+				So(s.RecordUsage("a", now), ShouldBeNil)
+				So(s.RecordUsage("a", now.Add(-time.Minute)), ShouldBeNil)
+				So(s.RecordUsage("b", now), ShouldEqual, ErrKeyNotFound)
+
+				t, ok := s.LastUsed("a")
+				So(ok, ShouldBeTrue)
+				So(t, ShouldEqual, now)
+			})
+
+			Convey("When the file is updated", func() {
+
+				So(s.RecordUsage("a", time.Now()), ShouldBeNil)
+				So(ioutil.WriteFile(path, []byte("keys:\n  - id: b\n    hash: "+hash), 0600), ShouldBeNil)
+				So(os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)), ShouldBeNil)
+
+				Convey("Then the keys should be reloaded", func() {
+					So(func() error {
+						for i := 0; i < 100; i++ {
+							if _, err := s.Key("b"); err == nil {
+								return nil
+							}
+							time.Sleep(10 * time.Millisecond)
+						}
+						return fmt.Errorf("not reloaded")
+					}(), ShouldBeNil)
+
+					_, err := s.Key("a")
+					So(err, ShouldEqual, ErrKeyNotFound)
+
+					_, ok := s.LastUsed("a")
+					So(ok, ShouldBeFalse)
+				})
+			})
+
+			Convey("When the file is updated with invalid keys", func() {
+
+				So(ioutil.WriteFile(path, []byte("keys:\n  - id: b\n    hash: nope"), 0600), ShouldBeNil)
+				So(os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)), ShouldBeNil)
+
+				Convey("Then the previous keys should be kept", func() {
+					time.Sleep(50 * time.Millisecond)
+					_, err := s.Key("a")
+					So(err, ShouldBeNil)
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,226 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Parameters used by HashSecret.
+const (
+	argon2Time    = 2
+	argon2Memory  = 19 * 1024
+	argon2Threads = 1
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// ErrKeyNotFound is returned by a KeyStore
+// when the requested key does not exist.
+var ErrKeyNotFound = errors.New("key not found")
+
+// A Key represents an API key as held by a KeyStore.
+type Key struct {
+
+	// ID is the public identifier of the key.
+	ID string `yaml:"id"`
+
+	// Hash is the argon2id or bcrypt hash of the secret
+	// part of the key, as returned by HashSecret.
+	Hash string `yaml:"hash"`
+
+	// Claims are the claims given to the
+	// clients authenticated with the key.
+	Claims []string `yaml:"claims"`
+
+	// ExpiresAt is the time after which the key is not
+	// valid anymore. If zero, the key never expires.
+	ExpiresAt time.Time `yaml:"expiresAt"`
+}
+
+// IsExpired returns true if the key is expired at the given time.
+func (k Key) IsExpired(now time.Time) bool {
+
+	return !k.ExpiresAt.IsZero() && now.After(k.ExpiresAt)
+}
+
+// A KeyStore holds the API keys.
+type KeyStore interface {
+
+	// Key returns the key with the given id, or
+	// ErrKeyNotFound if it does not exist.
+	Key(id string) (Key, error)
+
+	// RecordUsage records that the key with the
+	// given id has been used at the given time.
+	RecordUsage(id string, t time.Time) error
+}
+
+// GenerateKey generates a new random API key with the given id.
+// It returns the key to give to the client, and the hash of its
+// secret to put in the KeyStore.
+func GenerateKey(id string) (key string, hash string, err error) {
+
+	if id == "" || strings.Contains(id, ".") {
+		return "", "", fmt.Errorf("invalid key id '%s': must not be empty or contain '.'", id)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("unable to generate secret: %s", err)
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	if hash, err = HashSecret(secret); err != nil {
+		return "", "", err
+	}
+
+	return id + "." + secret, hash, nil
+}
+
+// HashSecret returns the argon2id hash of the given secret, encoded
+// in the PHC string format: $argon2id$v=19$m=...,t=...,p=...$salt$hash.
+func HashSecret(secret string) (string, error) {
+
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("unable to generate salt: %s", err)
+	}
+
+	sum := argon2.IDKey([]byte(secret), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		argon2Memory,
+		argon2Time,
+		argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(sum),
+	), nil
+}
+
+// verifySecret returns true if the given secret matches the given hash.
+// The comparison is done in constant time.
+func verifySecret(hash string, secret string) (bool, error) {
+
+	if strings.HasPrefix(hash, "$argon2id$") {
+
+		p, err := parseArgon2Hash(hash)
+		if err != nil {
+			return false, err
+		}
+
+		sum := argon2.IDKey([]byte(secret), p.salt, p.time, p.memory, p.threads, uint32(len(p.sum)))
+
+		return subtle.ConstantTimeCompare(sum, p.sum) == 1, nil
+	}
+
+	if isBcryptHash(hash) {
+
+		switch err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)); err {
+		case nil:
+			return true, nil
+		case bcrypt.ErrMismatchedHashAndPassword:
+			return false, nil
+		default:
+			return false, fmt.Errorf("invalid bcrypt hash: %s", err)
+		}
+	}
+
+	return false, fmt.Errorf("unsupported hash format")
+}
+
+// validateHash returns an error if the
+// given hash cannot be used to verify secrets.
+func validateHash(hash string) error {
+
+	if strings.HasPrefix(hash, "$argon2id$") {
+		_, err := parseArgon2Hash(hash)
+		return err
+	}
+
+	if isBcryptHash(hash) {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("invalid bcrypt hash: %s", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported hash format: must be argon2id or bcrypt")
+}
+
+func isBcryptHash(hash string) bool {
+
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	sum     []byte
+}
+
+func parseArgon2Hash(hash string) (argon2Params, error) {
+
+	p := argon2Params{}
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, fmt.Errorf("invalid argon2id hash: malformed value")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, fmt.Errorf("invalid argon2id hash: unable to parse version: %s", err)
+	}
+
+	if version != argon2.Version {
+		return p, fmt.Errorf("invalid argon2id hash: unsupported version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, fmt.Errorf("invalid argon2id hash: unable to parse parameters: %s", err)
+	}
+
+	if p.time == 0 || p.threads == 0 {
+		return p, fmt.Errorf("invalid argon2id hash: time and threads must be greater than 0")
+	}
+
+	var err error
+
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, fmt.Errorf("invalid argon2id hash: unable to decode salt: %s", err)
+	}
+
+	if p.sum, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, fmt.Errorf("invalid argon2id hash: unable to decode hash: %s", err)
+	}
+
+	if len(p.sum) == 0 {
+		return p, fmt.Errorf("invalid argon2id hash: empty hash")
+	}
+
+	return p, nil
+}
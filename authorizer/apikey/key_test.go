@@ -0,0 +1,144 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apikey
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGenerateKey(t *testing.T) {
+
+	Convey("Given I generate a key", t, func() {
+
+		key, hash, err := GenerateKey("ci")
+
+		Convey("Then err should be nil", func() {
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Then the key should be correct", func() {
+			So(key, ShouldStartWith, "ci.")
+			So(len(key), ShouldEqual, 46)
+		})
+
+		Convey("Then the hash should match the secret", func() {
+			ok, err := verifySecret(hash, strings.TrimPrefix(key, "ci."))
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+		})
+	})
+
+	Convey("Given I generate a key with an invalid id", t, func() {
+
+		_, _, err1 := GenerateKey("")
+		_, _, err2 := GenerateKey("a.b")
+
+		Convey("Then err should not be nil", func() {
+			So(err1, ShouldNotBeNil)
+			So(err2, ShouldNotBeNil)
+			So(err2.Error(), ShouldEqual, "invalid key id 'a.b': must not be empty or contain '.'")
+		})
+	})
+}
+
+func TestVerifySecret(t *testing.T) {
+
+	Convey("Given I have an argon2id hash", t, func() {
+
+		hash, err := HashSecret("secret")
+		So(err, ShouldBeNil)
+		So(hash, ShouldStartWith, "$argon2id$v=19$m=19456,t=2,p=1$")
+		So(validateHash(hash), ShouldBeNil)
+
+		Convey("Then the correct secret should match", func() {
+			ok, err := verifySecret(hash, "secret")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Then a wrong secret should not match", func() {
+			ok, err := verifySecret(hash, "not-secret")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Then hashing twice should use a different salt", func() {
+			hash2, err := HashSecret("secret")
+			So(err, ShouldBeNil)
+			So(hash2, ShouldNotEqual, hash)
+		})
+	})
+
+	Convey("Given I have a bcrypt hash", t, func() {
+
+		data, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+		So(err, ShouldBeNil)
+		hash := string(data)
+		So(validateHash(hash), ShouldBeNil)
+
+		Convey("Then the correct secret should match", func() {
+			ok, err := verifySecret(hash, "secret")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Then a wrong secret should not match", func() {
+			ok, err := verifySecret(hash, "not-secret")
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given I have invalid hashes", t, func() {
+
+		for _, hash := range []string{
+			"",
+			"secret",
+			"$argon2i$v=19$m=16,t=1,p=1$c2FsdA$aGFzaA",
+			"$argon2id$v=19$m=16,t=1,p=1$c2FsdA",
+			"$argon2id$v=16$m=16,t=1,p=1$c2FsdA$aGFzaA",
+			"$argon2id$v=19$m=16,t=0,p=1$c2FsdA$aGFzaA",
+			"$argon2id$v=19$m=16,t=1,p=1$!!!$aGFzaA",
+			"$argon2id$v=19$m=16,t=1,p=1$c2FsdA$",
+			"$2a$10$tooshort",
+		} {
+
+			Convey("Then "+hash+" should be rejected", func() {
+				So(validateHash(hash), ShouldNotBeNil)
+				_, err := verifySecret(hash, "secret")
+				So(err, ShouldNotBeNil)
+			})
+		}
+	})
+}
+
+func TestKeyIsExpired(t *testing.T) {
+
+	Convey("Given I have some keys", t, func() {
+
+		now := time.Now()
+
+		Convey("Then a key without expiration should never expire", func() {
+			So(Key{}.IsExpired(now), ShouldBeFalse)
+		})
+
+		Convey("Then a key should expire after its expiration time", func() {
+			So(Key{ExpiresAt: now.Add(time.Second)}.IsExpired(now), ShouldBeFalse)
+			So(Key{ExpiresAt: now.Add(-time.Second)}.IsExpired(now), ShouldBeTrue)
+		})
+	})
+}
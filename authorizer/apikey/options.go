@@ -0,0 +1,46 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apikey
+
+type config struct {
+	header         string
+	queryParameter string
+}
+
+func newConfig() config {
+	return config{
+		header: "X-API-Key",
+	}
+}
+
+// An Option represents a configuration option
+// for the Authenticator.
+type Option func(*config)
+
+// OptionHeader sets the name of the header carrying the API key.
+// Setting it to an empty string disables reading the key from the
+// headers. The default is X-API-Key.
+func OptionHeader(name string) Option {
+	return func(c *config) {
+		c.header = name
+	}
+}
+
+// OptionQueryParameter sets the name of the query parameter carrying
+// the API key. It is only used when the header is not set. By default,
+// the key is not read from the query parameters, as URLs are likely
+// to end up in access logs.
+func OptionQueryParameter(name string) Option {
+	return func(c *config) {
+		c.queryParameter = name
+	}
+}
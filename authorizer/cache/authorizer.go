@@ -0,0 +1,175 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/karlseguin/ccache/v2"
+	"go.aporeto.io/bahamut"
+	"go.uber.org/zap"
+)
+
+// A MetricsManager reports the hits and misses of the cache.
+// The prometheus bahamut.MetricsManager implements it.
+type MetricsManager interface {
+	RegisterAuthorizationCacheHit()
+	RegisterAuthorizationCacheMiss()
+}
+
+// A KeyFunc returns the cache key to use for the given bahamut.Context.
+// Requests with the same key share the same authorization decision.
+// If it returns an empty string, the decision is not cached.
+type KeyFunc func(bahamut.Context) string
+
+// DefaultKeyFunc is the default KeyFunc. It computes a key from the
+// claims of the caller and the identity, operation and namespace of
+// the request.
+//
+// If your authorizers make decisions based on other information, like
+// the ID of the object or the parameters, you must use a KeyFunc
+// taking this information into account.
+func DefaultKeyFunc(ctx bahamut.Context) string {
+
+	claims := ctx.Claims()
+	sort.Strings(claims)
+
+	req := ctx.Request()
+
+	return strings.Join(
+		[]string{
+			strings.Join(claims, "\x1f"),
+			req.Identity.Name,
+			string(req.Operation),
+			req.Namespace,
+		},
+		"\x1e",
+	)
+}
+
+// An Authorizer is a bahamut.Authorizer that evaluates a list of
+// bahamut.Authorizers in the same way bahamut.CheckAuthorization does,
+// and caches the resulting decision.
+//
+// Decisions allowing the request, bahamut.AuthActionOK or
+// bahamut.AuthActionContinue if all authorizers continued, are
+// cached with the TTL set by OptionTTL. Decisions denying the request,
+// bahamut.AuthActionKO, are cached with the TTL set by OptionNegativeTTL.
+// Errors are never cached.
+type Authorizer struct {
+	authorizers []bahamut.Authorizer
+	cache       *ccache.Cache
+	cfg         config
+}
+
+// NewAuthorizer returns a new Authorizer caching the decisions of the given
+// authorizers. If OptionInvalidationTopic is used, the subscription is
+// canceled when the given context is canceled.
+func NewAuthorizer(ctx context.Context, authorizers []bahamut.Authorizer, options ...Option) *Authorizer {
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	a := &Authorizer{
+		authorizers: authorizers,
+		cache:       ccache.New(ccache.Configure().MaxSize(cfg.maxSize)),
+		cfg:         cfg,
+	}
+
+	if cfg.pubsub != nil {
+		pubs := make(chan *bahamut.Publication, 10)
+		errs := make(chan error, 10)
+		unsubscribe := cfg.pubsub.Subscribe(pubs, errs, cfg.topic)
+		go a.listen(ctx, pubs, errs, unsubscribe)
+	}
+
+	return a
+}
+
+// IsAuthorized implements the bahamut.Authorizer interface.
+func (a *Authorizer) IsAuthorized(ctx bahamut.Context) (bahamut.AuthAction, error) {
+
+	key := a.cfg.keyFunc(ctx)
+	if key == "" {
+		return a.evaluate(ctx)
+	}
+
+	if item := a.cache.Get(key); item != nil && !item.Expired() {
+		if a.cfg.metricsManager != nil {
+			a.cfg.metricsManager.RegisterAuthorizationCacheHit()
+		}
+		return item.Value().(bahamut.AuthAction), nil
+	}
+
+	if a.cfg.metricsManager != nil {
+		a.cfg.metricsManager.RegisterAuthorizationCacheMiss()
+	}
+
+	action, err := a.evaluate(ctx)
+	if err != nil {
+		return action, err
+	}
+
+	switch action {
+	case bahamut.AuthActionKO:
+		if a.cfg.negativeTTL > 0 {
+			a.cache.Set(key, action, a.cfg.negativeTTL)
+		}
+	default:
+		a.cache.Set(key, action, a.cfg.ttl)
+	}
+
+	return action, nil
+}
+
+// Invalidate clears the cache.
+func (a *Authorizer) Invalidate() {
+
+	a.cache.Clear()
+}
+
+func (a *Authorizer) evaluate(ctx bahamut.Context) (bahamut.AuthAction, error) {
+
+	for _, authorizer := range a.authorizers {
+
+		action, err := authorizer.IsAuthorized(ctx)
+		if err != nil {
+			return bahamut.AuthActionKO, err
+		}
+
+		if action != bahamut.AuthActionContinue {
+			return action, nil
+		}
+	}
+
+	return bahamut.AuthActionContinue, nil
+}
+
+func (a *Authorizer) listen(ctx context.Context, pubs chan *bahamut.Publication, errs chan error, unsubscribe func()) {
+
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-pubs:
+			a.Invalidate()
+		case err := <-errs:
+			zap.L().Error("Error during authorization cache invalidation subscription", zap.String("topic", a.cfg.topic), zap.Error(err))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
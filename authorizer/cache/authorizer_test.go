@@ -0,0 +1,331 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+)
+
+type countingAuthorizer struct {
+	action bahamut.AuthAction
+	err    error
+	calls  int64
+}
+
+func (a *countingAuthorizer) IsAuthorized(bahamut.Context) (bahamut.AuthAction, error) {
+	atomic.AddInt64(&a.calls, 1)
+	return a.action, a.err
+}
+
+func (a *countingAuthorizer) count() int64 {
+	return atomic.LoadInt64(&a.calls)
+}
+
+// basicMetricsManager only implements bahamut.MetricsManager.
+type basicMetricsManager struct{}
+
+func (m *basicMetricsManager) MeasureRequest(string, string) bahamut.FinishMeasurementFunc {
+	return func(int, opentracing.Span) time.Duration { return 0 }
+}
+func (m *basicMetricsManager) RegisterWSConnection()                        {}
+func (m *basicMetricsManager) UnregisterWSConnection()                      {}
+func (m *basicMetricsManager) RegisterTCPConnection()                       {}
+func (m *basicMetricsManager) UnregisterTCPConnection()                     {}
+func (m *basicMetricsManager) Write(w http.ResponseWriter, r *http.Request) {}
+
+type countingMetricsManager struct {
+	basicMetricsManager
+	hits   int64
+	misses int64
+}
+
+func (m *countingMetricsManager) RegisterAuthorizationCacheHit()  { m.hits++ }
+func (m *countingMetricsManager) RegisterAuthorizationCacheMiss() { m.misses++ }
+
+func makeContext(namespace string, op elemental.Operation, claims ...string) bahamut.Context {
+
+	ctx := bahamut.NewContext(context.Background(), &elemental.Request{
+		Namespace: namespace,
+		Operation: op,
+		Identity:  elemental.MakeIdentity("task", "tasks"),
+	})
+	ctx.SetClaims(claims)
+
+	return ctx
+}
+
+func TestDefaultKeyFunc(t *testing.T) {
+
+	Convey("Given I have some contexts", t, func() {
+
+		Convey("Then the claims order should not matter", func() {
+			So(
+				DefaultKeyFunc(makeContext("/a", elemental.OperationCreate, "a=a", "b=b")),
+				ShouldEqual,
+				DefaultKeyFunc(makeContext("/a", elemental.OperationCreate, "b=b", "a=a")),
+			)
+		})
+
+		Convey("Then the keys should be different for different requests", func() {
+			k := DefaultKeyFunc(makeContext("/a", elemental.OperationCreate, "a=a"))
+			So(DefaultKeyFunc(makeContext("/b", elemental.OperationCreate, "a=a")), ShouldNotEqual, k)
+			So(DefaultKeyFunc(makeContext("/a", elemental.OperationDelete, "a=a")), ShouldNotEqual, k)
+			So(DefaultKeyFunc(makeContext("/a", elemental.OperationCreate, "a=b")), ShouldNotEqual, k)
+			So(DefaultKeyFunc(makeContext("/a", elemental.OperationCreate, "a=a", "b=b")), ShouldNotEqual, k)
+		})
+	})
+}
+
+func TestAuthorizer_IsAuthorized(t *testing.T) {
+
+	Convey("Given I have an Authorizer wrapping authorizers", t, func() {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		a1 := &countingAuthorizer{action: bahamut.AuthActionContinue}
+		a2 := &countingAuthorizer{action: bahamut.AuthActionOK}
+		mm := &countingMetricsManager{}
+
+		a := NewAuthorizer(ctx, []bahamut.Authorizer{a1, a2}, OptionMetricsManager(mm))
+
+		Convey("When I call IsAuthorized twice with the same request", func() {
+
+			action1, err1 := a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+			action2, err2 := a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+
+			Convey("Then the decision should be correct", func() {
+				So(err1, ShouldBeNil)
+				So(err2, ShouldBeNil)
+				So(action1, ShouldEqual, bahamut.AuthActionOK)
+				So(action2, ShouldEqual, bahamut.AuthActionOK)
+			})
+
+			Convey("Then the authorizers should have been called once", func() {
+				So(a1.count(), ShouldEqual, 1)
+				So(a2.count(), ShouldEqual, 1)
+			})
+
+			Convey("Then the metrics should be correct", func() {
+				So(mm.hits, ShouldEqual, 1)
+				So(mm.misses, ShouldEqual, 1)
+			})
+
+			Convey("When I call Invalidate and IsAuthorized again", func() {
+
+				a.Invalidate()
+				_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+
+				Convey("Then the authorizers should have been called again", func() {
+					So(a2.count(), ShouldEqual, 2)
+				})
+			})
+		})
+
+		Convey("When I call IsAuthorized with different requests", func() {
+
+			_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+			_, _ = a.IsAuthorized(makeContext("/b", elemental.OperationRetrieve, "a=a"))
+
+			Convey("Then the authorizers should have been called twice", func() {
+				So(a2.count(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When the authorizers return an error", func() {
+
+			a2.err = fmt.Errorf("boom")
+
+			_, err1 := a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+			_, err2 := a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+
+			Convey("Then the error should not be cached", func() {
+				So(err1, ShouldNotBeNil)
+				So(err2, ShouldNotBeNil)
+				So(a2.count(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When the authorizers deny the request", func() {
+
+			a2.action = bahamut.AuthActionKO
+
+			action, _ := a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+			_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+
+			Convey("Then the decision should not be cached", func() {
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+				So(a2.count(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When all the authorizers continue", func() {
+
+			a2.action = bahamut.AuthActionContinue
+
+			action, _ := a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+			_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+
+			Convey("Then the decision should be cached", func() {
+				So(action, ShouldEqual, bahamut.AuthActionContinue)
+				So(a2.count(), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given I have an Authorizer with negative caching", t, func() {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		a1 := &countingAuthorizer{action: bahamut.AuthActionKO}
+
+		a := NewAuthorizer(ctx, []bahamut.Authorizer{a1}, OptionNegativeTTL(time.Minute))
+
+		Convey("When I call IsAuthorized twice with a denied request", func() {
+
+			action, _ := a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+			_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+
+			Convey("Then the decision should be cached", func() {
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+				So(a1.count(), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given I have an Authorizer with a short ttl", t, func() {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		a1 := &countingAuthorizer{action: bahamut.AuthActionOK}
+
+		a := NewAuthorizer(ctx, []bahamut.Authorizer{a1}, OptionTTL(10*time.Millisecond))
+
+		Convey("When I call IsAuthorized twice with the ttl expiring in between", func() {
+
+			_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+			time.Sleep(20 * time.Millisecond)
+			_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+
+			Convey("Then the authorizer should have been called twice", func() {
+				So(a1.count(), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given I have an Authorizer with a key func that disables caching", t, func() {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		a1 := &countingAuthorizer{action: bahamut.AuthActionOK}
+
+		a := NewAuthorizer(ctx, []bahamut.Authorizer{a1}, OptionKeyFunc(func(bahamut.Context) string { return "" }))
+
+		Convey("When I call IsAuthorized twice", func() {
+
+			_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+			_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+
+			Convey("Then the authorizer should have been called twice", func() {
+				So(a1.count(), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given I have an Authorizer with an invalidation topic", t, func() {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		pubsub := bahamut.NewLocalPubSubClient()
+		if err := pubsub.Connect(ctx); err != nil {
+			panic(err)
+		}
+		defer pubsub.Disconnect() // nolint
+
+		a1 := &countingAuthorizer{action: bahamut.AuthActionOK}
+
+		a := NewAuthorizer(ctx, []bahamut.Authorizer{a1}, OptionInvalidationTopic(pubsub, "authz-invalidate"))
+
+		Convey("When I publish on the invalidation topic", func() {
+
+			_, _ = a.IsAuthorized(makeContext("/a", elemental.OperationRetrieve, "a=a"))
+
+			So(pubsub.Publish(bahamut.NewPublication("authz-invalidate")), ShouldBeNil)
+
+			Convey("Then the cache should eventually be cleared", func() {
+				So(func() bool {
+					for i := 0; i < 100; i++ {
+						if a.cache.ItemCount() == 0 {
+							return true
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+					return false
+				}(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestOptions(t *testing.T) {
+
+	Convey("Calling the options with invalid values should panic", t, func() {
+		So(func() { OptionTTL(0) }, ShouldPanicWith, "ttl must be greater than 0")
+		So(func() { OptionNegativeTTL(-time.Second) }, ShouldPanicWith, "ttl cannot be negative")
+		So(func() { OptionMaxSize(0) }, ShouldPanicWith, "size must be greater than 0")
+		So(func() { OptionKeyFunc(nil) }, ShouldPanicWith, "key func must not be nil")
+		So(func() { OptionInvalidationTopic(nil, "topic") }, ShouldPanicWith, "pubsub must not be nil")
+		So(func() { OptionInvalidationTopic(bahamut.NewLocalPubSubClient(), "") }, ShouldPanicWith, "topic must not be empty")
+	})
+
+	Convey("Calling the options should work", t, func() {
+
+		c := newConfig()
+		pubsub := bahamut.NewLocalPubSubClient()
+		mm := &countingMetricsManager{}
+
+		OptionTTL(time.Minute)(&c)
+		OptionNegativeTTL(time.Second)(&c)
+		OptionMaxSize(42)(&c)
+		OptionMetricsManager(mm)(&c)
+		OptionInvalidationTopic(pubsub, "topic")(&c)
+
+		So(c.ttl, ShouldEqual, time.Minute)
+		So(c.negativeTTL, ShouldEqual, time.Second)
+		So(c.maxSize, ShouldEqual, 42)
+		So(c.metricsManager, ShouldEqual, mm)
+		So(c.pubsub, ShouldEqual, pubsub)
+		So(c.topic, ShouldEqual, "topic")
+	})
+
+	Convey("Calling OptionMetricsManager with a manager not reporting the cache metrics should work", t, func() {
+
+		c := newConfig()
+		OptionMetricsManager(&basicMetricsManager{})(&c)
+
+		So(c.metricsManager, ShouldBeNil)
+	})
+}
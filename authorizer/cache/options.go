@@ -0,0 +1,120 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"time"
+
+	"go.aporeto.io/bahamut"
+)
+
+type config struct {
+	ttl            time.Duration
+	negativeTTL    time.Duration
+	maxSize        int64
+	keyFunc        KeyFunc
+	metricsManager MetricsManager
+	pubsub         bahamut.PubSubClient
+	topic          string
+}
+
+func newConfig() config {
+	return config{
+		ttl:     30 * time.Second,
+		maxSize: 10000,
+		keyFunc: DefaultKeyFunc,
+	}
+}
+
+// An Option represents a configuration option
+// for the Authorizer.
+type Option func(*config)
+
+// OptionTTL sets how long the decisions allowing the request
+// are cached. The default is 30s.
+func OptionTTL(ttl time.Duration) Option {
+
+	if ttl <= 0 {
+		panic("ttl must be greater than 0")
+	}
+
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// OptionNegativeTTL sets how long the decisions denying the request
+// are cached. The default is 0, meaning these decisions are not cached.
+func OptionNegativeTTL(ttl time.Duration) Option {
+
+	if ttl < 0 {
+		panic("ttl cannot be negative")
+	}
+
+	return func(c *config) {
+		c.negativeTTL = ttl
+	}
+}
+
+// OptionMaxSize sets the maximum number of decisions to keep
+// in cache. The default is 10000.
+func OptionMaxSize(size int64) Option {
+
+	if size <= 0 {
+		panic("size must be greater than 0")
+	}
+
+	return func(c *config) {
+		c.maxSize = size
+	}
+}
+
+// OptionKeyFunc sets the KeyFunc used to compute the cache key
+// of a request. The default is DefaultKeyFunc.
+func OptionKeyFunc(f KeyFunc) Option {
+
+	if f == nil {
+		panic("key func must not be nil")
+	}
+
+	return func(c *config) {
+		c.keyFunc = f
+	}
+}
+
+// OptionMetricsManager sets the bahamut.MetricsManager used to
+// report the cache hits and misses. It must also implement
+// MetricsManager, otherwise nothing is reported.
+func OptionMetricsManager(m bahamut.MetricsManager) Option {
+	return func(c *config) {
+		c.metricsManager, _ = m.(MetricsManager)
+	}
+}
+
+// OptionInvalidationTopic makes the Authorizer listen for publications
+// on the given topic of the given bahamut.PubSubClient. Any publication
+// received on that topic clears the cache.
+func OptionInvalidationTopic(pubsub bahamut.PubSubClient, topic string) Option {
+
+	if pubsub == nil {
+		panic("pubsub must not be nil")
+	}
+
+	if topic == "" {
+		panic("topic must not be empty")
+	}
+
+	return func(c *config) {
+		c.pubsub = pubsub
+		c.topic = topic
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cel
+
+import (
+	"fmt"
+
+	celgo "github.com/google/cel-go/cel"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+	"go.uber.org/zap"
+)
+
+// An Authorizer is a bahamut.Authorizer and bahamut.SessionAuthenticator
+// that evaluates a CEL expression.
+//
+// If the expression cannot be evaluated, for instance because it
+// accesses a missing key, the Authorizer returns bahamut.AuthActionKO.
+type Authorizer struct {
+	expression string
+	program    celgo.Program
+	cfg        config
+}
+
+// NewAuthorizer returns a new Authorizer evaluating the given expression.
+// It returns an error if the expression cannot be compiled or does not
+// evaluate to a boolean.
+func NewAuthorizer(expression string, options ...Option) (*Authorizer, error) {
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	env, err := celgo.NewEnv(
+		celgo.Variable("claims", celgo.MapType(celgo.StringType, celgo.StringType)),
+		celgo.Variable("request", celgo.MapType(celgo.StringType, celgo.DynType)),
+		celgo.Variable("input", celgo.DynType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cel environment: %s", err)
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("unable to compile expression: %s", issues.Err())
+	}
+
+	if ast.OutputType() != celgo.BoolType {
+		return nil, fmt.Errorf("unable to compile expression: must evaluate to a bool, not %s", ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create program: %s", err)
+	}
+
+	return &Authorizer{
+		expression: expression,
+		program:    program,
+		cfg:        cfg,
+	}, nil
+}
+
+// IsAuthorized implements the bahamut.Authorizer interface.
+func (a *Authorizer) IsAuthorized(ctx bahamut.Context) (bahamut.AuthAction, error) {
+
+	req := ctx.Request()
+
+	parameters := make(map[string][]interface{}, len(req.Parameters))
+	for k, p := range req.Parameters {
+		parameters[k] = p.Values()
+	}
+
+	headers := make(map[string]string, len(req.Headers))
+	for k := range req.Headers {
+		headers[k] = req.Headers.Get(k)
+	}
+
+	return a.evaluate(map[string]interface{}{
+		"claims": ctx.ClaimsMap(),
+		"request": map[string]interface{}{
+			"operation":  string(req.Operation),
+			"identity":   req.Identity.Name,
+			"namespace":  req.Namespace,
+			"parameters": parameters,
+			"headers":    headers,
+			"clientIP":   req.ClientIP,
+		},
+		"input": func() interface{} { return decodeInput(req) },
+	}), nil
+}
+
+// AuthenticateSession implements the bahamut.SessionAuthenticator interface.
+func (a *Authorizer) AuthenticateSession(session bahamut.Session) (bahamut.AuthAction, error) {
+
+	return a.evaluate(map[string]interface{}{
+		"claims": session.ClaimsMap(),
+		"request": map[string]interface{}{
+			"operation":  "",
+			"identity":   "",
+			"namespace":  session.Parameter("namespace"),
+			"parameters": map[string][]interface{}{},
+			"headers":    map[string]string{},
+			"clientIP":   session.ClientIP(),
+		},
+		"input": map[string]interface{}{},
+	}), nil
+}
+
+func (a *Authorizer) evaluate(vars map[string]interface{}) bahamut.AuthAction {
+
+	out, _, err := a.program.Eval(vars)
+	if err != nil {
+		zap.L().Debug("Unable to evaluate authorization expression",
+			zap.String("expression", a.expression),
+			zap.Error(err),
+		)
+		return bahamut.AuthActionKO
+	}
+
+	if v, ok := out.Value().(bool); ok && v {
+		return a.cfg.matchAction
+	}
+
+	return a.cfg.mismatchAction
+}
+
+// decodeInput decodes the data of the request. As the authorization
+// happens before the data is decoded by bahamut, the data is only
+// decoded if the expression uses it.
+func decodeInput(req *elemental.Request) interface{} {
+
+	input := map[string]interface{}{}
+
+	if len(req.Data) == 0 {
+		return input
+	}
+
+	if err := req.Decode(&input); err != nil {
+		zap.L().Debug("Unable to decode input data for authorization expression", zap.Error(err))
+		return map[string]interface{}{}
+	}
+
+	return input
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cel
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+)
+
+type mockSession struct {
+	parameters map[string]string
+	claims     []string
+}
+
+func (s *mockSession) Cookie(string) (*http.Cookie, error)      { return nil, nil }
+func (s *mockSession) Identifier() string                       { return "" }
+func (s *mockSession) Parameter(k string) string                { return s.parameters[k] }
+func (s *mockSession) Header(string) string                     { return "" }
+func (s *mockSession) PushConfig() *elemental.PushConfig        { return nil }
+func (s *mockSession) SetClaims(c []string)                     { s.claims = c }
+func (s *mockSession) Claims() []string                         { return s.claims }
+func (s *mockSession) Token() string                            { return "" }
+func (s *mockSession) TLSConnectionState() *tls.ConnectionState { return nil }
+func (s *mockSession) Metadata() interface{}                    { return nil }
+func (s *mockSession) SetMetadata(interface{})                  {}
+func (s *mockSession) Context() context.Context                 { return context.Background() }
+func (s *mockSession) ClientIP() string                         { return "10.0.0.1" }
+func (s *mockSession) ClaimsMap() map[string]string {
+	out := map[string]string{}
+	for _, c := range s.claims {
+		for i := range c {
+			if c[i] == '=' {
+				out[c[:i]] = c[i+1:]
+				break
+			}
+		}
+	}
+	return out
+}
+
+func TestNewAuthorizer(t *testing.T) {
+
+	Convey("Given I call NewAuthorizer with a valid expression", t, func() {
+
+		a, err := NewAuthorizer(
+			`request.operation == "delete" && claims["@auth:role"] == "admin"`,
+			OptionActions(bahamut.AuthActionContinue, bahamut.AuthActionKO),
+		)
+
+		Convey("Then it should be correctly initialized", func() {
+			So(err, ShouldBeNil)
+			So(a.program, ShouldNotBeNil)
+			So(a.cfg.matchAction, ShouldEqual, bahamut.AuthActionContinue)
+			So(a.cfg.mismatchAction, ShouldEqual, bahamut.AuthActionKO)
+		})
+	})
+
+	Convey("Given I call NewAuthorizer with an invalid expression", t, func() {
+
+		a, err := NewAuthorizer(`request.operation ==`)
+
+		Convey("Then it should fail", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "unable to compile expression: ")
+			So(a, ShouldBeNil)
+		})
+	})
+
+	Convey("Given I call NewAuthorizer with an expression using an unknown variable", t, func() {
+
+		a, err := NewAuthorizer(`user == "bob"`)
+
+		Convey("Then it should fail", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "undeclared reference to 'user'")
+			So(a, ShouldBeNil)
+		})
+	})
+
+	Convey("Given I call NewAuthorizer with an expression that is not a bool", t, func() {
+
+		a, err := NewAuthorizer(`claims["@auth:role"]`)
+
+		Convey("Then it should fail", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unable to compile expression: must evaluate to a bool, not string")
+			So(a, ShouldBeNil)
+		})
+	})
+}
+
+func TestAuthorizer_IsAuthorized(t *testing.T) {
+
+	Convey("Given I have a request", t, func() {
+
+		req := elemental.NewRequest()
+		req.Operation = elemental.OperationCreate
+		req.Identity = elemental.MakeIdentity("task", "tasks")
+		req.Namespace = "/acme/a"
+		req.ClientIP = "10.0.0.1"
+		req.Parameters = elemental.Parameters{
+			"mode": elemental.NewParameter(elemental.ParameterTypeString, "fast"),
+		}
+		req.Headers.Set("x-custom", "value")
+		req.Data = []byte(`{"name":"a","priority":3}`)
+
+		ctx := bahamut.NewContext(context.Background(), req)
+		ctx.SetClaims([]string{"@auth:role=admin", "@auth:subject=bob"})
+
+		for expression, expected := range map[string]bahamut.AuthAction{
+			`claims["@auth:role"] == "admin"`:                      bahamut.AuthActionOK,
+			`claims["@auth:role"] == "reader"`:                     bahamut.AuthActionContinue,
+			`request.operation == "create"`:                        bahamut.AuthActionOK,
+			`request.identity == "task"`:                           bahamut.AuthActionOK,
+			`request.namespace.startsWith("/acme")`:                bahamut.AuthActionOK,
+			`request.parameters["mode"][0] == "fast"`:              bahamut.AuthActionOK,
+			`request.headers["X-Custom"] == "value"`:               bahamut.AuthActionOK,
+			`request.clientIP == "10.0.0.1"`:                       bahamut.AuthActionOK,
+			`input.name == "a" && input.priority < 5`:              bahamut.AuthActionOK,
+			`input.priority > 5`:                                   bahamut.AuthActionContinue,
+			`request.headers["X-Missing"] == "value"`:              bahamut.AuthActionKO,
+			`"X-Missing" in request.headers && claims["a"] == "b"`: bahamut.AuthActionContinue,
+		} {
+
+			a, err := NewAuthorizer(expression)
+			So(err, ShouldBeNil)
+
+			action, err := a.IsAuthorized(ctx)
+
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, expected)
+		}
+	})
+
+	Convey("Given I have a request with no data", t, func() {
+
+		ctx := bahamut.NewContext(context.Background(), elemental.NewRequest())
+
+		Convey("When I evaluate an expression using the input", func() {
+
+			a, _ := NewAuthorizer(`size(input) == 0`)
+			action, err := a.IsAuthorized(ctx)
+
+			Convey("Then the input should be empty", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+		})
+	})
+}
+
+func TestAuthorizer_AuthenticateSession(t *testing.T) {
+
+	Convey("Given I have an Authorizer used as a guard and a session", t, func() {
+
+		a, err := NewAuthorizer(
+			`request.namespace.startsWith("/acme") && claims["@auth:role"] == "admin"`,
+			OptionActions(bahamut.AuthActionContinue, bahamut.AuthActionKO),
+		)
+		So(err, ShouldBeNil)
+
+		session := &mockSession{
+			parameters: map[string]string{"namespace": "/acme/a"},
+			claims:     []string{"@auth:role=admin"},
+		}
+
+		Convey("When the session matches the expression", func() {
+
+			action, err := a.AuthenticateSession(session)
+
+			Convey("Then action should be Continue", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionContinue)
+			})
+		})
+
+		Convey("When the session does not match the expression", func() {
+
+			session.parameters["namespace"] = "/other"
+
+			action, err := a.AuthenticateSession(session)
+
+			Convey("Then action should be KO", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+			})
+		})
+	})
+}
@@ -0,0 +1,39 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cel provides a bahamut.Authorizer and bahamut.SessionAuthenticator
+// evaluating a Common Expression Language (CEL) expression.
+//
+// The expression must evaluate to a boolean and has access to the
+// following variables:
+//
+//   - claims: map(string, string) containing the claims of the caller.
+//   - request: map(string, dyn) describing the request.
+//   - input: the decoded data sent by the client, or an empty map.
+//
+// The request variable contains the following keys:
+//
+//   - operation: the operation of the request, like "create".
+//   - identity: the name of the identity of the request, like "task".
+//   - namespace: the namespace of the request.
+//   - parameters: map(string, list(dyn)) containing the parameters of the request.
+//   - headers: map(string, string) containing the first value of each header,
+//     using the canonical header names, like "X-Custom-Header".
+//   - clientIP: the IP address of the client.
+//
+// For instance:
+//
+//	request.operation == "delete" && claims["@auth:role"] == "admin"
+//
+// When used as a bahamut.SessionAuthenticator, operation and identity are
+// empty, namespace is the namespace requested by the push session,
+// parameters and headers are empty and input is an empty map.
+package cel // import "go.aporeto.io/bahamut/authorizer/cel"
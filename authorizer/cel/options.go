@@ -0,0 +1,44 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cel
+
+import "go.aporeto.io/bahamut"
+
+type config struct {
+	matchAction    bahamut.AuthAction
+	mismatchAction bahamut.AuthAction
+}
+
+func newConfig() config {
+	return config{
+		matchAction:    bahamut.AuthActionOK,
+		mismatchAction: bahamut.AuthActionContinue,
+	}
+}
+
+// An Option represents a configuration option
+// for the Authorizer.
+type Option func(*config)
+
+// OptionActions sets the actions to return when the expression
+// evaluates to true and to false. The default is to return
+// bahamut.AuthActionOK when the expression is true and
+// bahamut.AuthActionContinue otherwise.
+//
+// To use the expression as a guard, you can for instance
+// use bahamut.AuthActionContinue and bahamut.AuthActionKO.
+func OptionActions(match bahamut.AuthAction, mismatch bahamut.AuthAction) Option {
+	return func(c *config) {
+		c.matchAction = match
+		c.mismatchAction = mismatch
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filewatcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// A Watcher calls a load function when
+// the files it watches change.
+type Watcher struct {
+	kind  string
+	paths []string
+	load  func() error
+	state string
+
+	sync.Mutex
+}
+
+// New returns a Watcher calling load when the files at the given
+// paths change. When a path is a directory, the files it contains
+// are watched. The kind describes the files in the errors and
+// the logs, like "policy file".
+func New(kind string, load func() error, paths ...string) *Watcher {
+
+	return &Watcher{
+		kind:  kind,
+		paths: paths,
+		load:  load,
+	}
+}
+
+// Reload calls the load function if the files have changed since
+// the last call. It returns true if the load function has been
+// called and succeeded.
+//
+// The state of the files is recorded even if the load function
+// fails, so it is not called again until the files change again.
+// The load function must keep the previously loaded content when
+// it fails.
+func (w *Watcher) Reload() (bool, error) {
+
+	w.Lock()
+	defer w.Unlock()
+
+	state, err := w.stat()
+	if err != nil {
+		return false, err
+	}
+
+	if state == w.state {
+		return false, nil
+	}
+
+	w.state = state
+
+	if err := w.load(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Watch calls Reload at the given interval until the given
+// context is canceled. The errors are logged. It is blocking
+// and should be run in its own goroutine.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ticker.C:
+
+			reloaded, err := w.Reload()
+			if err != nil {
+				zap.L().Error("Unable to reload "+w.kind, zap.Strings("paths", w.paths), zap.Error(err))
+				continue
+			}
+
+			if reloaded {
+				zap.L().Info("Reloaded "+w.kind, zap.Strings("paths", w.paths))
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stat returns a string describing the
+// current state of the watched files.
+func (w *Watcher) stat() (string, error) {
+
+	var parts []string
+
+	for _, p := range w.paths {
+
+		info, err := os.Stat(p)
+		if err != nil {
+			return "", fmt.Errorf("unable to stat %s '%s': %s", w.kind, p, err)
+		}
+
+		if !info.IsDir() {
+			parts = append(parts, describe(p, info))
+			continue
+		}
+
+		files, err := ioutil.ReadDir(p)
+		if err != nil {
+			return "", fmt.Errorf("unable to read %s '%s': %s", w.kind, p, err)
+		}
+
+		for _, f := range files {
+			if !f.IsDir() {
+				parts = append(parts, describe(filepath.Join(p, f.Name()), f))
+			}
+		}
+	}
+
+	return strings.Join(parts, "|"), nil
+}
+
+func describe(path string, info os.FileInfo) string {
+
+	return fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano())
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filewatcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+
+	Convey("Given I have a watcher on a file and a directory", t, func() {
+
+		dir, _ := ioutil.TempDir("", "filewatcher")
+		defer os.RemoveAll(dir) // nolint
+
+		path := filepath.Join(dir, "file")
+		So(ioutil.WriteFile(path, []byte("a"), 0600), ShouldBeNil)
+
+		sub := filepath.Join(dir, "sub")
+		So(os.Mkdir(sub, 0700), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(sub, "a"), []byte("a"), 0600), ShouldBeNil)
+
+		var calls int
+		var loadErr error
+		w := New("test file", func() error { calls++; return loadErr }, path, sub)
+
+		reloaded, err := w.Reload()
+		So(err, ShouldBeNil)
+		So(reloaded, ShouldBeTrue)
+		So(calls, ShouldEqual, 1)
+
+		Convey("When I reload without any change", func() {
+
+			reloaded, err := w.Reload()
+
+			Convey("Then nothing should be loaded", func() {
+				So(err, ShouldBeNil)
+				So(reloaded, ShouldBeFalse)
+				So(calls, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When the file changes", func() {
+
+			So(ioutil.WriteFile(path, []byte("ab"), 0600), ShouldBeNil)
+
+			reloaded, err := w.Reload()
+
+			Convey("Then it should be loaded", func() {
+				So(err, ShouldBeNil)
+				So(reloaded, ShouldBeTrue)
+				So(calls, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a file is added to the directory", func() {
+
+			So(ioutil.WriteFile(filepath.Join(sub, "b"), []byte("b"), 0600), ShouldBeNil)
+
+			reloaded, err := w.Reload()
+
+			Convey("Then it should be loaded", func() {
+				So(err, ShouldBeNil)
+				So(reloaded, ShouldBeTrue)
+				So(calls, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When a file in the directory is modified", func() {
+
+			So(os.Chtimes(filepath.Join(sub, "a"), time.Now(), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			reloaded, err := w.Reload()
+
+			Convey("Then it should be loaded", func() {
+				So(err, ShouldBeNil)
+				So(reloaded, ShouldBeTrue)
+				So(calls, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When the file changes and the load fails", func() {
+
+			loadErr = fmt.Errorf("boom")
+			So(ioutil.WriteFile(path, []byte("ab"), 0600), ShouldBeNil)
+
+			reloaded, err := w.Reload()
+
+			Convey("Then err should be returned", func() {
+				So(err, ShouldEqual, loadErr)
+				So(reloaded, ShouldBeFalse)
+				So(calls, ShouldEqual, 2)
+			})
+
+			Convey("Then it should not be loaded again until the file changes", func() {
+				reloaded, err := w.Reload()
+				So(err, ShouldBeNil)
+				So(reloaded, ShouldBeFalse)
+				So(calls, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When the file is removed", func() {
+
+			So(os.Remove(path), ShouldBeNil)
+
+			reloaded, err := w.Reload()
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "unable to stat test file '"+path+"': ")
+				So(reloaded, ShouldBeFalse)
+				So(calls, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestWatcher_Watch(t *testing.T) {
+
+	Convey("Given I have a watcher on a file", t, func() {
+
+		dir, _ := ioutil.TempDir("", "filewatcher")
+		defer os.RemoveAll(dir) // nolint
+
+		path := filepath.Join(dir, "file")
+		So(ioutil.WriteFile(path, []byte("a"), 0600), ShouldBeNil)
+
+		var calls int64
+		w := New("test file", func() error { atomic.AddInt64(&calls, 1); return nil }, path)
+
+		_, err := w.Reload()
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Watch(ctx, 10*time.Millisecond)
+
+		Convey("When the file changes", func() {
+
+			So(ioutil.WriteFile(path, []byte("ab"), 0600), ShouldBeNil)
+
+			Convey("Then it should be reloaded", func() {
+				So(func() bool {
+					for i := 0; i < 100; i++ {
+						if atomic.LoadInt64(&calls) == 2 {
+							return true
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+					return false
+				}(), ShouldBeTrue)
+			})
+		})
+	})
+}
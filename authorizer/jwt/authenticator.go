@@ -0,0 +1,130 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.aporeto.io/bahamut"
+	"go.uber.org/zap"
+)
+
+// An Authenticator is a bahamut.RequestAuthenticator and bahamut.SessionAuthenticator
+// that verifies the JSON Web Token passed by the clients.
+//
+// If the client did not pass a token, or passed something that is not a JWT,
+// the Authenticator returns bahamut.AuthActionContinue so another authenticator
+// can handle the request. If the token is not valid, it returns
+// bahamut.AuthActionKO. Otherwise, it sets the claims converted from the token
+// and returns bahamut.AuthActionOK.
+type Authenticator struct {
+	keys   KeyProvider
+	parser *jwt.Parser
+	cfg    config
+}
+
+// NewAuthenticator returns a new Authenticator verifying the tokens
+// using the keys provided by the given KeyProvider.
+func NewAuthenticator(keys KeyProvider, options ...Option) *Authenticator {
+
+	if keys == nil {
+		panic("keys must not be nil")
+	}
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	return &Authenticator{
+		keys: keys,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(cfg.validMethods),
+			jwt.WithoutClaimsValidation(),
+		),
+		cfg: cfg,
+	}
+}
+
+// AuthenticateRequest implements the bahamut.RequestAuthenticator interface.
+func (a *Authenticator) AuthenticateRequest(ctx bahamut.Context) (bahamut.AuthAction, error) {
+
+	return a.authenticate(ctx.Request().Password, ctx.SetClaims), nil
+}
+
+// AuthenticateSession implements the bahamut.SessionAuthenticator interface.
+func (a *Authenticator) AuthenticateSession(session bahamut.Session) (bahamut.AuthAction, error) {
+
+	return a.authenticate(session.Token(), session.SetClaims), nil
+}
+
+func (a *Authenticator) authenticate(token string, claimSetter func([]string)) bahamut.AuthAction {
+
+	if token == "" {
+		return bahamut.AuthActionContinue
+	}
+
+	claims, err := a.verify(token)
+	if err != nil {
+
+		var verr *jwt.ValidationError
+		if errors.As(err, &verr) && verr.Errors&jwt.ValidationErrorMalformed != 0 {
+			return bahamut.AuthActionContinue
+		}
+
+		zap.L().Debug("Unable to verify token", zap.Error(err))
+		return bahamut.AuthActionKO
+	}
+
+	claimSetter(makeClaims(claims, a.cfg.claimsMap))
+
+	return bahamut.AuthActionOK
+}
+
+func (a *Authenticator) verify(token string) (jwt.MapClaims, error) {
+
+	claims := jwt.MapClaims{}
+
+	if _, err := a.parser.ParseWithClaims(
+		token,
+		claims,
+		func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			return a.keys.Key(kid)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	now := time.Now()
+
+	if !claims.VerifyExpiresAt(now.Add(-a.cfg.leeway).Unix(), true) {
+		return nil, fmt.Errorf("invalid token: expired or missing expiration time")
+	}
+
+	if !claims.VerifyNotBefore(now.Add(a.cfg.leeway).Unix(), false) {
+		return nil, fmt.Errorf("invalid token: not valid yet")
+	}
+
+	if a.cfg.issuer != "" && !claims.VerifyIssuer(a.cfg.issuer, true) {
+		return nil, fmt.Errorf("invalid token: unexpected issuer")
+	}
+
+	if a.cfg.audience != "" && !claims.VerifyAudience(a.cfg.audience, true) {
+		return nil, fmt.Errorf("invalid token: unexpected audience")
+	}
+
+	return claims, nil
+}
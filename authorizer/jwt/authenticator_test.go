@@ -0,0 +1,276 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+)
+
+type mockSession struct {
+	token  string
+	claims []string
+}
+
+func (s *mockSession) Cookie(string) (*http.Cookie, error)      { return nil, nil }
+func (s *mockSession) Identifier() string                       { return "" }
+func (s *mockSession) Parameter(string) string                  { return "" }
+func (s *mockSession) Header(string) string                     { return "" }
+func (s *mockSession) PushConfig() *elemental.PushConfig        { return nil }
+func (s *mockSession) SetClaims(c []string)                     { s.claims = c }
+func (s *mockSession) Claims() []string                         { return s.claims }
+func (s *mockSession) ClaimsMap() map[string]string             { return nil }
+func (s *mockSession) Token() string                            { return s.token }
+func (s *mockSession) TLSConnectionState() *tls.ConnectionState { return nil }
+func (s *mockSession) Metadata() interface{}                    { return nil }
+func (s *mockSession) SetMetadata(interface{})                  {}
+func (s *mockSession) Context() context.Context                 { return context.Background() }
+func (s *mockSession) ClientIP() string                         { return "" }
+
+func makeToken(key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	s, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func TestNewAuthenticator(t *testing.T) {
+
+	Convey("Given I call NewAuthenticator with some options", t, func() {
+
+		keys := StaticKeys{}
+
+		auth := NewAuthenticator(
+			keys,
+			OptionIssuer("iss"),
+			OptionAudience("aud"),
+			OptionLeeway(time.Minute),
+			OptionValidMethods("ES256"),
+			OptionClaimsMapping(map[string]string{"email": "@auth:email"}),
+		)
+
+		Convey("Then it should be correctly initialized", func() {
+			So(auth.keys, ShouldResemble, keys)
+			So(auth.parser, ShouldNotBeNil)
+			So(auth.cfg.issuer, ShouldEqual, "iss")
+			So(auth.cfg.audience, ShouldEqual, "aud")
+			So(auth.cfg.leeway, ShouldEqual, time.Minute)
+			So(auth.cfg.validMethods, ShouldResemble, []string{"ES256"})
+			So(auth.cfg.claimsMap, ShouldResemble, map[string]string{"email": "@auth:email"})
+		})
+	})
+
+	Convey("Given I call NewAuthenticator with no keys", t, func() {
+
+		Convey("Then it should panic", func() {
+			So(func() { NewAuthenticator(nil) }, ShouldPanicWith, "keys must not be nil")
+		})
+	})
+
+	Convey("Given I call the options with invalid values", t, func() {
+
+		Convey("Then it should panic", func() {
+			So(func() { OptionLeeway(-time.Second) }, ShouldPanicWith, "leeway cannot be negative")
+			So(func() { OptionValidMethods() }, ShouldPanicWith, "methods must not be empty")
+		})
+	})
+}
+
+func TestAuthenticator_AuthenticateRequest(t *testing.T) {
+
+	Convey("Given I have an Authenticator", t, func() {
+
+		key, _ := rsa.GenerateKey(rand.Reader, 2048)
+		otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+		auth := NewAuthenticator(
+			StaticKeys{"a": &key.PublicKey},
+			OptionIssuer("me"),
+			OptionAudience("you"),
+			OptionClaimsMapping(map[string]string{
+				"sub":    "@auth:subject",
+				"groups": "@auth:group",
+			}),
+		)
+
+		exp := time.Now().Add(time.Hour).Unix()
+
+		Convey("When I authenticate a request with a valid token", func() {
+
+			ctx := bahamut.NewContext(context.Background(), &elemental.Request{
+				Password: makeToken(key, "a", jwt.MapClaims{
+					"iss":    "me",
+					"aud":    "you",
+					"sub":    "bob",
+					"exp":    exp,
+					"groups": []string{"a", "b"},
+				}),
+			})
+
+			action, err := auth.AuthenticateRequest(ctx)
+
+			Convey("Then action should be OK", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+
+			Convey("Then the claims should be set", func() {
+				So(ctx.Claims(), ShouldResemble, []string{
+					"@auth:realm=jwt",
+					"@auth:group=a",
+					"@auth:group=b",
+					"@auth:subject=bob",
+				})
+			})
+		})
+
+		Convey("When I authenticate a request with no token", func() {
+
+			ctx := bahamut.NewContext(context.Background(), &elemental.Request{})
+
+			action, err := auth.AuthenticateRequest(ctx)
+
+			Convey("Then action should be Continue", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionContinue)
+				So(ctx.Claims(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I authenticate a request with something that is not a jwt", func() {
+
+			ctx := bahamut.NewContext(context.Background(), &elemental.Request{Password: "not-a-jwt"})
+
+			action, err := auth.AuthenticateRequest(ctx)
+
+			Convey("Then action should be Continue", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionContinue)
+			})
+		})
+
+		Convey("When I authenticate requests with invalid tokens", func() {
+
+			for _, token := range []string{
+				makeToken(otherKey, "a", jwt.MapClaims{"iss": "me", "aud": "you", "exp": exp}),
+				makeToken(key, "b", jwt.MapClaims{"iss": "me", "aud": "you", "exp": exp}),
+				makeToken(key, "a", jwt.MapClaims{"iss": "me", "aud": "you"}),
+				makeToken(key, "a", jwt.MapClaims{"iss": "me", "aud": "you", "exp": time.Now().Add(-time.Minute).Unix()}),
+				makeToken(key, "a", jwt.MapClaims{"iss": "me", "aud": "you", "exp": exp, "nbf": time.Now().Add(time.Minute).Unix()}),
+				makeToken(key, "a", jwt.MapClaims{"iss": "not-me", "aud": "you", "exp": exp}),
+				makeToken(key, "a", jwt.MapClaims{"iss": "me", "aud": "not-you", "exp": exp}),
+			} {
+
+				ctx := bahamut.NewContext(context.Background(), &elemental.Request{Password: token})
+
+				action, err := auth.AuthenticateRequest(ctx)
+
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+				So(ctx.Claims(), ShouldBeEmpty)
+			}
+		})
+
+		Convey("When I authenticate a slightly expired token with a leeway", func() {
+
+			auth := NewAuthenticator(StaticKeys{"a": &key.PublicKey}, OptionLeeway(time.Minute))
+
+			ctx := bahamut.NewContext(context.Background(), &elemental.Request{
+				Password: makeToken(key, "a", jwt.MapClaims{"exp": time.Now().Add(-10 * time.Second).Unix()}),
+			})
+
+			action, err := auth.AuthenticateRequest(ctx)
+
+			Convey("Then action should be OK", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+		})
+	})
+}
+
+func TestAuthenticator_AuthenticateSession(t *testing.T) {
+
+	Convey("Given I have an Authenticator", t, func() {
+
+		key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+		auth := NewAuthenticator(StaticKeys{"a": &key.PublicKey})
+
+		Convey("When I authenticate a session with a valid token", func() {
+
+			session := &mockSession{
+				token: makeToken(key, "a", jwt.MapClaims{
+					"iss": "me",
+					"sub": "bob",
+					"exp": time.Now().Add(time.Hour).Unix(),
+				}),
+			}
+
+			action, err := auth.AuthenticateSession(session)
+
+			Convey("Then action should be OK", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+
+			Convey("Then the claims should be set", func() {
+				So(session.claims, ShouldResemble, []string{
+					"@auth:realm=jwt",
+					"@auth:issuer=me",
+					"@auth:subject=bob",
+				})
+			})
+		})
+
+		Convey("When I authenticate a session with an expired token", func() {
+
+			session := &mockSession{
+				token: makeToken(key, "a", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}),
+			}
+
+			action, err := auth.AuthenticateSession(session)
+
+			Convey("Then action should be KO", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+				So(session.claims, ShouldBeNil)
+			})
+		})
+
+		Convey("When I authenticate a session with no token", func() {
+
+			action, err := auth.AuthenticateSession(&mockSession{})
+
+			Convey("Then action should be Continue", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionContinue)
+			})
+		})
+	})
+}
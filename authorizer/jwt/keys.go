@@ -0,0 +1,320 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// jwksMinRefreshInterval is the minimum time between
+// two refreshes triggered by an unknown key ID.
+const jwksMinRefreshInterval = 10 * time.Second
+
+// A KeyProvider provides the public keys used to
+// verify the signature of the tokens.
+type KeyProvider interface {
+
+	// Key returns the key associated to the given key ID.
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// StaticKeys is a KeyProvider holding a fixed set of keys
+// indexed by key ID. The key associated to the empty key ID
+// is used to verify tokens that don't have a key ID.
+type StaticKeys map[string]crypto.PublicKey
+
+// Key implements the KeyProvider interface.
+func (k StaticKeys) Key(kid string) (crypto.PublicKey, error) {
+
+	key, ok := k[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", kid)
+	}
+
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// A JWKS is a KeyProvider holding the public keys of a
+// JSON Web Key Set located either in a file or behind an URL.
+//
+// When a token is signed with an unknown key ID, the key set
+// is reloaded from its location, as the keys may have been rotated.
+type JWKS struct {
+	location    string
+	client      *http.Client
+	keys        map[string]crypto.PublicKey
+	lastAttempt time.Time
+
+	sync.RWMutex
+}
+
+// NewJWKS returns a new JWKS loaded from the given location.
+// The location can either be a path to a file or an http(s) URL.
+// It returns an error if the key set cannot be loaded.
+func NewJWKS(location string) (*JWKS, error) {
+
+	j := &JWKS{
+		location: location,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     map[string]crypto.PublicKey{},
+	}
+
+	if err := j.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// Key implements the KeyProvider interface.
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+
+	j.RLock()
+	k, ok := j.keys[kid]
+	j.RUnlock()
+
+	if ok {
+		return k, nil
+	}
+
+	// We make sure an attacker cannot make us
+	// reload the key set on every request.
+	j.Lock()
+	if time.Since(j.lastAttempt) < jwksMinRefreshInterval {
+		j.Unlock()
+		return nil, fmt.Errorf("unknown key id '%s'", kid)
+	}
+	j.lastAttempt = time.Now()
+	j.Unlock()
+
+	if err := j.Refresh(); err != nil {
+		return nil, fmt.Errorf("unknown key id '%s': unable to refresh key set: %s", kid, err)
+	}
+
+	j.RLock()
+	k, ok = j.keys[kid]
+	j.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", kid)
+	}
+
+	return k, nil
+}
+
+// Refresh reloads the key set from its location.
+func (j *JWKS) Refresh() error {
+
+	data, err := j.read()
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	j.Lock()
+	j.keys = keys
+	j.Unlock()
+
+	return nil
+}
+
+// Poll refreshes the key set at the given interval until
+// the given context is canceled. It is blocking and
+// should be run in its own goroutine.
+func (j *JWKS) Poll(ctx context.Context, interval time.Duration) {
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.Refresh(); err != nil {
+				zap.L().Error("Unable to refresh jwks", zap.String("location", j.location), zap.Error(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (j *JWKS) read() ([]byte, error) {
+
+	if !strings.HasPrefix(j.location, "http://") && !strings.HasPrefix(j.location, "https://") {
+
+		data, err := ioutil.ReadFile(j.location)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read jwks file: %s", err)
+		}
+
+		return data, nil
+	}
+
+	resp, err := j.client.Get(j.location)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve jwks: %s", err)
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to retrieve jwks: unexpected status code %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read jwks: %s", err)
+	}
+
+	return data, nil
+}
+
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+
+	set := jsonWebKeySet{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("unable to decode jwks: %s", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+
+	for _, k := range set.Keys {
+
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// The key sets can contain keys of types we don't
+		// support, like OKP or oct. We simply ignore them.
+		if k.Kty != "RSA" && k.Kty != "EC" {
+			continue
+		}
+
+		pk, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode key '%s': %s", k.Kid, err)
+		}
+
+		keys[k.Kid] = pk
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+
+	switch k.Kty {
+
+	case "RSA":
+
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %s", err)
+		}
+
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %s", err)
+		}
+
+		if !e.IsInt64() {
+			return nil, fmt.Errorf("invalid exponent: too large")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %s", err)
+		}
+
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %s", err)
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+
+	if s == "" {
+		return nil, fmt.Errorf("empty value")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
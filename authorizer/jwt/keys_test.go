@@ -0,0 +1,253 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func makeRSAJWK(kid string, key *rsa.PrivateKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func makeECJWK(kid string, key *ecdsa.PrivateKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.Bytes()),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.Bytes()),
+	}
+}
+
+func makeJWKSData(keys ...jsonWebKey) []byte {
+	data, err := json.Marshal(jsonWebKeySet{Keys: keys})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestStaticKeys(t *testing.T) {
+
+	Convey("Given I have static keys", t, func() {
+
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		keys := StaticKeys{"a": &rsaKey.PublicKey}
+
+		Convey("When I retrieve a known key", func() {
+
+			k, err := keys.Key("a")
+
+			Convey("Then it should work", func() {
+				So(err, ShouldBeNil)
+				So(k, ShouldEqual, &rsaKey.PublicKey)
+			})
+		})
+
+		Convey("When I retrieve an unknown key", func() {
+
+			k, err := keys.Key("b")
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unknown key id 'b'")
+				So(k, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestParseJWKS(t *testing.T) {
+
+	Convey("Given I have a RSA and an EC key", t, func() {
+
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+		Convey("When I parse a valid jwks", func() {
+
+			keys, err := parseJWKS(makeJWKSData(makeRSAJWK("a", rsaKey), makeECJWK("b", ecKey)))
+
+			Convey("Then the keys should be correct", func() {
+				So(err, ShouldBeNil)
+				So(len(keys), ShouldEqual, 2)
+				So(keys["a"], ShouldResemble, &rsaKey.PublicKey)
+				So(keys["b"].(*ecdsa.PublicKey).X, ShouldResemble, ecKey.X)
+				So(keys["b"].(*ecdsa.PublicKey).Y, ShouldResemble, ecKey.Y)
+			})
+		})
+
+		Convey("When I parse a jwks with an encryption key", func() {
+
+			k := makeRSAJWK("a", rsaKey)
+			k.Use = "enc"
+			keys, err := parseJWKS(makeJWKSData(k))
+
+			Convey("Then the key should be ignored", func() {
+				So(err, ShouldBeNil)
+				So(len(keys), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I parse a jwks with keys of unsupported types", func() {
+
+			keys, err := parseJWKS(makeJWKSData(
+				jsonWebKey{Kty: "oct", Kid: "a"},
+				jsonWebKey{Kty: "OKP", Kid: "b", Crv: "Ed25519"},
+				makeRSAJWK("c", rsaKey),
+			))
+
+			Convey("Then they should be ignored", func() {
+				So(err, ShouldBeNil)
+				So(len(keys), ShouldEqual, 1)
+				So(keys["c"], ShouldResemble, &rsaKey.PublicKey)
+			})
+		})
+
+		Convey("When I parse a jwks with an invalid EC point", func() {
+
+			k := makeECJWK("b", ecKey)
+			k.Y = k.X
+			keys, err := parseJWKS(makeJWKSData(k))
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unable to decode key 'b': point is not on curve")
+				So(keys, ShouldBeNil)
+			})
+		})
+
+		Convey("When I parse invalid data", func() {
+
+			keys, err := parseJWKS([]byte("{"))
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(keys, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestJWKS(t *testing.T) {
+
+	Convey("Given I have a jwks file", t, func() {
+
+		rsaKey1, _ := rsa.GenerateKey(rand.Reader, 2048)
+		rsaKey2, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+		dir, _ := ioutil.TempDir("", "jwks")
+		defer os.RemoveAll(dir) // nolint
+
+		path := filepath.Join(dir, "jwks.json")
+		So(ioutil.WriteFile(path, makeJWKSData(makeRSAJWK("a", rsaKey1)), 0600), ShouldBeNil)
+
+		j, err := NewJWKS(path)
+		So(err, ShouldBeNil)
+
+		Convey("When I retrieve the key", func() {
+
+			k, err := j.Key("a")
+
+			Convey("Then it should be correct", func() {
+				So(err, ShouldBeNil)
+				So(k, ShouldResemble, &rsaKey1.PublicKey)
+			})
+		})
+
+		Convey("When the key is rotated", func() {
+
+			So(ioutil.WriteFile(path, makeJWKSData(makeRSAJWK("b", rsaKey2)), 0600), ShouldBeNil)
+
+			k, err := j.Key("b")
+
+			Convey("Then the new key should be loaded", func() {
+				So(err, ShouldBeNil)
+				So(k, ShouldResemble, &rsaKey2.PublicKey)
+			})
+
+			Convey("Then retrieving an unknown key should not refresh again", func() {
+
+				j.lastAttempt = time.Now()
+				So(ioutil.WriteFile(path, makeJWKSData(makeRSAJWK("c", rsaKey1)), 0600), ShouldBeNil)
+
+				k, err := j.Key("c")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unknown key id 'c'")
+				So(k, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have a jwks server", t, func() {
+
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(makeJWKSData(makeRSAJWK("a", rsaKey)))
+		}))
+		defer ts.Close()
+
+		Convey("When I call NewJWKS", func() {
+
+			j, err := NewJWKS(ts.URL)
+
+			Convey("Then the key should be loaded", func() {
+				So(err, ShouldBeNil)
+				k, err := j.Key("a")
+				So(err, ShouldBeNil)
+				So(k, ShouldResemble, &rsaKey.PublicKey)
+			})
+		})
+	})
+
+	Convey("Given I have a jwks server that fails", t, func() {
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer ts.Close()
+
+		Convey("When I call NewJWKS", func() {
+
+			j, err := NewJWKS(ts.URL)
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unable to retrieve jwks: unexpected status code 500")
+				So(j, ShouldBeNil)
+			})
+		})
+	})
+}
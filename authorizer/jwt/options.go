@@ -0,0 +1,94 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import "time"
+
+type config struct {
+	issuer       string
+	audience     string
+	validMethods []string
+	leeway       time.Duration
+	claimsMap    map[string]string
+}
+
+func newConfig() config {
+	return config{
+		validMethods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"},
+		claimsMap: map[string]string{
+			"iss": "@auth:issuer",
+			"sub": "@auth:subject",
+		},
+	}
+}
+
+// An Option represents a configuration option
+// for the Authenticator.
+type Option func(*config)
+
+// OptionIssuer sets the issuer the tokens must have
+// in their iss claim. If empty, the issuer is not checked.
+func OptionIssuer(issuer string) Option {
+	return func(c *config) {
+		c.issuer = issuer
+	}
+}
+
+// OptionAudience sets the audience the tokens must have
+// in their aud claim. If empty, the audience is not checked.
+func OptionAudience(audience string) Option {
+	return func(c *config) {
+		c.audience = audience
+	}
+}
+
+// OptionValidMethods sets the signing methods that are
+// accepted. By default, only RS*, PS* and ES* are accepted.
+func OptionValidMethods(methods ...string) Option {
+
+	if len(methods) == 0 {
+		panic("methods must not be empty")
+	}
+
+	return func(c *config) {
+		c.validMethods = methods
+	}
+}
+
+// OptionLeeway sets the leeway to apply when checking
+// the exp and nbf claims, to account for clock skew.
+func OptionLeeway(leeway time.Duration) Option {
+
+	if leeway < 0 {
+		panic("leeway cannot be negative")
+	}
+
+	return func(c *config) {
+		c.leeway = leeway
+	}
+}
+
+// OptionClaimsMapping sets how the claims of the token are converted
+// into bahamut claims. The keys of the given map are the names of the
+// token claims and the values are the keys of the resulting bahamut claims.
+//
+// For instance, the mapping {"email": "@auth:email"} will convert the
+// token claim "email": "a@b.com" into the bahamut claim "@auth:email=a@b.com".
+// Claims holding a list are converted into one bahamut claim per item.
+//
+// By default, iss and sub are mapped to @auth:issuer and @auth:subject.
+// The claim @auth:realm=jwt is always added.
+func OptionClaimsMapping(mapping map[string]string) Option {
+	return func(c *config) {
+		c.claimsMap = mapping
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	"sort"
+	"strconv"
+)
+
+func makeClaims(claims map[string]interface{}, mapping map[string]string) []string {
+
+	out := []string{"@auth:realm=jwt"}
+
+	names := make([]string, 0, len(mapping))
+	for name := range mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		key := mapping[name]
+
+		switch v := claims[name].(type) {
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := claimValue(item); ok {
+					out = append(out, key+"="+s)
+				}
+			}
+		default:
+			if s, ok := claimValue(v); ok {
+				out = append(out, key+"="+s)
+			}
+		}
+	}
+
+	return out
+}
+
+func claimValue(v interface{}) (string, bool) {
+
+	switch tv := v.(type) {
+	case string:
+		return tv, true
+	case float64:
+		return strconv.FormatFloat(tv, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(tv), true
+	default:
+		return "", false
+	}
+}
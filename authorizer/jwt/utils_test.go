@@ -0,0 +1,65 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMakeClaims(t *testing.T) {
+
+	Convey("Given I have some token claims", t, func() {
+
+		claims := map[string]interface{}{
+			"sub":    "bob",
+			"iss":    "issuer",
+			"admin":  true,
+			"level":  float64(3),
+			"groups": []interface{}{"a", "b", map[string]interface{}{}},
+			"nested": map[string]interface{}{"a": "b"},
+		}
+
+		Convey("When I call makeClaims with a mapping", func() {
+
+			out := makeClaims(claims, map[string]string{
+				"sub":     "@auth:subject",
+				"admin":   "@auth:admin",
+				"level":   "@auth:level",
+				"groups":  "@auth:group",
+				"nested":  "@auth:nested",
+				"missing": "@auth:missing",
+			})
+
+			Convey("Then the claims should be correct", func() {
+				So(out, ShouldResemble, []string{
+					"@auth:realm=jwt",
+					"@auth:admin=true",
+					"@auth:group=a",
+					"@auth:group=b",
+					"@auth:level=3",
+					"@auth:subject=bob",
+				})
+			})
+		})
+
+		Convey("When I call makeClaims without mapping", func() {
+
+			out := makeClaims(claims, nil)
+
+			Convey("Then only the realm should be set", func() {
+				So(out, ShouldResemble, []string{"@auth:realm=jwt"})
+			})
+		})
+	})
+}
@@ -117,6 +117,7 @@ type mtlsVerifier struct {
 	deciderFunc          DeciderFunc
 	verifier             VerifierFunc
 	certificateCheckMode CertificateCheckMode
+	cfg                  config
 }
 
 func newMTLSVerifier(
@@ -125,14 +126,21 @@ func newMTLSVerifier(
 	ignoredIdentities []elemental.Identity,
 	verifier VerifierFunc,
 	certificateCheckMode CertificateCheckMode,
+	options ...Option,
 ) *mtlsVerifier {
 
+	cfg := config{}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
 	return &mtlsVerifier{
 		verifyOptions:        verifyOptions,
 		ignoredIdentities:    ignoredIdentities,
 		deciderFunc:          deciderFunc,
 		verifier:             verifier,
 		certificateCheckMode: certificateCheckMode,
+		cfg:                  cfg,
 	}
 }
 
@@ -142,15 +150,18 @@ func newMTLSVerifier(
 //
 // deciderFunc is the DeciderFunc to used return the actual action you want the Authorizer
 // to return.
+//
+// Additional behaviors, like SPIFFE support, can be configured using the given Options.
 func NewMTLSAuthorizer(
 	verifyOptions x509.VerifyOptions,
 	deciderFunc DeciderFunc,
 	ignoredIdentities []elemental.Identity,
 	certVerifier VerifierFunc,
 	certificateCheckMode CertificateCheckMode,
+	options ...Option,
 ) bahamut.Authorizer {
 
-	return newMTLSVerifier(verifyOptions, deciderFunc, ignoredIdentities, certVerifier, certificateCheckMode)
+	return newMTLSVerifier(verifyOptions, deciderFunc, ignoredIdentities, certVerifier, certificateCheckMode, options...)
 }
 
 // NewMTLSRequestAuthenticator returns a new Authenticator that ensures the client certificate
@@ -159,14 +170,17 @@ func NewMTLSAuthorizer(
 //
 // deciderFunc is the DeciderFunc to used return the actual action you want the RequestAuthenticator
 // to return.
+//
+// Additional behaviors, like SPIFFE support, can be configured using the given Options.
 func NewMTLSRequestAuthenticator(
 	verifyOptions x509.VerifyOptions,
 	deciderFunc DeciderFunc,
 	certVerifier VerifierFunc,
 	certificateCheckMode CertificateCheckMode,
+	options ...Option,
 ) bahamut.RequestAuthenticator {
 
-	return newMTLSVerifier(verifyOptions, deciderFunc, nil, certVerifier, certificateCheckMode)
+	return newMTLSVerifier(verifyOptions, deciderFunc, nil, certVerifier, certificateCheckMode, options...)
 }
 
 // NewMTLSSessionAuthenticator returns a new Authenticator that ensures the client certificate are
@@ -175,14 +189,17 @@ func NewMTLSRequestAuthenticator(
 //
 // deciderFunc is the DeciderFunc to used return the actual action you want the SessionAuthenticator
 // to return.
+//
+// Additional behaviors, like SPIFFE support, can be configured using the given Options.
 func NewMTLSSessionAuthenticator(
 	verifyOptions x509.VerifyOptions,
 	deciderFunc DeciderFunc,
 	certVerifier VerifierFunc,
 	certificateCheckMode CertificateCheckMode,
+	options ...Option,
 ) bahamut.SessionAuthenticator {
 
-	return newMTLSVerifier(verifyOptions, deciderFunc, nil, certVerifier, certificateCheckMode)
+	return newMTLSVerifier(verifyOptions, deciderFunc, nil, certVerifier, certificateCheckMode, options...)
 }
 
 func (a *mtlsVerifier) IsAuthorized(ctx bahamut.Context) (bahamut.AuthAction, error) {
@@ -219,10 +236,12 @@ func (a *mtlsVerifier) IsAuthorized(ctx bahamut.Context) (bahamut.AuthAction, er
 
 	// If we can verify, we return the success auth action.
 	for _, cert := range certs {
-		if _, err := cert.Verify(a.verifyOptions); err == nil {
-			if a.verifier == nil || a.verifier(cert) {
-				return a.deciderFunc(bahamut.AuthActionOK, ctx, nil), nil
-			}
+		ok, err := a.verify(cert)
+		if err != nil {
+			return bahamut.AuthActionKO, err
+		}
+		if ok {
+			return a.deciderFunc(bahamut.AuthActionOK, ctx, nil), nil
 		}
 	}
 
@@ -276,11 +295,13 @@ func (a *mtlsVerifier) checkAction(tlsState *tls.ConnectionState, headerCert str
 
 	// If we can verify, we return the success auth action
 	for _, cert := range certs {
-		if _, err := cert.Verify(a.verifyOptions); err == nil {
-			if a.verifier == nil || a.verifier(cert) {
-				claimSetter(makeClaims(cert))
-				return bahamut.AuthActionOK, nil
-			}
+		ok, err := a.verify(cert)
+		if err != nil {
+			return bahamut.AuthActionKO, err
+		}
+		if ok {
+			claimSetter(makeClaims(cert, a.cfg.extensionClaims))
+			return bahamut.AuthActionOK, nil
 		}
 	}
 
@@ -288,6 +309,46 @@ func (a *mtlsVerifier) checkAction(tlsState *tls.ConnectionState, headerCert str
 	return bahamut.AuthActionKO, nil
 }
 
+// verify returns true if the given certificate can be verified
+// and is accepted by the VerifierFunc. If the certificate, or
+// any certificate of its chain, has been revoked, it returns
+// an error.
+func (a *mtlsVerifier) verify(cert *x509.Certificate) (bool, error) {
+
+	opts := a.verifyOptions
+
+	if a.cfg.spiffeBundles != nil {
+
+		id, err := SPIFFEID(cert)
+		if err != nil {
+			return false, nil
+		}
+
+		if opts.Roots = a.cfg.spiffeBundles.Pool(id.Host); opts.Roots == nil {
+			return false, nil
+		}
+	}
+
+	chains, err := cert.Verify(opts)
+	if err != nil {
+		return false, nil
+	}
+
+	if a.cfg.crlStore != nil {
+		for _, chain := range chains {
+			// The last certificate of the chain is
+			// the root CA and cannot be revoked.
+			for i := 0; i < len(chain)-1; i++ {
+				if a.cfg.crlStore.IsRevoked(chain[i]) {
+					return false, makeRevokedError(chain[i])
+				}
+			}
+		}
+	}
+
+	return a.verifier == nil || a.verifier(cert), nil
+}
+
 func decodeCertHeader(header string) ([]*x509.Certificate, error) {
 
 	if len(header) < 54 {
@@ -0,0 +1,214 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aporeto.io/bahamut/authorizer/internal/filewatcher"
+	"go.aporeto.io/elemental"
+)
+
+// CRLStore holds the certificate revocation lists loaded from
+// a set of files, in PEM or DER format.
+//
+// The files are periodically checked for changes so the lists
+// can be updated. Each list must be signed by one of the trusted
+// issuers and must not be past its next update.
+type CRLStore struct {
+	paths   []string
+	issuers []*x509.Certificate
+	revoked map[string]struct{}
+	watcher *filewatcher.Watcher
+
+	sync.RWMutex
+}
+
+// NewCRLStore returns a new CRLStore loading the revocation lists from the
+// given files. The lists must be signed by one of the given issuers, which
+// are the CA certificates allowed to sign them. It returns an error if the
+// lists cannot be loaded, if one of them is not signed by an issuer, or if
+// one of them has expired.
+//
+// If refreshInterval is greater than 0, the files are checked for
+// changes at that interval until the given context is canceled. An
+// unreadable, forged or expired list is logged as an error and leaves
+// the store with the lists it loaded last.
+func NewCRLStore(ctx context.Context, issuers []*x509.Certificate, refreshInterval time.Duration, paths ...string) (*CRLStore, error) {
+
+	if len(issuers) == 0 {
+		return nil, fmt.Errorf("at least one crl issuer must be given")
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("at least one crl file must be given")
+	}
+
+	s := &CRLStore{
+		paths:   paths,
+		issuers: issuers,
+	}
+	s.watcher = filewatcher.New("crl file", s.load, paths...)
+
+	if _, err := s.watcher.Reload(); err != nil {
+		return nil, err
+	}
+
+	if refreshInterval > 0 {
+		go s.watcher.Watch(ctx, refreshInterval)
+	}
+
+	return s, nil
+}
+
+// IsRevoked returns true if the given certificate
+// is revoked by one of the lists.
+func (s *CRLStore) IsRevoked(cert *x509.Certificate) bool {
+
+	s.RLock()
+	defer s.RUnlock()
+
+	_, ok := s.revoked[revocationKey(cert.RawIssuer, cert.SerialNumber)]
+
+	return ok
+}
+
+// load loads the lists from the files.
+func (s *CRLStore) load() error {
+
+	revoked := map[string]struct{}{}
+	now := time.Now()
+
+	for _, p := range s.paths {
+
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("unable to read crl file '%s': %s", p, err)
+		}
+
+		lists, err := parseCRLs(data)
+		if err != nil {
+			return fmt.Errorf("unable to parse crl file '%s': %s", p, err)
+		}
+
+		for _, l := range lists {
+
+			if err := s.check(l, now); err != nil {
+				return fmt.Errorf("invalid crl file '%s': %s", p, err)
+			}
+
+			for _, entry := range l.RevokedCertificateEntries {
+				revoked[revocationKey(l.RawIssuer, entry.SerialNumber)] = struct{}{}
+			}
+		}
+	}
+
+	s.Lock()
+	s.revoked = revoked
+	s.Unlock()
+
+	return nil
+}
+
+// check returns an error if the given list is not
+// signed by one of the issuers or if it has expired.
+func (s *CRLStore) check(l *x509.RevocationList, now time.Time) error {
+
+	var signed bool
+	for _, issuer := range s.issuers {
+		if bytes.Equal(issuer.RawSubject, l.RawIssuer) && l.CheckSignatureFrom(issuer) == nil {
+			signed = true
+			break
+		}
+	}
+
+	if !signed {
+		return fmt.Errorf("not signed by a trusted issuer")
+	}
+
+	if !l.NextUpdate.IsZero() && now.After(l.NextUpdate) {
+		return fmt.Errorf("expired since %s", l.NextUpdate.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// parseCRLs parses the revocation lists contained in
+// the given data, either as PEM blocks or as DER.
+func parseCRLs(data []byte) ([]*x509.RevocationList, error) {
+
+	if !strings.Contains(string(data), "-----BEGIN") {
+
+		l, err := x509.ParseRevocationList(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*x509.RevocationList{l}, nil
+	}
+
+	var lists []*x509.RevocationList
+	var block *pem.Block
+	rest := data
+
+	for {
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "X509 CRL" {
+			continue
+		}
+
+		l, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, l)
+	}
+
+	if len(lists) == 0 {
+		return nil, fmt.Errorf("no crl found")
+	}
+
+	return lists, nil
+}
+
+func revocationKey(rawIssuer []byte, serial *big.Int) string {
+
+	return string(rawIssuer) + "/" + serial.String()
+}
+
+// makeRevokedError returns the error returned when
+// the given certificate has been revoked.
+func makeRevokedError(cert *x509.Certificate) error {
+
+	return elemental.NewError(
+		"Certificate Revoked",
+		fmt.Sprintf("The certificate '%s' with serial number %s has been revoked.", cert.Subject.CommonName, cert.SerialNumber),
+		"bahamut",
+		http.StatusUnauthorized,
+	)
+}
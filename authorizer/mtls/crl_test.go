@@ -0,0 +1,417 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+)
+
+func writeCRL(path string, pemEncoded bool, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey, revoked ...*x509.Certificate) {
+	writeCRLWithNextUpdate(path, pemEncoded, time.Now().Add(time.Hour), issuer, issuerKey, revoked...)
+}
+
+func writeCRLWithNextUpdate(path string, pemEncoded bool, nextUpdate time.Time, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey, revoked ...*x509.Certificate) {
+
+	var entries []x509.RevocationListEntry
+	for _, c := range revoked {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: c.SerialNumber, RevocationTime: time.Now()})
+	}
+
+	der, err := x509.CreateRevocationList(
+		rand.Reader,
+		&x509.RevocationList{
+			Number:                    big.NewInt(time.Now().UnixNano()),
+			ThisUpdate:                nextUpdate.Add(-2 * time.Hour),
+			NextUpdate:                nextUpdate,
+			RevokedCertificateEntries: entries,
+		},
+		issuer,
+		issuerKey,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	data := der
+	if pemEncoded {
+		data = pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	}
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		panic(err)
+	}
+}
+
+func certToHeader(cert *x509.Certificate) string {
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+
+	return strings.Replace(strings.TrimSpace(string(data)), "\n", " ", -1)
+}
+
+func TestNewCRLStore(t *testing.T) {
+
+	Convey("Given I have a directory", t, func() {
+
+		dir, _ := ioutil.TempDir("", "crl")
+		defer os.RemoveAll(dir) // nolint
+
+		ca, caKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+		cert1, _ := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "cert1"}}, ca, caKey)
+		cert2, _ := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "cert2"}}, ca, caKey)
+
+		Convey("When I create a store without path", func() {
+
+			_, err := NewCRLStore(context.Background(), []*x509.Certificate{ca}, 0)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "at least one crl file must be given")
+			})
+		})
+
+		Convey("When I create a store without issuer", func() {
+
+			_, err := NewCRLStore(context.Background(), nil, 0, filepath.Join(dir, "ca.pem"))
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "at least one crl issuer must be given")
+			})
+		})
+
+		Convey("When I create a store with a missing file", func() {
+
+			_, err := NewCRLStore(context.Background(), []*x509.Certificate{ca}, 0, filepath.Join(dir, "missing.crl"))
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "unable to stat crl file")
+			})
+		})
+
+		Convey("When I create a store with an invalid file", func() {
+
+			p := filepath.Join(dir, "invalid.crl")
+			So(ioutil.WriteFile(p, []byte("not a crl"), 0600), ShouldBeNil)
+
+			_, err := NewCRLStore(context.Background(), []*x509.Certificate{ca}, 0, p)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "unable to parse crl file")
+			})
+		})
+
+		Convey("When I create a store with a pem and a der file", func() {
+
+			p1 := filepath.Join(dir, "1.pem")
+			p2 := filepath.Join(dir, "2.crl")
+			writeCRL(p1, true, ca, caKey, cert1)
+			writeCRL(p2, false, ca, caKey)
+
+			s, err := NewCRLStore(context.Background(), []*x509.Certificate{ca}, 0, p1, p2)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the revoked certificates should be reported", func() {
+				So(s.IsRevoked(cert1), ShouldBeTrue)
+				So(s.IsRevoked(cert2), ShouldBeFalse)
+				So(s.IsRevoked(ca), ShouldBeFalse)
+			})
+		})
+
+		Convey("When I create a store with a crl signed by another ca", func() {
+
+			other, otherKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+
+			p := filepath.Join(dir, "forged.pem")
+			writeCRL(p, true, other, otherKey, cert1)
+
+			_, err := NewCRLStore(context.Background(), []*x509.Certificate{ca}, 0, p)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid crl file '"+p+"': not signed by a trusted issuer")
+			})
+		})
+
+		Convey("When I create a store with an expired crl", func() {
+
+			p := filepath.Join(dir, "expired.pem")
+			writeCRLWithNextUpdate(p, true, time.Now().Add(-time.Minute), ca, caKey, cert1)
+
+			_, err := NewCRLStore(context.Background(), []*x509.Certificate{ca}, 0, p)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "invalid crl file '"+p+"': expired since ")
+			})
+		})
+
+		Convey("When a certificate with the same serial is issued by another ca", func() {
+
+			p := filepath.Join(dir, "ca.pem")
+			writeCRL(p, true, ca, caKey, cert1)
+
+			other, otherKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "other"}}, nil, nil)
+			template := &x509.Certificate{Subject: pkix.Name{CommonName: "cert1"}}
+			c, _ := issueTestCert(template, other, otherKey)
+			c.SerialNumber = cert1.SerialNumber
+
+			s, err := NewCRLStore(context.Background(), []*x509.Certificate{ca}, 0, p)
+			So(err, ShouldBeNil)
+
+			Convey("Then it should not be reported as revoked", func() {
+				So(s.IsRevoked(c), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestCRLStoreReload(t *testing.T) {
+
+	Convey("Given I have a store watching a crl file", t, func() {
+
+		dir, _ := ioutil.TempDir("", "crl")
+		defer os.RemoveAll(dir) // nolint
+
+		ca, caKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+		cert, _ := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "cert"}}, ca, caKey)
+
+		p := filepath.Join(dir, "ca.pem")
+		writeCRL(p, true, ca, caKey)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		s, err := NewCRLStore(ctx, []*x509.Certificate{ca}, 10*time.Millisecond, p)
+		So(err, ShouldBeNil)
+		So(s.IsRevoked(cert), ShouldBeFalse)
+
+		Convey("When the file is updated", func() {
+
+			writeCRL(p, true, ca, caKey, cert)
+			So(os.Chtimes(p, time.Now().Add(time.Minute), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			Convey("Then the certificate should be revoked", func() {
+				So(func() bool {
+					for i := 0; i < 100; i++ {
+						if s.IsRevoked(cert) {
+							return true
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+					return false
+				}(), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given I have a store loaded from a crl file", t, func() {
+
+		dir, _ := ioutil.TempDir("", "crl")
+		defer os.RemoveAll(dir) // nolint
+
+		ca, caKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+		cert, _ := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "cert"}}, ca, caKey)
+
+		p := filepath.Join(dir, "ca.pem")
+		writeCRL(p, true, ca, caKey)
+
+		s, err := NewCRLStore(context.Background(), []*x509.Certificate{ca}, 0, p)
+		So(err, ShouldBeNil)
+		So(s.IsRevoked(cert), ShouldBeFalse)
+
+		Convey("When the file is updated with a list signed by another ca", func() {
+
+			other, otherKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+			writeCRL(p, true, other, otherKey, cert)
+			So(os.Chtimes(p, time.Now().Add(time.Minute), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			Convey("Then the previous lists should be kept", func() {
+				reloaded, err := s.watcher.Reload()
+				So(err, ShouldNotBeNil)
+				So(reloaded, ShouldBeFalse)
+				So(s.IsRevoked(cert), ShouldBeFalse)
+			})
+		})
+
+		Convey("When the file is updated with an invalid content", func() {
+
+			So(ioutil.WriteFile(p, []byte("not a crl"), 0600), ShouldBeNil)
+			So(os.Chtimes(p, time.Now().Add(time.Minute), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			Convey("Then the previous lists should be kept", func() {
+				reloaded, err := s.watcher.Reload()
+				So(err, ShouldNotBeNil)
+				So(reloaded, ShouldBeFalse)
+				So(s.IsRevoked(cert), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestCRLStoreAuthenticator(t *testing.T) {
+
+	Convey("Given I have a chain of certificates and a crl store", t, func() {
+
+		dir, _ := ioutil.TempDir("", "crl")
+		defer os.RemoveAll(dir) // nolint
+
+		ca, caKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+		intermediate, intermediateKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "intermediate"}, IsCA: true}, ca, caKey)
+		revokedIntermediate, revokedIntermediateKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "revoked-intermediate"}, IsCA: true}, ca, caKey)
+
+		valid, _ := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "valid"}}, ca, caKey)
+		revoked, _ := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "revoked"}}, ca, caKey)
+		validLeaf, _ := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "valid-leaf"}}, intermediate, intermediateKey)
+		revokedChain, _ := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "revoked-chain"}}, revokedIntermediate, revokedIntermediateKey)
+
+		p := filepath.Join(dir, "ca.pem")
+		writeCRL(p, true, ca, caKey, revoked, revokedIntermediate)
+
+		store, err := NewCRLStore(context.Background(), []*x509.Certificate{ca}, 0, p)
+		So(err, ShouldBeNil)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		intermediates := x509.NewCertPool()
+		intermediates.AddCert(intermediate)
+		intermediates.AddCert(revokedIntermediate)
+
+		verifyOptions := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+
+		decider := func(a bahamut.AuthAction, c bahamut.Context, s bahamut.Session) bahamut.AuthAction { return a }
+
+		Convey("When I use a request authenticator checking the tls state", func() {
+
+			auth := NewMTLSRequestAuthenticator(verifyOptions, decider, nil, CertificateCheckModeTLSStateOnly, OptionCRLStore(store))
+
+			check := func(cert *x509.Certificate) (bahamut.AuthAction, error) {
+				return auth.AuthenticateRequest(bahamut.NewContext(context.Background(), &elemental.Request{
+					TLSConnectionState: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+				}))
+			}
+
+			Convey("Then the valid certificates should be accepted", func() {
+
+				action, err := check(valid)
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+
+				action, err = check(validLeaf)
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+
+			Convey("Then the revoked certificate should be rejected with a distinct error", func() {
+
+				action, err := check(revoked)
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+				So(err, ShouldNotBeNil)
+				So(err.(elemental.Error).Title, ShouldEqual, "Certificate Revoked")
+				So(err.(elemental.Error).Code, ShouldEqual, http.StatusUnauthorized)
+				So(err.(elemental.Error).Description, ShouldContainSubstring, "'revoked'")
+			})
+
+			Convey("Then the certificate with a revoked intermediate should be rejected with a distinct error", func() {
+
+				action, err := check(revokedChain)
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+				So(err, ShouldNotBeNil)
+				So(err.(elemental.Error).Title, ShouldEqual, "Certificate Revoked")
+				So(err.(elemental.Error).Description, ShouldContainSubstring, "'revoked-intermediate'")
+			})
+		})
+
+		Convey("When I use a request authenticator checking the header", func() {
+
+			auth := NewMTLSRequestAuthenticator(verifyOptions, decider, nil, CertificateCheckModeHeaderOnly, OptionCRLStore(store))
+
+			check := func(cert *x509.Certificate) (bahamut.AuthAction, error) {
+				req := elemental.NewRequest()
+				req.Headers.Set(tlsHeaderKey, certToHeader(cert))
+				return auth.AuthenticateRequest(bahamut.NewContext(context.Background(), req))
+			}
+
+			Convey("Then the valid certificate should be accepted", func() {
+
+				action, err := check(valid)
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+
+			Convey("Then the revoked certificate should be rejected with a distinct error", func() {
+
+				action, err := check(revoked)
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+				So(err, ShouldNotBeNil)
+				So(err.(elemental.Error).Title, ShouldEqual, "Certificate Revoked")
+			})
+		})
+
+		Convey("When I use an authorizer", func() {
+
+			auth := NewMTLSAuthorizer(verifyOptions, decider, nil, nil, CertificateCheckModeTLSStateOnly, OptionCRLStore(store))
+
+			check := func(cert *x509.Certificate) (bahamut.AuthAction, error) {
+				return auth.IsAuthorized(bahamut.NewContext(context.Background(), &elemental.Request{
+					TLSConnectionState: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+				}))
+			}
+
+			Convey("Then the revoked certificate should be rejected with a distinct error", func() {
+
+				action, err := check(valid)
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+
+				action, err = check(revoked)
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+				So(err, ShouldNotBeNil)
+				So(err.(elemental.Error).Title, ShouldEqual, "Certificate Revoked")
+			})
+		})
+	})
+}
+
+func TestOptionCRLStore(t *testing.T) {
+
+	Convey("Calling OptionCRLStore with nil should panic", t, func() {
+		So(func() { OptionCRLStore(nil) }, ShouldPanic)
+	})
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+type config struct {
+	extensionClaims map[string]string
+	spiffeBundles   *SPIFFEBundles
+	crlStore        *CRLStore
+}
+
+// An Option represents a configuration option
+// for the mTLS authenticators and authorizer.
+type Option func(*config)
+
+// OptionExtensionClaims sets the certificate extensions to convert into claims.
+// The keys of the given map are the dotted OIDs of the extensions, like
+// "1.3.6.1.4.1.50000.1", and the values are the keys of the resulting claims,
+// like "@auth:team".
+//
+// If the value of the extension is an ASN.1 string, the claim contains
+// the string. Otherwise, it contains the hex encoded raw value.
+func OptionExtensionClaims(oids map[string]string) Option {
+	return func(c *config) {
+		c.extensionClaims = oids
+	}
+}
+
+// OptionSPIFFEBundles makes the verification use the CA bundle of the
+// trust domain of the SPIFFE ID of the certificate, instead of the roots
+// of the given x509.VerifyOptions. Certificates without a SPIFFE ID, or
+// with a SPIFFE ID from an unknown trust domain, are rejected.
+func OptionSPIFFEBundles(bundles *SPIFFEBundles) Option {
+
+	if bundles == nil {
+		panic("bundles must not be nil")
+	}
+
+	return func(c *config) {
+		c.spiffeBundles = bundles
+	}
+}
+
+// OptionCRLStore makes the verification reject the certificates revoked by
+// the revocation lists of the given CRLStore. This applies to the client
+// certificate and to the intermediate certificates of its chain, whether
+// they are retrieved from the TLS state or from the header.
+//
+// When a revoked certificate is found, a distinct error with the title
+// "Certificate Revoked" is returned, so it appears in the audit trail.
+func OptionCRLStore(store *CRLStore) Option {
+
+	if store == nil {
+		panic("store must not be nil")
+	}
+
+	return func(c *config) {
+		c.crlStore = store
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aporeto.io/bahamut/authorizer/internal/filewatcher"
+)
+
+const spiffeScheme = "spiffe"
+
+// SPIFFEID returns the SPIFFE ID of the given certificate.
+// As defined by the SPIFFE specification, the certificate must
+// contain exactly one URI SAN, and it must be a valid SPIFFE ID.
+func SPIFFEID(cert *x509.Certificate) (*url.URL, error) {
+
+	if len(cert.URIs) != 1 {
+		return nil, fmt.Errorf("certificate must have exactly one uri san, got %d", len(cert.URIs))
+	}
+
+	u := cert.URIs[0]
+	if !isSPIFFEID(u) {
+		return nil, fmt.Errorf("invalid spiffe id '%s'", u)
+	}
+
+	return u, nil
+}
+
+func isSPIFFEID(u *url.URL) bool {
+
+	return u.Scheme == spiffeScheme &&
+		u.Host != "" &&
+		u.Port() == "" &&
+		u.User == nil &&
+		u.RawQuery == "" &&
+		u.Fragment == ""
+}
+
+// NewSPIFFEVerifier returns a VerifierFunc accepting the certificates with a
+// SPIFFE ID in the given trust domain, and with a path matching at least one
+// of the given patterns. The patterns use the path.Match syntax, like
+// "/ns/prod/sa/*". If no pattern is given, any path is accepted.
+func NewSPIFFEVerifier(trustDomain string, pathPatterns ...string) VerifierFunc {
+
+	for _, p := range pathPatterns {
+		if _, err := path.Match(p, ""); err != nil {
+			panic(fmt.Sprintf("invalid path pattern '%s': %s", p, err))
+		}
+	}
+
+	return func(cert *x509.Certificate) bool {
+
+		id, err := SPIFFEID(cert)
+		if err != nil {
+			return false
+		}
+
+		if !strings.EqualFold(id.Host, trustDomain) {
+			return false
+		}
+
+		if len(pathPatterns) == 0 {
+			return true
+		}
+
+		for _, p := range pathPatterns {
+			if ok, _ := path.Match(p, id.Path); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// SPIFFEBundles holds the CA bundles of several SPIFFE trust domains,
+// loaded from a directory. Each bundle must be a PEM file named after
+// the trust domain, like "example.org.pem".
+//
+// The directory is periodically checked for changes so the bundles
+// can be rotated.
+type SPIFFEBundles struct {
+	dir     string
+	pools   map[string]*x509.CertPool
+	watcher *filewatcher.Watcher
+
+	sync.RWMutex
+}
+
+// NewSPIFFEBundles returns a new SPIFFEBundles loading the bundles from the
+// given directory. It returns an error if the bundles cannot be loaded.
+//
+// If refreshInterval is greater than 0, the directory is checked for
+// changes at that interval until the given context is canceled. When
+// a bundle in the directory is invalid, none of the bundles are
+// replaced until the directory changes again.
+func NewSPIFFEBundles(ctx context.Context, dir string, refreshInterval time.Duration) (*SPIFFEBundles, error) {
+
+	b := &SPIFFEBundles{
+		dir: dir,
+	}
+	b.watcher = filewatcher.New("spiffe bundles directory", b.load, dir)
+
+	if _, err := b.watcher.Reload(); err != nil {
+		return nil, err
+	}
+
+	if refreshInterval > 0 {
+		go b.watcher.Watch(ctx, refreshInterval)
+	}
+
+	return b, nil
+}
+
+// Pool returns the CA bundle of the given trust domain, or
+// nil if the trust domain is unknown.
+func (b *SPIFFEBundles) Pool(trustDomain string) *x509.CertPool {
+
+	b.RLock()
+	defer b.RUnlock()
+
+	return b.pools[strings.ToLower(trustDomain)]
+}
+
+// load loads the bundles from the directory.
+func (b *SPIFFEBundles) load() error {
+
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return fmt.Errorf("unable to read spiffe bundles directory: %s", err)
+	}
+
+	pools := map[string]*x509.CertPool{}
+
+	for _, f := range files {
+
+		if f.IsDir() || filepath.Ext(f.Name()) != ".pem" {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(b.dir, f.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to read spiffe bundle '%s': %s", f.Name(), err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("unable to read spiffe bundle '%s': no valid certificate found", f.Name())
+		}
+
+		pools[strings.ToLower(strings.TrimSuffix(f.Name(), ".pem"))] = pool
+	}
+
+	b.Lock()
+	b.pools = pools
+	b.Unlock()
+
+	return nil
+}
@@ -0,0 +1,251 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+)
+
+func writeBundle(path string, certs ...*x509.Certificate) {
+
+	var data []byte
+	for _, c := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		panic(err)
+	}
+}
+
+func TestSPIFFEID(t *testing.T) {
+
+	Convey("Given I have some certificates", t, func() {
+
+		ca, caKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+
+		issue := func(uris ...string) *x509.Certificate {
+			var us []*url.URL
+			for _, u := range uris {
+				us = append(us, mustParseURL(u))
+			}
+			c, _ := issueTestCert(&x509.Certificate{URIs: us}, ca, caKey)
+			return c
+		}
+
+		Convey("Then a certificate with a valid spiffe id should work", func() {
+			id, err := SPIFFEID(issue("spiffe://example.org/a/b"))
+			So(err, ShouldBeNil)
+			So(id.String(), ShouldEqual, "spiffe://example.org/a/b")
+		})
+
+		Convey("Then a certificate without uri should fail", func() {
+			_, err := SPIFFEID(issue())
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "certificate must have exactly one uri san, got 0")
+		})
+
+		Convey("Then a certificate with two uris should fail", func() {
+			_, err := SPIFFEID(issue("spiffe://example.org/a", "spiffe://example.org/b"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "certificate must have exactly one uri san, got 2")
+		})
+
+		Convey("Then a certificate with an invalid spiffe id should fail", func() {
+			for _, u := range []string{
+				"https://example.org/a",
+				"spiffe://example.org:8443/a",
+				"spiffe://user@example.org/a",
+				"spiffe://example.org/a?b=c",
+				"spiffe://example.org/a#b",
+			} {
+				_, err := SPIFFEID(issue(u))
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestNewSPIFFEVerifier(t *testing.T) {
+
+	Convey("Given I have some certificates", t, func() {
+
+		ca, caKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+
+		issue := func(u string) *x509.Certificate {
+			c, _ := issueTestCert(&x509.Certificate{URIs: []*url.URL{mustParseURL(u)}}, ca, caKey)
+			return c
+		}
+
+		Convey("When I use a verifier with path patterns", func() {
+
+			v := NewSPIFFEVerifier("example.org", "/ns/prod/sa/*", "/admin")
+
+			Convey("Then it should accept the matching certificates", func() {
+				So(v(issue("spiffe://example.org/ns/prod/sa/api")), ShouldBeTrue)
+				So(v(issue("spiffe://example.org/admin")), ShouldBeTrue)
+				So(v(issue("spiffe://EXAMPLE.org/admin")), ShouldBeTrue)
+			})
+
+			Convey("Then it should reject the other certificates", func() {
+				So(v(issue("spiffe://example.org/ns/dev/sa/api")), ShouldBeFalse)
+				So(v(issue("spiffe://example.org/ns/prod/sa/api/sub")), ShouldBeFalse)
+				So(v(issue("spiffe://other.org/admin")), ShouldBeFalse)
+				So(v(issue("https://example.org/admin")), ShouldBeFalse)
+			})
+		})
+
+		Convey("When I use a verifier without path pattern", func() {
+
+			v := NewSPIFFEVerifier("example.org")
+
+			Convey("Then it should accept any path in the trust domain", func() {
+				So(v(issue("spiffe://example.org/anything")), ShouldBeTrue)
+				So(v(issue("spiffe://other.org/anything")), ShouldBeFalse)
+			})
+		})
+
+		Convey("When I use a verifier with an invalid pattern", func() {
+
+			Convey("Then it should panic", func() {
+				So(func() { NewSPIFFEVerifier("example.org", "[") }, ShouldPanic)
+			})
+		})
+	})
+}
+
+func TestSPIFFEBundles(t *testing.T) {
+
+	Convey("Given I have a directory with bundles for two trust domains", t, func() {
+
+		dir, _ := ioutil.TempDir("", "spiffe")
+		defer os.RemoveAll(dir) // nolint
+
+		caA, caAKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca-a"}}, nil, nil)
+		caB, caBKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca-b"}}, nil, nil)
+
+		writeBundle(filepath.Join(dir, "a.org.pem"), caA)
+		writeBundle(filepath.Join(dir, "b.org.pem"), caB)
+		So(ioutil.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0600), ShouldBeNil)
+
+		certA, _ := issueTestCert(&x509.Certificate{URIs: []*url.URL{mustParseURL("spiffe://a.org/api")}}, caA, caAKey)
+		certAFromB, _ := issueTestCert(&x509.Certificate{URIs: []*url.URL{mustParseURL("spiffe://a.org/api")}}, caB, caBKey)
+		certB, _ := issueTestCert(&x509.Certificate{URIs: []*url.URL{mustParseURL("spiffe://b.org/api")}}, caB, caBKey)
+		certC, _ := issueTestCert(&x509.Certificate{URIs: []*url.URL{mustParseURL("spiffe://c.org/api")}}, caB, caBKey)
+		certNoID, _ := issueTestCert(&x509.Certificate{}, caA, caAKey)
+
+		bundles, err := NewSPIFFEBundles(context.Background(), dir, 0)
+		So(err, ShouldBeNil)
+
+		Convey("Then the pools should be loaded", func() {
+			So(bundles.Pool("a.org"), ShouldNotBeNil)
+			So(bundles.Pool("b.org"), ShouldNotBeNil)
+			So(bundles.Pool("c.org"), ShouldBeNil)
+		})
+
+		Convey("When I use them in an authenticator", func() {
+
+			auth := NewMTLSRequestAuthenticator(
+				x509.VerifyOptions{KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}},
+				func(a bahamut.AuthAction, c bahamut.Context, s bahamut.Session) bahamut.AuthAction { return a },
+				nil,
+				CertificateCheckModeTLSStateOnly,
+				OptionSPIFFEBundles(bundles),
+			)
+
+			check := func(cert *x509.Certificate) bahamut.AuthAction {
+				action, err := auth.AuthenticateRequest(bahamut.NewContext(context.Background(), &elemental.Request{
+					TLSConnectionState: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+				}))
+				So(err, ShouldBeNil)
+				return action
+			}
+
+			Convey("Then the certificates should be verified using the bundle of their trust domain", func() {
+				So(check(certA), ShouldEqual, bahamut.AuthActionOK)
+				So(check(certB), ShouldEqual, bahamut.AuthActionOK)
+				So(check(certAFromB), ShouldEqual, bahamut.AuthActionKO)
+				So(check(certC), ShouldEqual, bahamut.AuthActionKO)
+				So(check(certNoID), ShouldEqual, bahamut.AuthActionKO)
+			})
+		})
+
+		Convey("When a bundle is rotated", func() {
+
+			caA2, caA2Key := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca-a2"}}, nil, nil)
+			certA2, _ := issueTestCert(&x509.Certificate{URIs: []*url.URL{mustParseURL("spiffe://a.org/api")}}, caA2, caA2Key)
+
+			writeBundle(filepath.Join(dir, "a.org.pem"), caA2)
+			So(os.Chtimes(filepath.Join(dir, "a.org.pem"), time.Now(), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			reloaded, err := bundles.watcher.Reload()
+
+			Convey("Then the new bundle should be used", func() {
+				So(err, ShouldBeNil)
+				So(reloaded, ShouldBeTrue)
+				_, err := certA2.Verify(x509.VerifyOptions{Roots: bundles.Pool("a.org"), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+				So(err, ShouldBeNil)
+				_, err = certA.Verify(x509.VerifyOptions{Roots: bundles.Pool("a.org"), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("Then reloading again should do nothing", func() {
+				reloaded, err := bundles.watcher.Reload()
+				So(err, ShouldBeNil)
+				So(reloaded, ShouldBeFalse)
+			})
+		})
+
+		Convey("When a bundle becomes invalid", func() {
+
+			So(ioutil.WriteFile(filepath.Join(dir, "a.org.pem"), []byte("nope"), 0600), ShouldBeNil)
+			So(os.Chtimes(filepath.Join(dir, "a.org.pem"), time.Now(), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			reloaded, err := bundles.watcher.Reload()
+
+			Convey("Then the previous bundles should be kept", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unable to read spiffe bundle 'a.org.pem': no valid certificate found")
+				So(reloaded, ShouldBeFalse)
+				So(bundles.Pool("a.org"), ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have a directory that does not exist", t, func() {
+
+		bundles, err := NewSPIFFEBundles(context.Background(), "/not/a/dir", 0)
+
+		Convey("Then it should fail", func() {
+			So(err, ShouldNotBeNil)
+			So(bundles, ShouldBeNil)
+		})
+	})
+
+	Convey("Calling OptionSPIFFEBundles with nil should panic", t, func() {
+		So(func() { OptionSPIFFEBundles(nil) }, ShouldPanicWith, "bundles must not be nil")
+	})
+}
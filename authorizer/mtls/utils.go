@@ -11,9 +11,13 @@
 
 package mtls
 
-import "crypto/x509"
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+)
 
-func makeClaims(cert *x509.Certificate) []string {
+func makeClaims(cert *x509.Certificate, extensionClaims map[string]string) []string {
 
 	claims := []string{
 		"@auth:realm=certificate",
@@ -30,5 +34,47 @@ func makeClaims(cert *x509.Certificate) []string {
 		claims = append(claims, "@auth:organizationalunit="+ou)
 	}
 
+	for _, u := range cert.URIs {
+		claims = append(claims, "@auth:uri="+u.String())
+	}
+
+	if id, err := SPIFFEID(cert); err == nil {
+		claims = append(claims,
+			"@auth:spiffetrustdomain="+id.Host,
+			"@auth:spiffepath="+id.Path,
+		)
+	}
+
+	for _, dns := range cert.DNSNames {
+		claims = append(claims, "@auth:dnsname="+dns)
+	}
+
+	for _, email := range cert.EmailAddresses {
+		claims = append(claims, "@auth:email="+email)
+	}
+
+	if len(extensionClaims) > 0 {
+		for _, ext := range cert.Extensions {
+			if key, ok := extensionClaims[ext.Id.String()]; ok {
+				claims = append(claims, key+"="+extensionValue(ext.Value))
+			}
+		}
+	}
+
 	return claims
 }
+
+// extensionValue returns the string contained in the given
+// extension value, or its hex encoding if it is not a string.
+func extensionValue(data []byte) string {
+
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(data, &raw); err == nil && len(rest) == 0 && raw.Class == asn1.ClassUniversal {
+		switch raw.Tag {
+		case asn1.TagUTF8String, asn1.TagPrintableString, asn1.TagIA5String, asn1.TagT61String:
+			return string(raw.Bytes)
+		}
+	}
+
+	return hex.EncodeToString(data)
+}
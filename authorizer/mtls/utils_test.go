@@ -12,21 +12,103 @@
 package mtls
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
 	"io/ioutil"
+	"math/big"
+	"net/url"
 	"reflect"
 	"testing"
+	"time"
 )
 
+// issueTestCert issues a certificate from the given template, signed by the given
+// parent. If parent is nil, the certificate is a self signed CA.
+func issueTestCert(template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err)
+	}
+
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		parent = template
+		parentKey = key
+	} else if template.IsCA {
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	} else {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		panic(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+
+	return cert, key
+}
+
+func mustParseURL(s string) *url.URL {
+
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return u
+}
+
 func Test_makeClaims(t *testing.T) {
 
 	cdata, _ := ioutil.ReadFile("./fixtures/claim-test-cert.pem")
 	cblock, _ := pem.Decode(cdata)
 	cert, _ := x509.ParseCertificate(cblock.Bytes)
 
+	ca, caKey := issueTestCert(&x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}, nil, nil)
+
+	teamValue, _ := asn1.MarshalWithParams("blue", "utf8")
+	sanCert, _ := issueTestCert(
+		&x509.Certificate{
+			Subject:        pkix.Name{CommonName: "workload"},
+			URIs:           []*url.URL{mustParseURL("spiffe://example.org/ns/prod/sa/api")},
+			DNSNames:       []string{"api.example.org"},
+			EmailAddresses: []string{"api@example.org"},
+			ExtraExtensions: []pkix.Extension{
+				{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 50000, 1}, Value: teamValue},
+				{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 50000, 2}, Value: []byte{0x01, 0x02}},
+			},
+		},
+		ca,
+		caKey,
+	)
+
 	type args struct {
-		cert *x509.Certificate
+		cert            *x509.Certificate
+		extensionClaims map[string]string
 	}
 	tests := []struct {
 		name string
@@ -37,6 +119,7 @@ func Test_makeClaims(t *testing.T) {
 			"simple",
 			args{
 				cert,
+				nil,
 			},
 			[]string{
 				"@auth:realm=certificate",
@@ -47,10 +130,33 @@ func Test_makeClaims(t *testing.T) {
 				"@auth:organizationalunit=B",
 			},
 		},
+		{
+			"sans and extensions",
+			args{
+				sanCert,
+				map[string]string{
+					"1.3.6.1.4.1.50000.1": "@auth:team",
+					"1.3.6.1.4.1.50000.2": "@auth:raw",
+				},
+			},
+			[]string{
+				"@auth:realm=certificate",
+				"@auth:mode=internal",
+				"@auth:serialnumber=" + sanCert.SerialNumber.String(),
+				"@auth:commonname=workload",
+				"@auth:uri=spiffe://example.org/ns/prod/sa/api",
+				"@auth:spiffetrustdomain=example.org",
+				"@auth:spiffepath=/ns/prod/sa/api",
+				"@auth:dnsname=api.example.org",
+				"@auth:email=api@example.org",
+				"@auth:team=blue",
+				"@auth:raw=0102",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := makeClaims(tt.args.cert); !reflect.DeepEqual(got, tt.want) {
+			if got := makeClaims(tt.args.cert, tt.args.extensionClaims); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("makeClaims() = %v, want %v", got, tt.want)
 			}
 		})
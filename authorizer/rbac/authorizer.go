@@ -0,0 +1,124 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/bahamut/authorizer/internal/filewatcher"
+)
+
+// An Authorizer is a bahamut.Authorizer evaluating the
+// policies loaded from a YAML policy file.
+//
+// For a given request, the Authorizer returns:
+//   - bahamut.AuthActionKO if any matching policy has the deny effect,
+//   - bahamut.AuthActionOK if any matching policy has the allow effect,
+//   - bahamut.AuthActionContinue if no policy matches,
+//
+// so it can be combined with other authorizers.
+type Authorizer struct {
+	path     string
+	policies atomic.Value
+	watcher  *filewatcher.Watcher
+}
+
+// NewAuthorizer returns a new Authorizer loading the policies from
+// the file at the given path. It returns an error if the policies
+// cannot be loaded.
+//
+// Unless disabled with OptionReloadInterval, the file is periodically
+// checked for changes and reloaded until the given context is canceled.
+// If the new policies are invalid, the error is logged and the Authorizer
+// keeps using the previous ones.
+func NewAuthorizer(ctx context.Context, path string, options ...Option) (*Authorizer, error) {
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	a := &Authorizer{
+		path: path,
+	}
+	a.watcher = filewatcher.New("rbac policy file", a.load, path)
+
+	if _, err := a.watcher.Reload(); err != nil {
+		return nil, err
+	}
+
+	if cfg.reloadInterval > 0 {
+		go a.watcher.Watch(ctx, cfg.reloadInterval)
+	}
+
+	return a, nil
+}
+
+// Policies returns the policies currently in use.
+func (a *Authorizer) Policies() []Policy {
+
+	return append([]Policy{}, a.policies.Load().([]Policy)...)
+}
+
+// IsAuthorized implements the bahamut.Authorizer interface.
+func (a *Authorizer) IsAuthorized(ctx bahamut.Context) (bahamut.AuthAction, error) {
+
+	req := ctx.Request()
+
+	claims := map[string]struct{}{}
+	for _, c := range ctx.Claims() {
+		claims[c] = struct{}{}
+	}
+
+	var allowed bool
+
+	for _, p := range a.policies.Load().([]Policy) {
+
+		if !p.appliesTo(req, claims) {
+			continue
+		}
+
+		if p.Effect == EffectDeny {
+			return bahamut.AuthActionKO, nil
+		}
+
+		allowed = true
+	}
+
+	if allowed {
+		return bahamut.AuthActionOK, nil
+	}
+
+	return bahamut.AuthActionContinue, nil
+}
+
+// load loads the policies from the policy file.
+func (a *Authorizer) load() error {
+
+	data, err := ioutil.ReadFile(a.path)
+	if err != nil {
+		return fmt.Errorf("unable to read policy file: %s", err)
+	}
+
+	policies, err := ParsePolicies(data)
+	if err != nil {
+		return err
+	}
+
+	a.policies.Store(policies)
+
+	return nil
+}
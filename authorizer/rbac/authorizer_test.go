@@ -0,0 +1,223 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+)
+
+const testPolicies = `
+policies:
+  - name: readers
+    subject:
+      - ["@auth:group=readers"]
+    namespace: /acme
+    identities: ["task"]
+    operations: ["retrieve", "retrieve-many"]
+  - name: no-bob
+    effect: deny
+    subject:
+      - ["@auth:subject=bob"]
+    identities: ["*"]
+    operations: ["*"]
+`
+
+func TestNewAuthorizer(t *testing.T) {
+
+	Convey("Given I have a policy file", t, func() {
+
+		dir, _ := ioutil.TempDir("", "rbac")
+		defer os.RemoveAll(dir) // nolint
+
+		path := filepath.Join(dir, "policies.yaml")
+		So(ioutil.WriteFile(path, []byte(testPolicies), 0600), ShouldBeNil)
+
+		Convey("When I call NewAuthorizer", func() {
+
+			a, err := NewAuthorizer(context.Background(), path, OptionReloadInterval(0))
+
+			Convey("Then the policies should be loaded", func() {
+				So(err, ShouldBeNil)
+				So(len(a.Policies()), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I call NewAuthorizer on a missing file", func() {
+
+			a, err := NewAuthorizer(context.Background(), filepath.Join(dir, "missing.yaml"))
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(a, ShouldBeNil)
+			})
+		})
+
+		Convey("When I call NewAuthorizer on an invalid file", func() {
+
+			So(ioutil.WriteFile(path, []byte(`policies: [{}]`), 0600), ShouldBeNil)
+
+			a, err := NewAuthorizer(context.Background(), path)
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid policy at index 0: subject must not be empty")
+				So(a, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I call OptionReloadInterval with a negative interval", t, func() {
+
+		Convey("Then it should panic", func() {
+			So(func() { OptionReloadInterval(-time.Second) }, ShouldPanicWith, "interval cannot be negative")
+		})
+	})
+}
+
+func TestAuthorizer_IsAuthorized(t *testing.T) {
+
+	Convey("Given I have an Authorizer", t, func() {
+
+		dir, _ := ioutil.TempDir("", "rbac")
+		defer os.RemoveAll(dir) // nolint
+
+		path := filepath.Join(dir, "policies.yaml")
+		So(ioutil.WriteFile(path, []byte(testPolicies), 0600), ShouldBeNil)
+
+		a, err := NewAuthorizer(context.Background(), path, OptionReloadInterval(0))
+		So(err, ShouldBeNil)
+
+		makeContext := func(ns string, op elemental.Operation, claims ...string) bahamut.Context {
+			ctx := bahamut.NewContext(context.Background(), &elemental.Request{
+				Namespace: ns,
+				Operation: op,
+				Identity:  elemental.MakeIdentity("task", "tasks"),
+			})
+			ctx.SetClaims(claims)
+			return ctx
+		}
+
+		Convey("When a reader retrieves a task", func() {
+
+			action, err := a.IsAuthorized(makeContext("/acme/a", elemental.OperationRetrieve, "@auth:group=readers"))
+
+			Convey("Then action should be OK", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+		})
+
+		Convey("When a reader deletes a task", func() {
+
+			action, err := a.IsAuthorized(makeContext("/acme/a", elemental.OperationDelete, "@auth:group=readers"))
+
+			Convey("Then action should be Continue", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionContinue)
+			})
+		})
+
+		Convey("When bob, who is a reader, retrieves a task", func() {
+
+			action, err := a.IsAuthorized(makeContext("/acme/a", elemental.OperationRetrieve, "@auth:group=readers", "@auth:subject=bob"))
+
+			Convey("Then action should be KO", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+			})
+		})
+
+		Convey("When the policy file changes", func() {
+
+			So(ioutil.WriteFile(path, []byte(`
+policies:
+  - subject: [["@auth:group=readers"]]
+    identities: ["*"]
+    operations: ["*"]
+`), 0600), ShouldBeNil)
+			So(os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			reloaded, err := a.watcher.Reload()
+			So(err, ShouldBeNil)
+			So(reloaded, ShouldBeTrue)
+
+			action, err := a.IsAuthorized(makeContext("/acme/a", elemental.OperationDelete, "@auth:group=readers"))
+
+			Convey("Then the new policies should be used", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+
+			Convey("Then reloading again should do nothing", func() {
+				reloaded, err := a.watcher.Reload()
+				So(err, ShouldBeNil)
+				So(reloaded, ShouldBeFalse)
+			})
+		})
+
+		Convey("When the policy file becomes invalid", func() {
+
+			So(ioutil.WriteFile(path, []byte(`policies: [`), 0600), ShouldBeNil)
+			So(os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			reloaded, err := a.watcher.Reload()
+
+			Convey("Then the previous policies should be kept", func() {
+				So(err, ShouldNotBeNil)
+				So(reloaded, ShouldBeFalse)
+				So(len(a.Policies()), ShouldEqual, 2)
+			})
+		})
+	})
+
+	Convey("Given I have an Authorizer that reloads the policies", t, func() {
+
+		dir, _ := ioutil.TempDir("", "rbac")
+		defer os.RemoveAll(dir) // nolint
+
+		path := filepath.Join(dir, "policies.yaml")
+		So(ioutil.WriteFile(path, []byte(testPolicies), 0600), ShouldBeNil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		a, err := NewAuthorizer(ctx, path, OptionReloadInterval(10*time.Millisecond))
+		So(err, ShouldBeNil)
+
+		Convey("When the policy file changes", func() {
+
+			So(ioutil.WriteFile(path, []byte(`policies: []`), 0600), ShouldBeNil)
+			So(os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)), ShouldBeNil)
+
+			Convey("Then the policies should eventually be reloaded", func() {
+				So(func() bool {
+					for i := 0; i < 100; i++ {
+						if len(a.Policies()) == 0 {
+							return true
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+					return false
+				}(), ShouldBeTrue)
+			})
+		})
+	})
+}
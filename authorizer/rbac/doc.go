@@ -0,0 +1,39 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rbac provides a bahamut.Authorizer evaluating
+// role based access control policies loaded from a YAML file.
+//
+// A policy file looks like:
+//
+//	policies:
+//	  - name: admins
+//	    subject:
+//	      - - "@auth:realm=certificate"
+//	        - "@auth:organizationalunit=admin"
+//	    identities: ["*"]
+//	    operations: ["*"]
+//
+//	  - name: readers
+//	    subject:
+//	      - - "@auth:realm=jwt"
+//	        - "@auth:group=readers"
+//	    namespace: /acme
+//	    identities: ["task", "user"]
+//	    operations: ["retrieve", "retrieve-many", "info"]
+//
+//	  - name: no-delete-for-bob
+//	    effect: deny
+//	    subject:
+//	      - - "@auth:subject=bob"
+//	    identities: ["*"]
+//	    operations: ["delete"]
+package rbac // import "go.aporeto.io/bahamut/authorizer/rbac"
@@ -0,0 +1,42 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import "time"
+
+type config struct {
+	reloadInterval time.Duration
+}
+
+func newConfig() config {
+	return config{
+		reloadInterval: 10 * time.Second,
+	}
+}
+
+// An Option represents a configuration option
+// for the Authorizer.
+type Option func(*config)
+
+// OptionReloadInterval sets the interval at which the Authorizer
+// checks if the policy file has changed, and reloads it if needed.
+// Setting it to 0 disables reloading. The default is 10s.
+func OptionReloadInterval(interval time.Duration) Option {
+
+	if interval < 0 {
+		panic("interval cannot be negative")
+	}
+
+	return func(c *config) {
+		c.reloadInterval = interval
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	"go.aporeto.io/elemental"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// An Effect represents the effect of a Policy.
+type Effect string
+
+// Various values for Effect.
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+const wildcard = "*"
+
+var validOperations = map[elemental.Operation]struct{}{
+	elemental.OperationRetrieveMany: {},
+	elemental.OperationRetrieve:     {},
+	elemental.OperationCreate:       {},
+	elemental.OperationUpdate:       {},
+	elemental.OperationDelete:       {},
+	elemental.OperationPatch:        {},
+	elemental.OperationInfo:         {},
+}
+
+// A Policy grants or denies the given operations
+// on the given identities to the subjects matching
+// the Subject claims.
+type Policy struct {
+
+	// Name is the name of the policy. It is only informative.
+	Name string `yaml:"name"`
+
+	// Effect is the effect of the policy. It
+	// defaults to EffectAllow.
+	Effect Effect `yaml:"effect"`
+
+	// Subject is the list of claims the subject must have.
+	// The first level is an OR and the second level is an AND:
+	// [["a=a", "b=b"], ["c=c"]] matches the subjects that either
+	// have both a=a and b=b claims, or have the c=c claim.
+	Subject [][]string `yaml:"subject"`
+
+	// Namespace optionally restricts the policy to the requests
+	// made in the given namespace or in its children.
+	Namespace string `yaml:"namespace"`
+
+	// Identities is the list of identity names or categories
+	// the policy applies to. "*" matches all identities.
+	Identities []string `yaml:"identities"`
+
+	// Operations is the list of operations the policy
+	// applies to. "*" matches all operations.
+	Operations []elemental.Operation `yaml:"operations"`
+}
+
+type policyFile struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+// ParsePolicies parses the policies from the given YAML data
+// and validates them.
+func ParsePolicies(data []byte) ([]Policy, error) {
+
+	f := policyFile{}
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("unable to decode policies: %s", err)
+	}
+
+	for i := range f.Policies {
+
+		if f.Policies[i].Effect == "" {
+			f.Policies[i].Effect = EffectAllow
+		}
+
+		if err := f.Policies[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid policy at index %d: %w", i, err)
+		}
+	}
+
+	return f.Policies, nil
+}
+
+func (p Policy) validate() error {
+
+	if p.Effect != EffectAllow && p.Effect != EffectDeny {
+		return fmt.Errorf("invalid effect '%s'", p.Effect)
+	}
+
+	if len(p.Subject) == 0 {
+		return fmt.Errorf("subject must not be empty")
+	}
+
+	for _, ands := range p.Subject {
+
+		if len(ands) == 0 {
+			return fmt.Errorf("subject must not contain empty claim lists")
+		}
+
+		for _, claim := range ands {
+			if parts := strings.SplitN(claim, "=", 2); len(parts) != 2 || parts[0] == "" {
+				return fmt.Errorf("invalid subject claim '%s': must be in the form key=value", claim)
+			}
+		}
+	}
+
+	if len(p.Identities) == 0 {
+		return fmt.Errorf("identities must not be empty")
+	}
+
+	if len(p.Operations) == 0 {
+		return fmt.Errorf("operations must not be empty")
+	}
+
+	for _, op := range p.Operations {
+
+		if op == wildcard {
+			continue
+		}
+
+		if _, ok := validOperations[op]; !ok {
+			return fmt.Errorf("invalid operation '%s'", op)
+		}
+	}
+
+	return nil
+}
+
+// appliesTo returns true if the policy applies
+// to the given request made by a subject with
+// the given claims.
+func (p Policy) appliesTo(req *elemental.Request, claims map[string]struct{}) bool {
+
+	if p.Namespace != "" && !inNamespace(req.Namespace, p.Namespace) {
+		return false
+	}
+
+	if !p.matchesOperation(req.Operation) {
+		return false
+	}
+
+	if !p.matchesIdentity(req.Identity) {
+		return false
+	}
+
+	return p.matchesSubject(claims)
+}
+
+func (p Policy) matchesOperation(operation elemental.Operation) bool {
+
+	for _, op := range p.Operations {
+		if op == wildcard || op == operation {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p Policy) matchesIdentity(identity elemental.Identity) bool {
+
+	for _, i := range p.Identities {
+		if i == wildcard || i == identity.Name || i == identity.Category {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p Policy) matchesSubject(claims map[string]struct{}) bool {
+
+	for _, ands := range p.Subject {
+
+		matched := true
+		for _, claim := range ands {
+			if _, ok := claims[claim]; !ok {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inNamespace(namespace string, parent string) bool {
+
+	if parent == "/" {
+		return true
+	}
+
+	parent = strings.TrimSuffix(parent, "/")
+
+	return namespace == parent || strings.HasPrefix(namespace, parent+"/")
+}
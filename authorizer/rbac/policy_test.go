@@ -0,0 +1,180 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+)
+
+func TestParsePolicies(t *testing.T) {
+
+	Convey("Given I have a valid policy file", t, func() {
+
+		data := []byte(`
+policies:
+  - name: admins
+    subject:
+      - - "@auth:realm=certificate"
+        - "@auth:organizationalunit=admin"
+    identities: ["*"]
+    operations: ["*"]
+  - name: no-delete
+    effect: deny
+    subject:
+      - ["@auth:subject=bob"]
+    namespace: /acme
+    identities: ["task"]
+    operations: ["delete"]
+`)
+
+		Convey("When I call ParsePolicies", func() {
+
+			policies, err := ParsePolicies(data)
+
+			Convey("Then the policies should be correct", func() {
+				So(err, ShouldBeNil)
+				So(policies, ShouldResemble, []Policy{
+					{
+						Name:       "admins",
+						Effect:     EffectAllow,
+						Subject:    [][]string{{"@auth:realm=certificate", "@auth:organizationalunit=admin"}},
+						Identities: []string{"*"},
+						Operations: []elemental.Operation{"*"},
+					},
+					{
+						Name:       "no-delete",
+						Effect:     EffectDeny,
+						Subject:    [][]string{{"@auth:subject=bob"}},
+						Namespace:  "/acme",
+						Identities: []string{"task"},
+						Operations: []elemental.Operation{elemental.OperationDelete},
+					},
+				})
+			})
+		})
+	})
+
+	Convey("Given I have invalid policy files", t, func() {
+
+		for data, expected := range map[string]string{
+			`policies: [`: "unable to decode policies: yaml: line 1: did not find expected node content",
+			`policies: [{subject: [[a=b]], identities: [a], operations: [create], unknown: a}]`:    "unable to decode policies: yaml: unmarshal errors:\n  line 1: field unknown not found in type rbac.Policy",
+			`policies: [{effect: maybe, subject: [[a=b]], identities: [a], operations: [create]}]`: "invalid policy at index 0: invalid effect 'maybe'",
+			`policies: [{identities: [a], operations: [create]}]`:                                  "invalid policy at index 0: subject must not be empty",
+			`policies: [{subject: [[]], identities: [a], operations: [create]}]`:                   "invalid policy at index 0: subject must not contain empty claim lists",
+			`policies: [{subject: [[a]], identities: [a], operations: [create]}]`:                  "invalid policy at index 0: invalid subject claim 'a': must be in the form key=value",
+			`policies: [{subject: [[a=b]], operations: [create]}]`:                                 "invalid policy at index 0: identities must not be empty",
+			`policies: [{subject: [[a=b]], identities: [a]}]`:                                      "invalid policy at index 0: operations must not be empty",
+			`policies: [{subject: [[a=b]], identities: [a], operations: [destroy]}]`:               "invalid policy at index 0: invalid operation 'destroy'",
+		} {
+
+			policies, err := ParsePolicies([]byte(data))
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, expected)
+			So(policies, ShouldBeNil)
+		}
+	})
+}
+
+func TestPolicy_appliesTo(t *testing.T) {
+
+	Convey("Given I have a policy", t, func() {
+
+		p := Policy{
+			Effect:     EffectAllow,
+			Subject:    [][]string{{"a=a", "b=b"}, {"c=c"}},
+			Namespace:  "/acme/",
+			Identities: []string{"task"},
+			Operations: []elemental.Operation{elemental.OperationRetrieve, elemental.OperationRetrieveMany},
+		}
+
+		claims := func(c ...string) map[string]struct{} {
+			out := map[string]struct{}{}
+			for _, cl := range c {
+				out[cl] = struct{}{}
+			}
+			return out
+		}
+
+		request := func(ns string, identity elemental.Identity, op elemental.Operation) *elemental.Request {
+			return &elemental.Request{Namespace: ns, Identity: identity, Operation: op}
+		}
+
+		task := elemental.MakeIdentity("task", "tasks")
+		user := elemental.MakeIdentity("user", "users")
+
+		Convey("Then it should apply to the matching requests", func() {
+			So(p.appliesTo(request("/acme", task, elemental.OperationRetrieve), claims("a=a", "b=b")), ShouldBeTrue)
+			So(p.appliesTo(request("/acme/child", task, elemental.OperationRetrieveMany), claims("c=c")), ShouldBeTrue)
+			So(p.appliesTo(request("/acme", task, elemental.OperationRetrieve), claims("a=a", "b=b", "d=d")), ShouldBeTrue)
+		})
+
+		Convey("Then it should not apply to the other requests", func() {
+			So(p.appliesTo(request("/acme", task, elemental.OperationRetrieve), claims("a=a")), ShouldBeFalse)
+			So(p.appliesTo(request("/acme", task, elemental.OperationDelete), claims("c=c")), ShouldBeFalse)
+			So(p.appliesTo(request("/acme", user, elemental.OperationRetrieve), claims("c=c")), ShouldBeFalse)
+			So(p.appliesTo(request("/acmeother", task, elemental.OperationRetrieve), claims("c=c")), ShouldBeFalse)
+			So(p.appliesTo(request("/", task, elemental.OperationRetrieve), claims("c=c")), ShouldBeFalse)
+		})
+
+		Convey("When I use wildcards", func() {
+
+			p.Namespace = ""
+			p.Identities = []string{"*"}
+			p.Operations = []elemental.Operation{"*"}
+
+			Convey("Then it should apply to all requests", func() {
+				So(p.appliesTo(request("/", user, elemental.OperationDelete), claims("c=c")), ShouldBeTrue)
+			})
+		})
+
+		Convey("When I use the identity category", func() {
+
+			p.Identities = []string{"tasks"}
+
+			Convey("Then it should apply to the identity", func() {
+				So(p.appliesTo(request("/acme", task, elemental.OperationRetrieve), claims("c=c")), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func Test_inNamespace(t *testing.T) {
+
+	type args struct {
+		namespace string
+		parent    string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"same", args{"/a", "/a"}, true},
+		{"child", args{"/a/b", "/a"}, true},
+		{"child with trailing slash", args{"/a/b", "/a/"}, true},
+		{"root", args{"/a/b", "/"}, true},
+		{"sibling with same prefix", args{"/ab", "/a"}, false},
+		{"parent", args{"/", "/a"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inNamespace(tt.args.namespace, tt.args.parent); got != tt.want {
+				t.Errorf("inNamespace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
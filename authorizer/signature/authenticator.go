@@ -0,0 +1,146 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"crypto/hmac"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/karlseguin/ccache/v2"
+	"go.aporeto.io/bahamut"
+	"go.uber.org/zap"
+)
+
+// An Authenticator is a bahamut.RequestAuthenticator that verifies
+// the HMAC-SHA256 signature of the requests, as set by a Signer.
+//
+// If the request has no signature, the Authenticator returns
+// bahamut.AuthActionContinue so another authenticator can handle it.
+// If the signature is invalid, if the timestamp is outside of the
+// allowed clock skew window, or if the nonce has already been used,
+// it returns bahamut.AuthActionKO. Otherwise, it sets the following
+// claims and returns bahamut.AuthActionOK:
+//
+//	@auth:realm=signature
+//	@auth:keyid=<key id>
+type Authenticator struct {
+	secrets SecretProvider
+	nonces  *ccache.Cache
+	cfg     config
+
+	// noncesLock makes checking and
+	// recording a nonce atomic.
+	noncesLock sync.Mutex
+}
+
+// NewAuthenticator returns a new Authenticator verifying the
+// signatures using the secrets provided by the given SecretProvider.
+func NewAuthenticator(secrets SecretProvider, options ...Option) *Authenticator {
+
+	if secrets == nil {
+		panic("secrets must not be nil")
+	}
+
+	cfg := newConfig()
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	return &Authenticator{
+		secrets: secrets,
+		nonces:  ccache.New(ccache.Configure().MaxSize(cfg.nonceCacheSize)),
+		cfg:     cfg,
+	}
+}
+
+// AuthenticateRequest implements the bahamut.RequestAuthenticator interface.
+func (a *Authenticator) AuthenticateRequest(ctx bahamut.Context) (bahamut.AuthAction, error) {
+
+	req := ctx.Request()
+
+	signature := req.Headers.Get(HeaderSignature)
+	if signature == "" {
+		return bahamut.AuthActionContinue, nil
+	}
+
+	hreq := req.HTTPRequest()
+	if hreq == nil {
+		zap.L().Debug("Unable to verify signature: no http request")
+		return bahamut.AuthActionKO, nil
+	}
+
+	keyID := req.Headers.Get(HeaderKeyID)
+	timestamp := req.Headers.Get(HeaderTimestamp)
+	nonce := req.Headers.Get(HeaderNonce)
+
+	if keyID == "" || timestamp == "" || nonce == "" {
+		zap.L().Debug("Unable to verify signature: missing signature headers")
+		return bahamut.AuthActionKO, nil
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		zap.L().Debug("Unable to verify signature: invalid timestamp", zap.String("timestamp", timestamp))
+		return bahamut.AuthActionKO, nil
+	}
+
+	if skew := time.Since(time.Unix(ts, 0)); skew > a.cfg.maxClockSkew || skew < -a.cfg.maxClockSkew {
+		zap.L().Debug("Unable to verify signature: timestamp outside of the allowed window", zap.Duration("skew", skew))
+		return bahamut.AuthActionKO, nil
+	}
+
+	secret, err := a.secrets.Secret(keyID)
+	if err != nil {
+		zap.L().Debug("Unable to verify signature: unable to retrieve secret", zap.String("keyid", keyID), zap.Error(err))
+		return bahamut.AuthActionKO, nil
+	}
+
+	expected := computeSignature(secret, stringToSign(keyID, timestamp, nonce, hreq.Method, hreq.URL, req.Data))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		zap.L().Debug("Unable to verify signature: signature mismatch", zap.String("keyid", keyID))
+		return bahamut.AuthActionKO, nil
+	}
+
+	// The nonce is only recorded once the signature is verified,
+	// so clients cannot fill the cache with random nonces.
+	if !a.useNonce(keyID, nonce) {
+		zap.L().Debug("Unable to verify signature: replayed nonce", zap.String("keyid", keyID))
+		return bahamut.AuthActionKO, nil
+	}
+
+	ctx.SetClaims([]string{"@auth:realm=signature", "@auth:keyid=" + keyID})
+
+	return bahamut.AuthActionOK, nil
+}
+
+// useNonce records the given nonce. It returns false if
+// the nonce has already been used.
+func (a *Authenticator) useNonce(keyID string, nonce string) bool {
+
+	key := keyID + "\n" + nonce
+
+	a.noncesLock.Lock()
+	defer a.noncesLock.Unlock()
+
+	if item := a.nonces.Get(key); item != nil && !item.Expired() {
+		return false
+	}
+
+	// Requests are accepted up to maxClockSkew in the past or
+	// in the future, so a nonce must be remembered for twice
+	// that duration to cover the whole window.
+	a.nonces.Set(key, struct{}{}, 2*a.cfg.maxClockSkew)
+
+	return true
+}
@@ -0,0 +1,209 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+
+	Convey("Given I call NewAuthenticator with some options", t, func() {
+
+		secrets := StaticSecrets{"k1": []byte("secret")}
+
+		auth := NewAuthenticator(
+			secrets,
+			OptionMaxClockSkew(time.Minute),
+			OptionNonceCacheSize(42),
+		)
+
+		Convey("Then it should be correctly initialized", func() {
+			So(auth.secrets, ShouldResemble, secrets)
+			So(auth.nonces, ShouldNotBeNil)
+			So(auth.cfg.maxClockSkew, ShouldEqual, time.Minute)
+			So(auth.cfg.nonceCacheSize, ShouldEqual, 42)
+		})
+	})
+
+	Convey("Calling NewAuthenticator with nil secrets should panic", t, func() {
+		So(func() { NewAuthenticator(nil) }, ShouldPanic)
+	})
+
+	Convey("Calling the options with invalid values should panic", t, func() {
+		So(func() { OptionMaxClockSkew(0) }, ShouldPanic)
+		So(func() { OptionNonceCacheSize(0) }, ShouldPanic)
+	})
+}
+
+func TestAuthenticateRequest(t *testing.T) {
+
+	Convey("Given I have an Authenticator and a Signer", t, func() {
+
+		auth := NewAuthenticator(StaticSecrets{"k1": []byte("secret")}, OptionMaxClockSkew(time.Minute))
+		signer := NewSigner("k1", []byte("secret"))
+
+		newRequest := func() *http.Request {
+			req, _ := http.NewRequest(http.MethodPost, "https://server/lists?b=2&a=1", bytes.NewBufferString(`{"name":"l1"}`))
+			return req
+		}
+
+		check := func(hreq *http.Request) (bahamut.AuthAction, bahamut.Context) {
+
+			req, err := elemental.NewRequestFromHTTPRequest(hreq, testmodel.Manager())
+			So(err, ShouldBeNil)
+
+			ctx := bahamut.NewContext(context.Background(), req)
+
+			action, err := auth.AuthenticateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			return action, ctx
+		}
+
+		Convey("When I send a signed request", func() {
+
+			hreq := newRequest()
+			So(signer.Sign(hreq), ShouldBeNil)
+
+			action, ctx := check(hreq)
+
+			Convey("Then action should be OK", func() {
+				So(action, ShouldEqual, bahamut.AuthActionOK)
+			})
+
+			Convey("Then the claims should be set", func() {
+				So(ctx.Claims(), ShouldResemble, []string{"@auth:realm=signature", "@auth:keyid=k1"})
+			})
+
+			Convey("When I replay it", func() {
+
+				replayed := newRequest()
+				replayed.Header = hreq.Header.Clone()
+
+				action, _ := check(replayed)
+
+				Convey("Then action should be KO", func() {
+					So(action, ShouldEqual, bahamut.AuthActionKO)
+				})
+			})
+		})
+
+		Convey("When I send a request without signature", func() {
+
+			action, ctx := check(newRequest())
+
+			Convey("Then action should be Continue", func() {
+				So(action, ShouldEqual, bahamut.AuthActionContinue)
+				So(ctx.Claims(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I send requests that have been tampered with", func() {
+
+			for name, tamper := range map[string]func(*http.Request){
+				"method": func(r *http.Request) { r.Method = http.MethodPut },
+				"path":   func(r *http.Request) { r.URL.Path = "/tasks" },
+				"query":  func(r *http.Request) { r.URL.RawQuery = "a=1&b=3" },
+				"body": func(r *http.Request) {
+					r.Body = http.NoBody
+				},
+				"key id": func(r *http.Request) { r.Header.Set(HeaderKeyID, "k2") },
+				"nonce":  func(r *http.Request) { r.Header.Set(HeaderNonce, "other") },
+				"timestamp": func(r *http.Request) {
+					ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+					r.Header.Set(HeaderTimestamp, strconv.FormatInt(ts-1, 10))
+				},
+				"signature": func(r *http.Request) { r.Header.Set(HeaderSignature, "nope") },
+			} {
+
+				hreq := newRequest()
+				So(signer.Sign(hreq), ShouldBeNil)
+				tamper(hreq)
+
+				action, ctx := check(hreq)
+
+				Convey("Then the request with a modified "+name+" should be rejected", func() {
+					So(action, ShouldEqual, bahamut.AuthActionKO)
+					So(ctx.Claims(), ShouldBeEmpty)
+				})
+			}
+		})
+
+		Convey("When I send requests with missing or invalid headers", func() {
+
+			for name, tamper := range map[string]func(*http.Request){
+				"no key id":         func(r *http.Request) { r.Header.Del(HeaderKeyID) },
+				"no nonce":          func(r *http.Request) { r.Header.Del(HeaderNonce) },
+				"no timestamp":      func(r *http.Request) { r.Header.Del(HeaderTimestamp) },
+				"invalid timestamp": func(r *http.Request) { r.Header.Set(HeaderTimestamp, "nope") },
+				"unknown key id":    func(r *http.Request) { r.Header.Set(HeaderKeyID, "k2") },
+			} {
+
+				hreq := newRequest()
+				So(signer.Sign(hreq), ShouldBeNil)
+				tamper(hreq)
+
+				action, _ := check(hreq)
+
+				Convey("Then the request with "+name+" should be rejected", func() {
+					So(action, ShouldEqual, bahamut.AuthActionKO)
+				})
+			}
+		})
+
+		Convey("When I send requests signed outside of the clock skew window", func() {
+
+			for name, offset := range map[string]time.Duration{
+				"in the past":   -2 * time.Minute,
+				"in the future": 2 * time.Minute,
+			} {
+
+				hreq := newRequest()
+				ts := strconv.FormatInt(time.Now().Add(offset).Unix(), 10)
+				hreq.Header.Set(HeaderKeyID, "k1")
+				hreq.Header.Set(HeaderTimestamp, ts)
+				hreq.Header.Set(HeaderNonce, "n")
+				hreq.Header.Set(HeaderSignature, computeSignature([]byte("secret"), stringToSign("k1", ts, "n", hreq.Method, hreq.URL, []byte(`{"name":"l1"}`))))
+
+				action, _ := check(hreq)
+
+				Convey("Then the request signed "+name+" should be rejected", func() {
+					So(action, ShouldEqual, bahamut.AuthActionKO)
+				})
+			}
+		})
+
+		Convey("When the request does not come from http", func() {
+
+			req := elemental.NewRequest()
+			req.Headers.Set(HeaderSignature, "sig")
+
+			action, err := auth.AuthenticateRequest(bahamut.NewContext(context.Background(), req))
+
+			Convey("Then action should be KO", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, bahamut.AuthActionKO)
+			})
+		})
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Various headers used to carry the signature.
+const (
+	HeaderKeyID     = "X-Signature-Key-ID"
+	HeaderTimestamp = "X-Signature-Timestamp"
+	HeaderNonce     = "X-Signature-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+// algorithm is the first line of the string to sign.
+const algorithm = "BAHAMUT-HMAC-SHA256"
+
+// stringToSign returns the string to sign for the given
+// request elements. It is in the form:
+//
+//	BAHAMUT-HMAC-SHA256
+//	<key id>
+//	<timestamp>
+//	<nonce>
+//	<method>
+//	<escaped path>
+//	<canonical query>
+//	<hex encoded sha256 of the body>
+func stringToSign(keyID string, timestamp string, nonce string, method string, u *url.URL, body []byte) string {
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	bodyHash := sha256.Sum256(body)
+
+	return strings.Join(
+		[]string{
+			algorithm,
+			keyID,
+			timestamp,
+			nonce,
+			strings.ToUpper(method),
+			path,
+			canonicalQuery(u.Query()),
+			hex.EncodeToString(bodyHash[:]),
+		},
+		"\n",
+	)
+}
+
+// canonicalQuery returns the given query with the keys, and
+// the values of each key, sorted and escaped.
+func canonicalQuery(query url.Values) string {
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+
+	for _, k := range keys {
+
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+
+		for _, v := range values {
+			parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// computeSignature returns the hex encoded
+// HMAC-SHA256 of the given string.
+func computeSignature(secret []byte, s string) string {
+
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(s))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
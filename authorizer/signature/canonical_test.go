@@ -0,0 +1,72 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCanonicalQuery(t *testing.T) {
+
+	Convey("Given I have a query", t, func() {
+
+		q := url.Values{
+			"b":     {"2", "1"},
+			"a":     {"x y"},
+			"c&d=e": {"f&g"},
+			"empty": {""},
+		}
+
+		Convey("Then the canonical query should be correct", func() {
+			So(canonicalQuery(q), ShouldEqual, "a=x+y&b=1&b=2&c%26d%3De=f%26g&empty=")
+		})
+	})
+
+	Convey("Given I have an empty query", t, func() {
+
+		Convey("Then the canonical query should be empty", func() {
+			So(canonicalQuery(url.Values{}), ShouldEqual, "")
+		})
+	})
+}
+
+func TestStringToSign(t *testing.T) {
+
+	Convey("Given I have a url", t, func() {
+
+		u, _ := url.Parse("https://server/a%20b/c?z=1&y=2")
+
+		Convey("Then the string to sign should be correct", func() {
+			So(
+				stringToSign("k1", "1600000000", "n", "post", u, []byte("hello")),
+				ShouldEqual,
+				"BAHAMUT-HMAC-SHA256\nk1\n1600000000\nn\nPOST\n/a%20b/c\ny=2&z=1\n2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			)
+		})
+	})
+
+	Convey("Given I have a url without path", t, func() {
+
+		u, _ := url.Parse("https://server")
+
+		Convey("Then the path should be /", func() {
+			So(
+				stringToSign("k1", "1600000000", "n", "GET", u, nil),
+				ShouldEqual,
+				"BAHAMUT-HMAC-SHA256\nk1\n1600000000\nn\nGET\n/\n\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			)
+		})
+	})
+}
@@ -0,0 +1,19 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signature provides an implementation of bahamut.RequestAuthenticator
+// that verifies requests signed with a shared secret using HMAC-SHA256, and a
+// Signer to sign requests from Go clients.
+//
+// The signature covers the method, the path, the canonical query, the hash of
+// the body, a timestamp and a nonce, in a way similar to AWS SigV4. It is meant
+// for service-to-service calls, like webhooks, where mTLS is not an option.
+package signature // import "go.aporeto.io/bahamut/authorizer/signature"
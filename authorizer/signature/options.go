@@ -0,0 +1,58 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import "time"
+
+type config struct {
+	maxClockSkew   time.Duration
+	nonceCacheSize int64
+}
+
+func newConfig() config {
+	return config{
+		maxClockSkew:   5 * time.Minute,
+		nonceCacheSize: 100000,
+	}
+}
+
+// An Option represents a configuration option
+// for the Authenticator.
+type Option func(*config)
+
+// OptionMaxClockSkew sets the maximum difference allowed between
+// the timestamp of a request and the time it is received. Requests
+// outside of this window are rejected. The default is 5m.
+func OptionMaxClockSkew(skew time.Duration) Option {
+
+	if skew <= 0 {
+		panic("skew must be greater than 0")
+	}
+
+	return func(c *config) {
+		c.maxClockSkew = skew
+	}
+}
+
+// OptionNonceCacheSize sets the maximum number of nonces remembered
+// to detect replayed requests. It should be greater than the number of
+// requests received during twice the max clock skew. The default is 100000.
+func OptionNonceCacheSize(size int64) Option {
+
+	if size <= 0 {
+		panic("size must be greater than 0")
+	}
+
+	return func(c *config) {
+		c.nonceCacheSize = size
+	}
+}
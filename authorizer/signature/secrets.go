@@ -0,0 +1,37 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import "fmt"
+
+// A SecretProvider provides the shared
+// secrets used to verify the signatures.
+type SecretProvider interface {
+
+	// Secret returns the secret with the given key id.
+	Secret(keyID string) ([]byte, error)
+}
+
+// StaticSecrets is a SecretProvider holding
+// a fixed set of secrets, indexed by key id.
+type StaticSecrets map[string][]byte
+
+// Secret implements the SecretProvider interface.
+func (s StaticSecrets) Secret(keyID string) ([]byte, error) {
+
+	secret, ok := s[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", keyID)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A Signer signs http requests so they
+// can be verified by an Authenticator.
+type Signer struct {
+	keyID  string
+	secret []byte
+}
+
+// NewSigner returns a new Signer signing the requests
+// with the given secret, identified by the given key id.
+func NewSigner(keyID string, secret []byte) *Signer {
+
+	if keyID == "" {
+		panic("keyID must not be empty")
+	}
+
+	if len(secret) == 0 {
+		panic("secret must not be empty")
+	}
+
+	return &Signer{
+		keyID:  keyID,
+		secret: secret,
+	}
+}
+
+// Sign signs the given request by setting the signature headers.
+// It must be called after the method, the URL and the body of the
+// request have been set. The body is read and replaced by an
+// equivalent reader.
+func (s *Signer) Sign(req *http.Request) error {
+
+	var body []byte
+
+	if req.Body != nil && req.Body != http.NoBody {
+
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return fmt.Errorf("unable to read body: %s", err)
+		}
+
+		_ = req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	n := make([]byte, 16)
+	if _, err := rand.Read(n); err != nil {
+		return fmt.Errorf("unable to generate nonce: %s", err)
+	}
+
+	nonce := hex.EncodeToString(n)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set(HeaderKeyID, s.keyID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, computeSignature(s.secret, stringToSign(s.keyID, timestamp, nonce, req.Method, req.URL, body)))
+
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewSigner(t *testing.T) {
+
+	Convey("Calling NewSigner with an empty key id should panic", t, func() {
+		So(func() { NewSigner("", []byte("secret")) }, ShouldPanic)
+	})
+
+	Convey("Calling NewSigner with an empty secret should panic", t, func() {
+		So(func() { NewSigner("k1", nil) }, ShouldPanic)
+	})
+}
+
+func TestSignerSign(t *testing.T) {
+
+	Convey("Given I have a Signer and a request with a body", t, func() {
+
+		s := NewSigner("k1", []byte("secret"))
+		req, _ := http.NewRequest(http.MethodPost, "https://server/lists?b=2&a=1", bytes.NewBufferString(`{"name":"l1"}`))
+
+		err := s.Sign(req)
+
+		Convey("Then err should be nil", func() {
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Then the headers should be set", func() {
+
+			So(req.Header.Get(HeaderKeyID), ShouldEqual, "k1")
+			So(req.Header.Get(HeaderTimestamp), ShouldNotBeEmpty)
+			So(len(req.Header.Get(HeaderNonce)), ShouldEqual, 32)
+
+			expected := computeSignature(
+				[]byte("secret"),
+				stringToSign("k1", req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderNonce), http.MethodPost, req.URL, []byte(`{"name":"l1"}`)),
+			)
+			So(req.Header.Get(HeaderSignature), ShouldEqual, expected)
+		})
+
+		Convey("Then the body should still be readable", func() {
+
+			data, err := ioutil.ReadAll(req.Body)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"name":"l1"}`)
+
+			body, err := req.GetBody()
+			So(err, ShouldBeNil)
+			data, err = ioutil.ReadAll(body)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"name":"l1"}`)
+		})
+
+		Convey("When I sign it again", func() {
+
+			nonce := req.Header.Get(HeaderNonce)
+			So(s.Sign(req), ShouldBeNil)
+
+			Convey("Then the nonce should change", func() {
+				So(req.Header.Get(HeaderNonce), ShouldNotEqual, nonce)
+			})
+		})
+	})
+
+	Convey("Given I have a Signer and a request without body", t, func() {
+
+		s := NewSigner("k1", []byte("secret"))
+		req, _ := http.NewRequest(http.MethodGet, "https://server/lists", nil)
+
+		Convey("Then the signature should be computed over an empty body", func() {
+			So(s.Sign(req), ShouldBeNil)
+			So(req.Body, ShouldBeNil)
+
+			expected := computeSignature(
+				[]byte("secret"),
+				stringToSign("k1", req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderNonce), http.MethodGet, req.URL, nil),
+			)
+			So(req.Header.Get(HeaderSignature), ShouldEqual, expected)
+		})
+	})
+}
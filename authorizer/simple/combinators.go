@@ -0,0 +1,428 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"fmt"
+
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+)
+
+// A claimsHolder is either a bahamut.Context
+// or a bahamut.Session.
+type claimsHolder interface {
+	Claims() []string
+	SetClaims([]string)
+}
+
+// An evaluator evaluates a bahamut.Context or a bahamut.Session,
+// depending on the kind of evaluation it has been built for.
+type evaluator func(claimsHolder) (bahamut.AuthAction, error)
+
+// A Combinator is a bahamut.RequestAuthenticator, bahamut.SessionAuthenticator
+// and bahamut.Authorizer combining other authenticators and authorizers.
+//
+// It is returned by AllOf, AnyOf, ForIdentities, ForOperations, Not and
+// WithClaims, and can itself be combined. When a Combinator is used as one
+// of the three interfaces, only the combined values implementing that
+// interface are evaluated. If none of them implements it, the Combinator
+// returns bahamut.AuthActionContinue.
+type Combinator struct {
+	request   evaluator
+	session   evaluator
+	authorize evaluator
+}
+
+// AuthenticateRequest implements the bahamut.RequestAuthenticator interface.
+func (c *Combinator) AuthenticateRequest(ctx bahamut.Context) (bahamut.AuthAction, error) {
+
+	if c.request == nil {
+		return bahamut.AuthActionContinue, nil
+	}
+
+	return c.request(ctx)
+}
+
+// AuthenticateSession implements the bahamut.SessionAuthenticator interface.
+func (c *Combinator) AuthenticateSession(session bahamut.Session) (bahamut.AuthAction, error) {
+
+	if c.session == nil {
+		return bahamut.AuthActionContinue, nil
+	}
+
+	return c.session(session)
+}
+
+// IsAuthorized implements the bahamut.Authorizer interface.
+func (c *Combinator) IsAuthorized(ctx bahamut.Context) (bahamut.AuthAction, error) {
+
+	if c.authorize == nil {
+		return bahamut.AuthActionContinue, nil
+	}
+
+	return c.authorize(ctx)
+}
+
+// AllOf returns a Combinator that returns bahamut.AuthActionOK only if all the
+// given authenticators or authorizers return bahamut.AuthActionOK.
+//
+// They are evaluated in order. As soon as one returns bahamut.AuthActionKO or
+// an error, the evaluation stops and the Combinator returns bahamut.AuthActionKO
+// with that error. If none fails but one returns bahamut.AuthActionContinue,
+// the Combinator returns bahamut.AuthActionContinue.
+//
+// The claims set by the successful authenticators are merged, so each one
+// sees the claims set by the previous ones. If the result is not
+// bahamut.AuthActionOK, the original claims are restored.
+//
+// The values must implement at least one of bahamut.RequestAuthenticator,
+// bahamut.SessionAuthenticator or bahamut.Authorizer, or AllOf will panic.
+func AllOf(auths ...interface{}) *Combinator {
+
+	return combine(auths, func(evaluators []evaluator) evaluator {
+
+		return func(h claimsHolder) (bahamut.AuthAction, error) {
+
+			original := h.Claims()
+			claims := original
+			result := bahamut.AuthActionOK
+
+			for _, e := range evaluators {
+
+				action, err := e(h)
+				if err != nil {
+					h.SetClaims(original)
+					return bahamut.AuthActionKO, err
+				}
+
+				switch action {
+
+				case bahamut.AuthActionKO:
+					h.SetClaims(original)
+					return bahamut.AuthActionKO, nil
+
+				case bahamut.AuthActionContinue:
+					result = bahamut.AuthActionContinue
+					h.SetClaims(claims)
+
+				case bahamut.AuthActionOK:
+					claims = mergeClaims(claims, h.Claims())
+					h.SetClaims(claims)
+				}
+			}
+
+			if result != bahamut.AuthActionOK {
+				h.SetClaims(original)
+			}
+
+			return result, nil
+		}
+	})
+}
+
+// AnyOf returns a Combinator that returns bahamut.AuthActionOK as soon as one
+// of the given authenticators or authorizers returns bahamut.AuthActionOK.
+//
+// They are evaluated in order. If none succeeds, the Combinator returns
+// bahamut.AuthActionKO if one of them failed, or bahamut.AuthActionContinue
+// otherwise. In case of failure, the errors returned by all of them are
+// aggregated into an elemental.Errors.
+//
+// The values must implement at least one of bahamut.RequestAuthenticator,
+// bahamut.SessionAuthenticator or bahamut.Authorizer, or AnyOf will panic.
+func AnyOf(auths ...interface{}) *Combinator {
+
+	return combine(auths, func(evaluators []evaluator) evaluator {
+
+		return func(h claimsHolder) (bahamut.AuthAction, error) {
+
+			original := h.Claims()
+
+			var errs []error
+			var failed bool
+
+			for _, e := range evaluators {
+
+				action, err := e(h)
+				if err != nil {
+					errs = append(errs, err)
+					h.SetClaims(original)
+					continue
+				}
+
+				switch action {
+
+				case bahamut.AuthActionOK:
+					return bahamut.AuthActionOK, nil
+
+				case bahamut.AuthActionKO:
+					failed = true
+					h.SetClaims(original)
+				}
+			}
+
+			switch len(errs) {
+			case 0:
+			case 1:
+				return bahamut.AuthActionKO, errs[0]
+			default:
+				return bahamut.AuthActionKO, elemental.NewErrors(errs...)
+			}
+
+			if failed {
+				return bahamut.AuthActionKO, nil
+			}
+
+			return bahamut.AuthActionContinue, nil
+		}
+	})
+}
+
+// ForIdentities returns a Combinator that evaluates the given authenticator
+// or authorizer only for the requests on one of the given identities. For
+// the other requests, it returns bahamut.AuthActionContinue.
+//
+// As sessions are not bound to an identity, the Combinator always returns
+// bahamut.AuthActionContinue when used as a bahamut.SessionAuthenticator.
+func ForIdentities(auth interface{}, identities ...elemental.Identity) *Combinator {
+
+	if len(identities) == 0 {
+		panic("identities must not be empty")
+	}
+
+	return forRequests(auth, func(req *elemental.Request) bool {
+		for _, i := range identities {
+			if req.Identity.IsEqual(i) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ForOperations returns a Combinator that evaluates the given authenticator
+// or authorizer only for the requests with one of the given operations. For
+// the other requests, it returns bahamut.AuthActionContinue.
+//
+// As sessions are not bound to an operation, the Combinator always returns
+// bahamut.AuthActionContinue when used as a bahamut.SessionAuthenticator.
+func ForOperations(auth interface{}, operations ...elemental.Operation) *Combinator {
+
+	if len(operations) == 0 {
+		panic("operations must not be empty")
+	}
+
+	return forRequests(auth, func(req *elemental.Request) bool {
+		for _, op := range operations {
+			if req.Operation == op {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not returns a Combinator that negates the result of the given authenticator
+// or authorizer: bahamut.AuthActionOK becomes bahamut.AuthActionKO and the
+// other way around. bahamut.AuthActionContinue is left untouched, and errors
+// are returned along with bahamut.AuthActionKO.
+//
+// The claims set by the given authenticator are discarded. This means that
+// a Not used alone as an authenticator authenticates the callers rejected
+// by the given authenticator without setting any claims. It should only be
+// used as an authorizer, or in AllOf after an authenticator setting the
+// claims, for instance:
+//
+//	AllOf(mtlsAuthenticator, Not(WithClaims("@auth:realm=apikey")))
+func Not(auth interface{}) *Combinator {
+
+	return combine([]interface{}{auth}, func(evaluators []evaluator) evaluator {
+
+		return func(h claimsHolder) (bahamut.AuthAction, error) {
+
+			original := h.Claims()
+
+			action, err := evaluators[0](h)
+			h.SetClaims(original)
+
+			if err != nil {
+				return bahamut.AuthActionKO, err
+			}
+
+			switch action {
+			case bahamut.AuthActionOK:
+				return bahamut.AuthActionKO, nil
+			case bahamut.AuthActionKO:
+				return bahamut.AuthActionOK, nil
+			default:
+				return action, nil
+			}
+		}
+	})
+}
+
+// WithClaims returns a Combinator that returns bahamut.AuthActionOK if the
+// context or session holds all the given claims, and bahamut.AuthActionKO
+// otherwise.
+//
+// As the claims are set during authentication, it is mostly meant to be used
+// as an authorizer, or in AllOf after an authenticator, for instance:
+//
+//	AllOf(mtlsAuthenticator, WithClaims("@auth:organization=system"))
+func WithClaims(claims ...string) *Combinator {
+
+	if len(claims) == 0 {
+		panic("claims must not be empty")
+	}
+
+	e := func(h claimsHolder) (bahamut.AuthAction, error) {
+
+		held := map[string]struct{}{}
+		for _, c := range h.Claims() {
+			held[c] = struct{}{}
+		}
+
+		for _, c := range claims {
+			if _, ok := held[c]; !ok {
+				return bahamut.AuthActionKO, nil
+			}
+		}
+
+		return bahamut.AuthActionOK, nil
+	}
+
+	return &Combinator{
+		request:   e,
+		session:   e,
+		authorize: e,
+	}
+}
+
+// combine returns a Combinator evaluating the given values
+// using the evaluator returned by the given function.
+//
+// A Combinator implements all three interfaces, so only the
+// evaluators it actually holds are used when one is given.
+func combine(auths []interface{}, combiner func([]evaluator) evaluator) *Combinator {
+
+	if len(auths) == 0 {
+		panic("at least one authenticator or authorizer must be given")
+	}
+
+	var requests, sessions, authorizes []evaluator
+
+	for i, auth := range auths {
+
+		if c, ok := auth.(*Combinator); ok {
+
+			if c.request != nil {
+				requests = append(requests, c.request)
+			}
+
+			if c.session != nil {
+				sessions = append(sessions, c.session)
+			}
+
+			if c.authorize != nil {
+				authorizes = append(authorizes, c.authorize)
+			}
+
+			continue
+		}
+
+		var implemented bool
+
+		if a, ok := auth.(bahamut.RequestAuthenticator); ok {
+			requests = append(requests, func(h claimsHolder) (bahamut.AuthAction, error) {
+				return a.AuthenticateRequest(h.(bahamut.Context))
+			})
+			implemented = true
+		}
+
+		if a, ok := auth.(bahamut.SessionAuthenticator); ok {
+			sessions = append(sessions, func(h claimsHolder) (bahamut.AuthAction, error) {
+				return a.AuthenticateSession(h.(bahamut.Session))
+			})
+			implemented = true
+		}
+
+		if a, ok := auth.(bahamut.Authorizer); ok {
+			authorizes = append(authorizes, func(h claimsHolder) (bahamut.AuthAction, error) {
+				return a.IsAuthorized(h.(bahamut.Context))
+			})
+			implemented = true
+		}
+
+		if !implemented {
+			panic(fmt.Sprintf("value at index %d of type %T is not an authenticator or an authorizer", i, auth))
+		}
+	}
+
+	c := &Combinator{}
+
+	if len(requests) > 0 {
+		c.request = combiner(requests)
+	}
+
+	if len(sessions) > 0 {
+		c.session = combiner(sessions)
+	}
+
+	if len(authorizes) > 0 {
+		c.authorize = combiner(authorizes)
+	}
+
+	return c
+}
+
+// forRequests returns a Combinator evaluating the given value
+// only for the requests matching the given function.
+func forRequests(auth interface{}, matches func(*elemental.Request) bool) *Combinator {
+
+	c := combine([]interface{}{auth}, func(evaluators []evaluator) evaluator {
+
+		return func(h claimsHolder) (bahamut.AuthAction, error) {
+
+			if !matches(h.(bahamut.Context).Request()) {
+				return bahamut.AuthActionContinue, nil
+			}
+
+			return evaluators[0](h)
+		}
+	})
+
+	c.session = nil
+
+	return c
+}
+
+// mergeClaims returns the claims of a followed
+// by the claims of b that are not in a.
+func mergeClaims(a []string, b []string) []string {
+
+	out := append([]string{}, a...)
+
+	seen := make(map[string]struct{}, len(a))
+	for _, c := range a {
+		seen[c] = struct{}{}
+	}
+
+	for _, c := range b {
+		if _, ok := seen[c]; !ok {
+			seen[c] = struct{}{}
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,430 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simple
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/bahamut"
+	"go.aporeto.io/elemental"
+)
+
+type mockSession struct {
+	claims []string
+}
+
+func (s *mockSession) Cookie(string) (*http.Cookie, error)      { return nil, nil }
+func (s *mockSession) Identifier() string                       { return "" }
+func (s *mockSession) Parameter(string) string                  { return "" }
+func (s *mockSession) Header(string) string                     { return "" }
+func (s *mockSession) PushConfig() *elemental.PushConfig        { return nil }
+func (s *mockSession) SetClaims(c []string)                     { s.claims = c }
+func (s *mockSession) Claims() []string                         { return s.claims }
+func (s *mockSession) ClaimsMap() map[string]string             { return nil }
+func (s *mockSession) Token() string                            { return "" }
+func (s *mockSession) TLSConnectionState() *tls.ConnectionState { return nil }
+func (s *mockSession) Metadata() interface{}                    { return nil }
+func (s *mockSession) SetMetadata(interface{})                  {}
+func (s *mockSession) Context() context.Context                 { return context.Background() }
+func (s *mockSession) ClientIP() string                         { return "" }
+
+// makeAuth returns an Authenticator and Authorizer returning the given
+// action and error, and setting the given claims when the action is OK.
+func makeAuth(action bahamut.AuthAction, err error, claims ...string) *Combinator {
+
+	f := func(h claimsHolder) (bahamut.AuthAction, error) {
+		if action == bahamut.AuthActionOK && len(claims) > 0 {
+			h.SetClaims(claims)
+		}
+		return action, err
+	}
+
+	return AllOf(
+		NewAuthenticator(
+			func(ctx bahamut.Context) (bahamut.AuthAction, error) { return f(ctx) },
+			func(s bahamut.Session) (bahamut.AuthAction, error) { return f(s) },
+		),
+		NewAuthorizer(
+			func(ctx bahamut.Context) (bahamut.AuthAction, error) { return f(ctx) },
+		),
+	)
+}
+
+func newTestContext(identity elemental.Identity, operation elemental.Operation) bahamut.Context {
+
+	req := elemental.NewRequest()
+	req.Identity = identity
+	req.Operation = operation
+
+	return bahamut.NewContext(context.Background(), req)
+}
+
+func TestCombinator_Interfaces(t *testing.T) {
+
+	Convey("Given I have a Combinator", t, func() {
+
+		c := AllOf(NewAuthorizer(nil))
+
+		Convey("Then it should implement the interfaces", func() {
+			So(c, ShouldImplement, (*bahamut.RequestAuthenticator)(nil))
+			So(c, ShouldImplement, (*bahamut.SessionAuthenticator)(nil))
+			So(c, ShouldImplement, (*bahamut.Authorizer)(nil))
+		})
+	})
+
+	Convey("Given I combine only an Authorizer", t, func() {
+
+		c := AllOf(NewAuthorizer(func(bahamut.Context) (bahamut.AuthAction, error) { return bahamut.AuthActionKO, nil }))
+
+		Convey("Then it should return Continue when used as an authenticator", func() {
+
+			action, err := c.AuthenticateRequest(newTestContext(elemental.Identity{}, elemental.OperationCreate))
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionContinue)
+
+			action, err = c.AuthenticateSession(&mockSession{})
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionContinue)
+		})
+
+		Convey("Then it should evaluate it when used as an authorizer", func() {
+
+			action, err := c.IsAuthorized(newTestContext(elemental.Identity{}, elemental.OperationCreate))
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionKO)
+		})
+	})
+
+	Convey("Given I combine an authenticator with a Combinator holding only an Authorizer", t, func() {
+
+		authz := AllOf(NewAuthorizer(func(bahamut.Context) (bahamut.AuthAction, error) { return bahamut.AuthActionKO, nil }))
+		c := AllOf(makeAuth(bahamut.AuthActionOK, nil, "a=a"), authz)
+
+		Convey("Then the nested Combinator should be ignored when used as an authenticator", func() {
+
+			ctx := newTestContext(elemental.Identity{}, elemental.OperationCreate)
+			action, err := c.AuthenticateRequest(ctx)
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionOK)
+			So(ctx.Claims(), ShouldResemble, []string{"a=a"})
+
+			session := &mockSession{}
+			action, err = c.AuthenticateSession(session)
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionOK)
+			So(session.claims, ShouldResemble, []string{"a=a"})
+		})
+
+		Convey("Then the nested Combinator should be evaluated when used as an authorizer", func() {
+
+			action, err := c.IsAuthorized(newTestContext(elemental.Identity{}, elemental.OperationCreate))
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionKO)
+		})
+	})
+
+	Convey("Given I combine a Combinator holding no evaluator for sessions", t, func() {
+
+		c := AnyOf(ForOperations(makeAuth(bahamut.AuthActionOK, nil), elemental.OperationCreate))
+
+		Convey("Then it should return Continue when used as a session authenticator", func() {
+
+			action, err := c.AuthenticateSession(&mockSession{})
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionContinue)
+		})
+
+		Convey("Then it should evaluate it for the requests", func() {
+
+			action, err := c.AuthenticateRequest(newTestContext(elemental.Identity{}, elemental.OperationCreate))
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionOK)
+		})
+	})
+
+	Convey("Calling a combinator with invalid values should panic", t, func() {
+		So(func() { AllOf() }, ShouldPanic)
+		So(func() { AnyOf("nope") }, ShouldPanicWith, "value at index 0 of type string is not an authenticator or an authorizer")
+		So(func() { Not(nil) }, ShouldPanic)
+		So(func() { ForIdentities(makeAuth(bahamut.AuthActionOK, nil)) }, ShouldPanic)
+		So(func() { ForOperations(makeAuth(bahamut.AuthActionOK, nil)) }, ShouldPanic)
+		So(func() { WithClaims() }, ShouldPanic)
+	})
+}
+
+func TestCombinator_AllOf(t *testing.T) {
+
+	ok := bahamut.AuthActionOK
+	ko := bahamut.AuthActionKO
+	cont := bahamut.AuthActionContinue
+
+	Convey("Given I have some authenticators", t, func() {
+
+		Convey("When all of them return OK", func() {
+
+			c := AllOf(makeAuth(ok, nil, "a=a"), makeAuth(ok, nil, "b=b", "a=a"))
+			ctx := newTestContext(elemental.Identity{}, elemental.OperationCreate)
+			action, err := c.AuthenticateRequest(ctx)
+
+			Convey("Then action should be OK and the claims merged", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, ok)
+				So(ctx.Claims(), ShouldResemble, []string{"a=a", "b=b"})
+			})
+		})
+
+		Convey("When one of them returns KO", func() {
+
+			c := AllOf(makeAuth(ok, nil, "a=a"), makeAuth(ko, nil), makeAuth(ok, fmt.Errorf("not evaluated")))
+			ctx := newTestContext(elemental.Identity{}, elemental.OperationCreate)
+			action, err := c.AuthenticateRequest(ctx)
+
+			Convey("Then action should be KO and the claims restored", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, ko)
+				So(ctx.Claims(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When one of them returns an error", func() {
+
+			c := AllOf(makeAuth(ok, nil), makeAuth(ko, fmt.Errorf("boom")))
+			action, err := c.IsAuthorized(newTestContext(elemental.Identity{}, elemental.OperationCreate))
+
+			Convey("Then action should be KO with the error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "boom")
+				So(action, ShouldEqual, ko)
+			})
+		})
+
+		Convey("When one of them returns Continue", func() {
+
+			c := AllOf(makeAuth(ok, nil, "a=a"), makeAuth(cont, nil))
+			session := &mockSession{}
+			action, err := c.AuthenticateSession(session)
+
+			Convey("Then action should be Continue and the claims restored", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, cont)
+				So(session.claims, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When one returns Continue and a later one returns KO", func() {
+
+			c := AllOf(makeAuth(cont, nil), makeAuth(ko, nil))
+			action, err := c.AuthenticateSession(&mockSession{})
+
+			Convey("Then action should be KO", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, ko)
+			})
+		})
+
+		Convey("When I check claims set by a previous authenticator", func() {
+
+			ctx := newTestContext(elemental.Identity{}, elemental.OperationCreate)
+
+			action1, _ := AllOf(makeAuth(ok, nil, "a=a"), WithClaims("a=a")).AuthenticateRequest(ctx)
+			action2, _ := AllOf(makeAuth(ok, nil, "a=a"), WithClaims("b=b")).AuthenticateRequest(ctx)
+
+			Convey("Then the claims should be visible", func() {
+				So(action1, ShouldEqual, ok)
+				So(action2, ShouldEqual, ko)
+			})
+		})
+	})
+}
+
+func TestCombinator_AnyOf(t *testing.T) {
+
+	ok := bahamut.AuthActionOK
+	ko := bahamut.AuthActionKO
+	cont := bahamut.AuthActionContinue
+
+	Convey("Given I have some authenticators", t, func() {
+
+		Convey("When one of them returns OK", func() {
+
+			c := AnyOf(makeAuth(ko, nil), makeAuth(cont, nil), makeAuth(ok, nil, "b=b"), makeAuth(ko, fmt.Errorf("not evaluated")))
+			ctx := newTestContext(elemental.Identity{}, elemental.OperationCreate)
+			action, err := c.AuthenticateRequest(ctx)
+
+			Convey("Then action should be OK", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, ok)
+				So(ctx.Claims(), ShouldResemble, []string{"b=b"})
+			})
+		})
+
+		Convey("When none returns OK and one returns KO", func() {
+
+			c := AnyOf(makeAuth(cont, nil), makeAuth(ko, nil))
+			action, err := c.IsAuthorized(newTestContext(elemental.Identity{}, elemental.OperationCreate))
+
+			Convey("Then action should be KO", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, ko)
+			})
+		})
+
+		Convey("When all of them return Continue", func() {
+
+			c := AnyOf(makeAuth(cont, nil), makeAuth(cont, nil))
+			action, err := c.AuthenticateSession(&mockSession{})
+
+			Convey("Then action should be Continue", func() {
+				So(err, ShouldBeNil)
+				So(action, ShouldEqual, cont)
+			})
+		})
+
+		Convey("When one of them returns an error", func() {
+
+			err1 := elemental.NewError("Nope", "nope", "test", http.StatusUnauthorized)
+			c := AnyOf(makeAuth(ko, err1), makeAuth(cont, nil))
+			action, err := c.AuthenticateSession(&mockSession{})
+
+			Convey("Then action should be KO with the error", func() {
+				So(action, ShouldEqual, ko)
+				So(err, ShouldResemble, err1)
+			})
+		})
+
+		Convey("When several of them return an error", func() {
+
+			err1 := elemental.NewError("Nope", "nope", "test", http.StatusUnauthorized)
+			err2 := elemental.NewError("Revoked", "revoked", "test", http.StatusUnauthorized)
+			c := AnyOf(makeAuth(ko, err1), makeAuth(ko, nil), makeAuth(ko, err2))
+			action, err := c.AuthenticateRequest(newTestContext(elemental.Identity{}, elemental.OperationCreate))
+
+			Convey("Then action should be KO with the aggregated errors", func() {
+				So(action, ShouldEqual, ko)
+				So(err, ShouldResemble, elemental.NewErrors(err1, err2))
+			})
+		})
+	})
+}
+
+func TestCombinator_ForIdentitiesAndOperations(t *testing.T) {
+
+	list := elemental.MakeIdentity("list", "lists")
+	task := elemental.MakeIdentity("task", "tasks")
+
+	Convey("Given I have an authorizer restricted to some identities", t, func() {
+
+		c := ForIdentities(makeAuth(bahamut.AuthActionKO, nil), list)
+
+		Convey("Then it should be evaluated for the matching identities", func() {
+			action, _ := c.IsAuthorized(newTestContext(list, elemental.OperationCreate))
+			So(action, ShouldEqual, bahamut.AuthActionKO)
+		})
+
+		Convey("Then it should return Continue for the other identities", func() {
+			action, _ := c.IsAuthorized(newTestContext(task, elemental.OperationCreate))
+			So(action, ShouldEqual, bahamut.AuthActionContinue)
+		})
+
+		Convey("Then it should return Continue for sessions", func() {
+			action, _ := c.AuthenticateSession(&mockSession{})
+			So(action, ShouldEqual, bahamut.AuthActionContinue)
+		})
+	})
+
+	Convey("Given I have an authorizer restricted to some operations", t, func() {
+
+		c := ForOperations(makeAuth(bahamut.AuthActionKO, nil), elemental.OperationDelete, elemental.OperationUpdate)
+
+		Convey("Then it should be evaluated for the matching operations", func() {
+			action, _ := c.IsAuthorized(newTestContext(list, elemental.OperationDelete))
+			So(action, ShouldEqual, bahamut.AuthActionKO)
+			action, _ = c.AuthenticateRequest(newTestContext(list, elemental.OperationUpdate))
+			So(action, ShouldEqual, bahamut.AuthActionKO)
+		})
+
+		Convey("Then it should return Continue for the other operations", func() {
+			action, _ := c.IsAuthorized(newTestContext(list, elemental.OperationRetrieve))
+			So(action, ShouldEqual, bahamut.AuthActionContinue)
+		})
+	})
+}
+
+func TestCombinator_Not(t *testing.T) {
+
+	Convey("Given I negate some authorizers", t, func() {
+
+		ctx := newTestContext(elemental.Identity{}, elemental.OperationCreate)
+
+		Convey("Then OK should become KO and the claims discarded", func() {
+			action, err := Not(makeAuth(bahamut.AuthActionOK, nil, "a=a")).AuthenticateRequest(ctx)
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionKO)
+			So(ctx.Claims(), ShouldBeEmpty)
+		})
+
+		Convey("Then KO should become OK", func() {
+			action, err := Not(makeAuth(bahamut.AuthActionKO, nil)).IsAuthorized(ctx)
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionOK)
+		})
+
+		Convey("Then Continue should stay Continue", func() {
+			action, err := Not(makeAuth(bahamut.AuthActionContinue, nil)).AuthenticateSession(&mockSession{})
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionContinue)
+		})
+
+		Convey("Then errors should be returned with KO", func() {
+			action, err := Not(makeAuth(bahamut.AuthActionKO, fmt.Errorf("boom"))).IsAuthorized(ctx)
+			So(err, ShouldNotBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionKO)
+		})
+	})
+}
+
+func TestCombinator_WithClaims(t *testing.T) {
+
+	Convey("Given I have a context with some claims", t, func() {
+
+		ctx := newTestContext(elemental.Identity{}, elemental.OperationCreate)
+		ctx.SetClaims([]string{"a=a", "b=b"})
+
+		Convey("Then it should be authorized if it has all the claims", func() {
+			action, err := WithClaims("a=a", "b=b").IsAuthorized(ctx)
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionOK)
+		})
+
+		Convey("Then it should not be authorized if it misses one claim", func() {
+			action, err := WithClaims("a=a", "c=c").IsAuthorized(ctx)
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionKO)
+		})
+	})
+
+	Convey("Given I have a session with some claims", t, func() {
+
+		session := &mockSession{claims: []string{"a=a"}}
+
+		Convey("Then it should be authenticated if it has all the claims", func() {
+			action, err := WithClaims("a=a").AuthenticateSession(session)
+			So(err, ShouldBeNil)
+			So(action, ShouldEqual, bahamut.AuthActionOK)
+		})
+	})
+}
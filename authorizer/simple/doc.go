@@ -12,4 +12,8 @@
 // Package simple provides implementations of bahamut.SessionAuthenticator
 // bahamut.RequestAuthenticator and a bahamut.Authorizer using
 // a given function to decide if a request should be authenticated/authorized.
+//
+// It also provides combinators, like AllOf, AnyOf or Not, to combine
+// authenticators and authorizers beyond the "first non-Continue wins"
+// semantics of bahamut.CheckAuthentication and bahamut.CheckAuthorization.
 package simple // import "go.aporeto.io/bahamut/authorizer/simple"
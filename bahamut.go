@@ -18,6 +18,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-zoo/bone"
 	"go.aporeto.io/elemental"
@@ -128,7 +129,7 @@ func NewServer(cfg config) Server {
 	}
 
 	if cfg.restServer.enabled {
-		srv.restServer = newRestServer(cfg, mux, srv.ProcessorForIdentity, srv.CustomHandlers, srv.Push)
+		srv.restServer = newRestServer(cfg, mux, srv.ProcessorForIdentity, srv.CustomHandlers, srv.push)
 	}
 
 	if cfg.pushServer.enabled {
@@ -222,11 +223,18 @@ func (b *server) ProcessorsCount() int {
 
 func (b *server) Push(events ...*elemental.Event) {
 
+	b.push(context.Background(), events...)
+}
+
+// push pushes the given events, using the given context
+// as the parent of the publication trace.
+func (b *server) push(ctx context.Context, events ...*elemental.Event) {
+
 	if b.pushServer == nil {
 		return
 	}
 
-	b.pushServer.pushEvents(events...)
+	b.pushServer.pushEvents(ctx, events...)
 }
 
 func (b *server) RoutesInfo() map[int][]RouteInfo {
@@ -262,8 +270,17 @@ func (b *server) Run(ctx context.Context) {
 		go b.restServer.start(ctx, b.RoutesInfo())
 	}
 
+	// When draining, the push server must outlive the main
+	// context so the push sessions are closed only once
+	// the server has been drained.
+	pushCtx, pushCancel := ctx, func() {}
+	if b.cfg.drain.enabled {
+		pushCtx, pushCancel = context.WithCancel(context.Background())
+	}
+	defer pushCancel()
+
 	if b.pushServer != nil {
-		go b.pushServer.start(ctx)
+		go b.pushServer.start(pushCtx)
 	}
 
 	if b.healthServer != nil {
@@ -276,6 +293,10 @@ func (b *server) Run(ctx context.Context) {
 		}
 	}
 
+	if b.healthServer != nil {
+		b.healthServer.setReady(true)
+	}
+
 	<-ctx.Done()
 
 	if hook := b.cfg.hooks.preStop; hook != nil {
@@ -284,6 +305,22 @@ func (b *server) Run(ctx context.Context) {
 		}
 	}
 
+	if b.healthServer != nil {
+		b.healthServer.setReady(false)
+	}
+
+	// When draining, we give some time to the load balancers
+	// to notice we are not ready anymore before closing the
+	// push sessions and stopping the API server.
+	if b.cfg.drain.enabled {
+		zap.L().Info("Draining server",
+			zap.Duration("pre-drain-delay", b.cfg.drain.preDrainDelay),
+			zap.Duration("timeout", b.cfg.drain.timeout),
+		)
+		time.Sleep(b.cfg.drain.preDrainDelay)
+		pushCancel()
+	}
+
 	// Stop the health server first so we become unhealthy.
 	if b.healthServer != nil {
 		<-b.healthServer.stop().Done()
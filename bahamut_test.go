@@ -12,8 +12,11 @@
 package bahamut
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"go.aporeto.io/elemental"
@@ -435,3 +438,75 @@ func TestBahamyt_RegistorProcessorOrDie(t *testing.T) {
 		})
 	})
 }
+
+func TestBahamut_RunWithDrain(t *testing.T) {
+
+	Convey("Given I have a bahamut server with drain enabled", t, func() {
+
+		port := freePort()
+		cfg := config{}
+		cfg.healthServer.enabled = true
+		cfg.healthServer.listenAddress = fmt.Sprintf("127.0.0.1:%d", port)
+		cfg.healthServer.pingInterval = time.Second
+		cfg.healthServer.pingTimeout = time.Second
+		cfg.healthServer.pingers = map[string]Pinger{"p1": MockPinger{}}
+		cfg.drain.enabled = true
+		cfg.drain.preDrainDelay = 1 * time.Second
+		cfg.drain.timeout = 1 * time.Second
+
+		preStopCalled := make(chan struct{})
+		cfg.hooks.preStop = func(Server) error {
+			close(preStopCalled)
+			return nil
+		}
+
+		b := NewServer(cfg)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stopped := make(chan struct{})
+		go func() {
+			b.Run(ctx)
+			close(stopped)
+		}()
+
+		time.Sleep(500 * time.Millisecond)
+
+		Convey("When I get /ready while running", func() {
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/ready", port))
+
+			Convey("Then the server should be ready", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When I stop the server", func() {
+
+			cancel()
+			<-preStopCalled
+			time.Sleep(100 * time.Millisecond)
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/ready", port))
+
+			Convey("Then the server should not be ready during the pre drain delay", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			})
+
+			Convey("Then the server should stop after the pre drain delay", func() {
+
+				var stoppedInTime bool
+				select {
+				case <-stopped:
+					stoppedInTime = true
+				case <-time.After(3 * time.Second):
+				}
+
+				So(stoppedInTime, ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -21,6 +21,7 @@ import (
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"go.aporeto.io/elemental"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
@@ -90,11 +91,29 @@ type config struct {
 		enabled        bool
 		customStats    map[string]HealthStatFunc
 		metricsManager MetricsManager
+		pingers        map[string]Pinger
+		pingInterval   time.Duration
+		pingTimeout    time.Duration
+		pingTimeouts   map[string]time.Duration
 	}
 
 	profilingServer struct {
-		listenAddress string
-		enabled       bool
+		listenAddress        string
+		enabled              bool
+		ratesConfigured      bool
+		mutexProfileFraction int
+		blockProfileRate     int
+		capture              struct {
+			enabled         bool
+			directory       string
+			interval        time.Duration
+			cpuDuration     time.Duration
+			maxCount        int
+			maxAge          time.Duration
+			triggers        []ProfilingTrigger
+			triggerInterval time.Duration
+			cooldown        time.Duration
+		}
 	}
 
 	tls struct {
@@ -111,6 +130,7 @@ type config struct {
 		sessionAuthenticators []SessionAuthenticator
 		authorizers           []Authorizer
 		auditer               Auditer
+		authDebugClaim        string
 	}
 
 	rateLimiting struct {
@@ -140,9 +160,31 @@ type config struct {
 		traceCleaner       TraceCleaner
 	}
 
+	opentelemetry struct {
+		tracerProvider trace.TracerProvider
+	}
+
 	hooks struct {
 		postStart        func(Server) error
 		preStop          func(Server) error
 		errorTransformer func(error) error
+		errorReporter    *errorReporter
+	}
+
+	accessLog struct {
+		enabled            bool
+		sampleRate         float64
+		slowThreshold      time.Duration
+		slowThresholds     map[elemental.Identity]time.Duration
+		redactedHeaders    []string
+		redactedAttributes []string
+		attributes         bool
+	}
+
+	drain struct {
+		enabled        bool
+		preDrainDelay  time.Duration
+		timeout        time.Duration
+		reconnectDelay time.Duration
 	}
 }
@@ -15,6 +15,7 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"go.aporeto.io/elemental"
@@ -40,6 +41,18 @@ type bcontext struct {
 	responseWriter        ResponseWriter
 	statusCode            int
 	disableOutputDataPush bool
+	failureStage          FailureStage
+	startTime             time.Time
+	timings               requestTimings
+}
+
+// requestTimings holds the time spent in
+// the various stages of a request.
+type requestTimings struct {
+	authentication time.Duration
+	authorization  time.Duration
+	processor      time.Duration
+	encoding       time.Duration
 }
 
 // NewContext creates a new *Context.
@@ -61,6 +74,7 @@ func newContext(ctx context.Context, request *elemental.Request) *bcontext {
 		id:           uuid.Must(uuid.NewV4()).String(),
 		messagesLock: &sync.Mutex{},
 		request:      request,
+		startTime:    time.Now(),
 	}
 }
 
@@ -215,6 +229,9 @@ func (c *bcontext) Duplicate() Context {
 	c2.outputCookies = append(c2.outputCookies, c.outputCookies...)
 	c2.responseWriter = c.responseWriter
 	c2.disableOutputDataPush = c.disableOutputDataPush
+	c2.failureStage = c.failureStage
+	c2.startTime = c.startTime
+	c2.timings = c.timings
 
 	for k, v := range c.claimsMap {
 		c2.claimsMap[k] = v
@@ -229,3 +246,17 @@ func (c *bcontext) Duplicate() Context {
 
 	return c2
 }
+
+// process calls the given processor function and
+// records the time spent in it.
+func (c *bcontext) process(f func(Context) error) error {
+
+	defer c.addTiming(&c.timings.processor, time.Now())
+
+	return f(c)
+}
+
+// addTiming adds the time elapsed since start to the given duration.
+func (c *bcontext) addTiming(d *time.Duration, start time.Time) {
+	*d += time.Since(start)
+}
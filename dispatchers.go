@@ -47,11 +47,13 @@ func dispatchRetrieveManyOperation(
 ) (err error) {
 
 	if err = CheckAuthentication(authenticators, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthentication
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if err = CheckAuthorization(authorizers, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthorization
 		audit(auditer, ctx, err)
 		return err
 	}
@@ -64,13 +66,13 @@ func dispatchRetrieveManyOperation(
 		return err
 	}
 
-	if err = proc.(RetrieveManyProcessor).ProcessRetrieveMany(ctx); err != nil {
+	if err = ctx.process(proc.(RetrieveManyProcessor).ProcessRetrieveMany); err != nil {
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if len(ctx.events) > 0 {
-		pusher(ctx.events...)
+		pusher(ctx.ctx, ctx.events...)
 	}
 
 	audit(auditer, ctx, nil)
@@ -88,11 +90,13 @@ func dispatchRetrieveOperation(
 ) (err error) {
 
 	if err = CheckAuthentication(authenticators, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthentication
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if err = CheckAuthorization(authorizers, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthorization
 		audit(auditer, ctx, err)
 		return err
 	}
@@ -105,13 +109,13 @@ func dispatchRetrieveOperation(
 		return err
 	}
 
-	if err = proc.(RetrieveProcessor).ProcessRetrieve(ctx); err != nil {
+	if err = ctx.process(proc.(RetrieveProcessor).ProcessRetrieve); err != nil {
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if len(ctx.events) > 0 {
-		pusher(ctx.events...)
+		pusher(ctx.ctx, ctx.events...)
 	}
 
 	audit(auditer, ctx, nil)
@@ -133,17 +137,20 @@ func dispatchCreateOperation(
 ) (err error) {
 
 	if err = CheckAuthentication(authenticators, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthentication
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if err = CheckAuthorization(authorizers, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthorization
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if readOnlyMode {
 		if err = makeReadOnlyError(ctx.request.Identity, readOnlyExclusion); err != nil {
+			ctx.failureStage = FailureStageReadOnly
 			return err
 		}
 	}
@@ -182,18 +189,18 @@ func dispatchCreateOperation(
 
 	ctx.inputData = obj
 
-	if err = proc.(CreateProcessor).ProcessCreate(ctx); err != nil {
+	if err = ctx.process(proc.(CreateProcessor).ProcessCreate); err != nil {
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if len(ctx.events) > 0 {
-		pusher(ctx.events...)
+		pusher(ctx.ctx, ctx.events...)
 	}
 
 	if o, ok := ctx.outputData.(elemental.Identifiable); ok && !ctx.disableOutputDataPush {
 		elemental.ResetSecretAttributesValues(o)
-		pusher(elemental.NewEvent(elemental.EventCreate, o))
+		pusher(ctx.ctx, elemental.NewEvent(elemental.EventCreate, o))
 	}
 
 	audit(auditer, ctx, nil)
@@ -215,17 +222,20 @@ func dispatchUpdateOperation(
 ) (err error) {
 
 	if err = CheckAuthentication(authenticators, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthentication
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if err = CheckAuthorization(authorizers, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthorization
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if readOnlyMode {
 		if err = makeReadOnlyError(ctx.request.Identity, readOnlyExclusion); err != nil {
+			ctx.failureStage = FailureStageReadOnly
 			return err
 		}
 	}
@@ -264,18 +274,18 @@ func dispatchUpdateOperation(
 
 	ctx.inputData = obj
 
-	if err = proc.(UpdateProcessor).ProcessUpdate(ctx); err != nil {
+	if err = ctx.process(proc.(UpdateProcessor).ProcessUpdate); err != nil {
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if len(ctx.events) > 0 {
-		pusher(ctx.events...)
+		pusher(ctx.ctx, ctx.events...)
 	}
 
 	if o, ok := ctx.outputData.(elemental.Identifiable); ok && !ctx.disableOutputDataPush {
 		elemental.ResetSecretAttributesValues(o)
-		pusher(elemental.NewEvent(elemental.EventUpdate, o))
+		pusher(ctx.ctx, elemental.NewEvent(elemental.EventUpdate, o))
 	}
 
 	audit(auditer, ctx, nil)
@@ -295,17 +305,20 @@ func dispatchDeleteOperation(
 ) (err error) {
 
 	if err = CheckAuthentication(authenticators, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthentication
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if err = CheckAuthorization(authorizers, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthorization
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if readOnlyMode {
 		if err = makeReadOnlyError(ctx.request.Identity, readOnlyExclusion); err != nil {
+			ctx.failureStage = FailureStageReadOnly
 			return err
 		}
 	}
@@ -318,18 +331,18 @@ func dispatchDeleteOperation(
 		return err
 	}
 
-	if err = proc.(DeleteProcessor).ProcessDelete(ctx); err != nil {
+	if err = ctx.process(proc.(DeleteProcessor).ProcessDelete); err != nil {
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if len(ctx.events) > 0 {
-		pusher(ctx.events...)
+		pusher(ctx.ctx, ctx.events...)
 	}
 
 	if o, ok := ctx.outputData.(elemental.Identifiable); ok && !ctx.disableOutputDataPush {
 		elemental.ResetSecretAttributesValues(o)
-		pusher(elemental.NewEvent(elemental.EventDelete, o))
+		pusher(ctx.ctx, elemental.NewEvent(elemental.EventDelete, o))
 	}
 
 	audit(auditer, ctx, nil)
@@ -352,17 +365,20 @@ func dispatchPatchOperation(
 ) (err error) {
 
 	if err = CheckAuthentication(authenticators, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthentication
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if err = CheckAuthorization(authorizers, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthorization
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if readOnlyMode {
 		if err = makeReadOnlyError(ctx.request.Identity, readOnlyExclusion); err != nil {
+			ctx.failureStage = FailureStageReadOnly
 			return err
 		}
 	}
@@ -428,25 +444,25 @@ func dispatchPatchOperation(
 
 		ctx.inputData = patchable
 
-		if err = proc.(UpdateProcessor).ProcessUpdate(ctx); err != nil {
+		if err = ctx.process(proc.(UpdateProcessor).ProcessUpdate); err != nil {
 			audit(auditer, ctx, err)
 			return err
 		}
 	} else {
 		ctx.inputData = sparse
-		if err = proc.(PatchProcessor).ProcessPatch(ctx); err != nil {
+		if err = ctx.process(proc.(PatchProcessor).ProcessPatch); err != nil {
 			audit(auditer, ctx, err)
 			return err
 		}
 	}
 
 	if len(ctx.events) > 0 {
-		pusher(ctx.events...)
+		pusher(ctx.ctx, ctx.events...)
 	}
 
 	if o, ok := ctx.outputData.(elemental.Identifiable); ok && !ctx.disableOutputDataPush {
 		elemental.ResetSecretAttributesValues(o)
-		pusher(elemental.NewEvent(elemental.EventUpdate, o))
+		pusher(ctx.ctx, elemental.NewEvent(elemental.EventUpdate, o))
 	}
 
 	audit(auditer, ctx, nil)
@@ -464,11 +480,13 @@ func dispatchInfoOperation(
 ) (err error) {
 
 	if err = CheckAuthentication(authenticators, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthentication
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if err = CheckAuthorization(authorizers, ctx); err != nil {
+		ctx.failureStage = FailureStageAuthorization
 		audit(auditer, ctx, err)
 		return err
 	}
@@ -481,13 +499,13 @@ func dispatchInfoOperation(
 		return err
 	}
 
-	if err = proc.(InfoProcessor).ProcessInfo(ctx); err != nil {
+	if err = ctx.process(proc.(InfoProcessor).ProcessInfo); err != nil {
 		audit(auditer, ctx, err)
 		return err
 	}
 
 	if len(ctx.events) > 0 {
-		pusher(ctx.events...)
+		pusher(ctx.ctx, ctx.events...)
 	}
 
 	audit(auditer, ctx, nil)
@@ -0,0 +1,231 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aporeto.io/elemental"
+	"golang.org/x/time/rate"
+)
+
+// panicFingerprintFrames is the number of stack frames,
+// from where the panic occurred, used to fingerprint it.
+const panicFingerprintFrames = 5
+
+// An ErrorReport describes a panic recovered or an
+// internal error returned while handling a request.
+type ErrorReport struct {
+	Time        time.Time           `json:"time"`
+	Fingerprint string              `json:"fingerprint"`
+	Panic       bool                `json:"panic"`
+	Value       interface{}         `json:"-"`
+	Message     string              `json:"message"`
+	Stack       string              `json:"stack,omitempty"`
+	Identity    string              `json:"identity"`
+	Operation   elemental.Operation `json:"operation"`
+	Claims      []string            `json:"claims,omitempty"`
+	TraceID     string              `json:"traceID,omitempty"`
+}
+
+// an errorReporter rate limits and deduplicates
+// the ErrorReports sent to an ErrorReporter.
+type errorReporter struct {
+	reporter    ErrorReporter
+	limiter     *rate.Limiter
+	dedupWindow time.Duration
+	seen        map[string]time.Time
+	lastPrune   time.Time
+	lock        sync.Mutex
+}
+
+// newErrorReporter returns a new errorReporter sending at most limit reports
+// per second to the given ErrorReporter, and reporting a given fingerprint
+// at most once per dedupWindow.
+func newErrorReporter(reporter ErrorReporter, limit float64, burst int, dedupWindow time.Duration) *errorReporter {
+
+	return &errorReporter{
+		reporter:    reporter,
+		limiter:     rate.NewLimiter(rate.Limit(limit), burst),
+		dedupWindow: dedupWindow,
+		seen:        map[string]time.Time{},
+	}
+}
+
+// reportPanic reports the given value recovered from a panic,
+// along with the stack of the goroutine that panicked.
+func (r *errorReporter) reportPanic(ctx *bcontext, value interface{}, stack []byte) {
+	r.report(ctx, true, value, fmt.Sprintf("panic: %v", value), stack, panicLocation(stack))
+}
+
+// reportError reports the given internal error, along with the stack
+// of the goroutine that got it. Unlike for the panics, the stack is not
+// used in the fingerprint, as the same error can be reported from many places.
+func (r *errorReporter) reportError(ctx *bcontext, err error, stack []byte) {
+	r.report(ctx, false, err, err.Error(), stack, errorKind(err))
+}
+
+// report reports the given value. The origin identifies where the value
+// comes from, and is used with the request to compute the fingerprint.
+func (r *errorReporter) report(ctx *bcontext, panicked bool, value interface{}, message string, stack []byte, origin string) {
+
+	now := time.Now()
+
+	report := ErrorReport{
+		Time:      now,
+		Panic:     panicked,
+		Value:     value,
+		Message:   message,
+		Stack:     string(stack),
+		Identity:  ctx.request.Identity.Name,
+		Operation: ctx.request.Operation,
+		Claims:    redactClaimValues(ctx.claims),
+	}
+
+	if traceID := extractTraceID(ctx.ctx); traceID != "unknown" {
+		report.TraceID = traceID
+	}
+
+	report.Fingerprint = errorFingerprint(report, origin)
+
+	if !r.shouldReport(report.Fingerprint, now) {
+		return
+	}
+
+	r.reporter.ReportError(report)
+}
+
+// shouldReport returns true if the report with the given fingerprint
+// is not a duplicate and is allowed by the rate limiter.
+func (r *errorReporter) shouldReport(fingerprint string, now time.Time) bool {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if now.Sub(r.lastPrune) > r.dedupWindow {
+		for f, t := range r.seen {
+			if now.Sub(t) > r.dedupWindow {
+				delete(r.seen, f)
+			}
+		}
+		r.lastPrune = now
+	}
+
+	if t, ok := r.seen[fingerprint]; ok && now.Sub(t) <= r.dedupWindow {
+		return false
+	}
+
+	if !r.limiter.AllowN(now, 1) {
+		return false
+	}
+
+	r.seen[fingerprint] = now
+
+	return true
+}
+
+// errorFingerprint computes the fingerprint used to deduplicate the given
+// ErrorReport from the given origin. The message is not part of it as it
+// may contain values that change from one request to another.
+func errorFingerprint(r ErrorReport, origin string) string {
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%t\n%s\n%s\n%s", r.Panic, r.Identity, r.Operation, origin) // nolint: errcheck
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// panicLocation returns the functions and the lines of the top frames
+// of the given stack, from where the panic occurred. The arguments, the
+// offsets and the goroutine IDs are removed as they change from one
+// request to another.
+func panicLocation(stack []byte) string {
+
+	lines := strings.Split(string(stack), "\n")
+	frames := make([]string, 0, panicFingerprintFrames)
+
+	// The first line is the goroutine header, then each
+	// frame is made of the function and of its location.
+	for i := 1; i+1 < len(lines); i += 2 {
+
+		fn := lines[i]
+		if idx := strings.LastIndex(fn, "("); idx > 0 && !strings.HasPrefix(fn, "created by ") {
+			fn = fn[:idx]
+		}
+		if idx := strings.Index(fn, " in goroutine "); idx > 0 {
+			fn = fn[:idx]
+		}
+
+		// The frames above the panic are the ones
+		// of the recovery, so we skip them.
+		if fn == "panic" {
+			frames = frames[:0]
+			continue
+		}
+
+		location := strings.TrimSpace(lines[i+1])
+		if idx := strings.LastIndex(location, " +0x"); idx > 0 {
+			location = location[:idx]
+		}
+
+		frames = append(frames, fn+" "+location)
+	}
+
+	if len(frames) > panicFingerprintFrames {
+		frames = frames[:panicFingerprintFrames]
+	}
+
+	return strings.Join(frames, "\n")
+}
+
+// errorKind returns the type of the given error, along with
+// the titles of the elemental errors it holds, if any.
+func errorKind(err error) string {
+
+	switch e := err.(type) {
+
+	case elemental.Error:
+		return fmt.Sprintf("%T %s", e, e.Title)
+
+	case elemental.Errors:
+		titles := make([]string, len(e))
+		for i, ee := range e {
+			titles[i] = ee.Title
+		}
+		return fmt.Sprintf("%T %s", e, strings.Join(titles, ","))
+
+	default:
+		return fmt.Sprintf("%T", err)
+	}
+}
+
+// redactClaimValues returns the given claims
+// with their values replaced by AuditRedacted.
+func redactClaimValues(claims []string) []string {
+
+	if len(claims) == 0 {
+		return nil
+	}
+
+	out := make([]string, len(claims))
+
+	for i, claim := range claims {
+		out[i] = strings.SplitN(claim, "=", 2)[0] + "=" + AuditRedacted
+	}
+
+	return out
+}
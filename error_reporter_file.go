@@ -0,0 +1,84 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// A FileErrorReporter is an ErrorReporter that writes the
+// ErrorReports as JSON lines into a file. It is mostly
+// meant to be used for testing and local development.
+type FileErrorReporter struct {
+	path string
+	file *os.File
+	lock sync.Mutex
+}
+
+// NewFileErrorReporter returns a new FileErrorReporter writing into the
+// file at the given path. The file is created if it does not exist.
+func NewFileErrorReporter(path string) (*FileErrorReporter, error) {
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileErrorReporter{
+		path: path,
+		file: f,
+	}, nil
+}
+
+// ReportError writes the given ErrorReport.
+func (r *FileErrorReporter) ReportError(report ErrorReport) {
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		zap.L().Error("Unable to encode error report", zap.Error(err))
+		return
+	}
+
+	data = append(data, '\n')
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return
+	}
+
+	if _, err := r.file.Write(data); err != nil {
+		zap.L().Error("Unable to write error report", zap.String("path", r.path), zap.Error(err))
+	}
+}
+
+// Close closes the underlying file.
+// Reports sent after Close are dropped.
+func (r *FileErrorReporter) Close() error {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+
+	return err
+}
@@ -0,0 +1,357 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+)
+
+type mockErrorReporter struct {
+	reports []ErrorReport
+	lock    sync.Mutex
+}
+
+func (r *mockErrorReporter) ReportError(report ErrorReport) {
+	r.lock.Lock()
+	r.reports = append(r.reports, report)
+	r.lock.Unlock()
+}
+
+func (r *mockErrorReporter) Reports() []ErrorReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]ErrorReport{}, r.reports...)
+}
+
+func makeErrorReporterContext() *bcontext {
+
+	req := elemental.NewRequest()
+	req.Identity = elemental.MakeIdentity("list", "lists")
+	req.Operation = elemental.OperationCreate
+
+	ctx := newContext(context.Background(), req)
+	ctx.SetClaims([]string{"@auth:realm=certificate", "@auth:token=secret"})
+
+	return ctx
+}
+
+func TestErrorReporter_report(t *testing.T) {
+
+	Convey("Given I have an error reporter", t, func() {
+
+		mr := &mockErrorReporter{}
+		r := newErrorReporter(mr, 100, 100, time.Minute)
+		ctx := makeErrorReporterContext()
+
+		Convey("When I report a panic", func() {
+
+			r.reportPanic(ctx, "boom", []byte("the stack"))
+
+			Convey("Then the report should be correct", func() {
+				reports := mr.Reports()
+				So(len(reports), ShouldEqual, 1)
+				So(reports[0].Panic, ShouldBeTrue)
+				So(reports[0].Value, ShouldEqual, "boom")
+				So(reports[0].Message, ShouldEqual, "panic: boom")
+				So(reports[0].Stack, ShouldEqual, "the stack")
+				So(reports[0].Identity, ShouldEqual, "list")
+				So(reports[0].Operation, ShouldEqual, elemental.OperationCreate)
+				So(reports[0].Claims, ShouldResemble, []string{"@auth:realm=[redacted]", "@auth:token=[redacted]"})
+				So(reports[0].TraceID, ShouldBeEmpty)
+				So(reports[0].Fingerprint, ShouldNotBeEmpty)
+				So(reports[0].Time.IsZero(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When I report an error", func() {
+
+			err := fmt.Errorf("oh no")
+			r.reportError(ctx, err, []byte("the stack"))
+
+			Convey("Then the report should be correct", func() {
+				reports := mr.Reports()
+				So(len(reports), ShouldEqual, 1)
+				So(reports[0].Panic, ShouldBeFalse)
+				So(reports[0].Value, ShouldEqual, err)
+				So(reports[0].Message, ShouldEqual, "oh no")
+				So(reports[0].Stack, ShouldEqual, "the stack")
+			})
+		})
+
+		Convey("When I report the same error twice with different messages and stacks", func() {
+
+			r.reportError(ctx, fmt.Errorf("oh no: id 1"), []byte("the stack 1"))
+			r.reportError(ctx, fmt.Errorf("oh no: id 2"), []byte("the stack 2"))
+
+			Convey("Then it should have been reported once", func() {
+				So(len(mr.Reports()), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I report the same panic twice with different values", func() {
+
+			stack := func(value string) []byte {
+				return []byte("goroutine 7 [running]:\n" +
+					"runtime/debug.Stack()\n\t/go/src/runtime/debug/stack.go:24 +0x5e\n" +
+					"go.aporeto.io/bahamut.runDispatcher.func1()\n\t/bahamut/handlers.go:157 +0x4d\n" +
+					"panic({0x8a5e20?, " + value + "})\n\t/go/src/runtime/panic.go:914 +0x21f\n" +
+					"main.handle(" + value + ")\n\t/app/main.go:12 +0x1d\n" +
+					"created by main.main in goroutine 1\n\t/app/main.go:20 +0x3e\n")
+			}
+
+			r.reportPanic(ctx, "oh no 1", stack("0xc000012345"))
+			r.reportPanic(ctx, "oh no 2", stack("0xc000067890"))
+
+			Convey("Then it should have been reported once", func() {
+				So(len(mr.Reports()), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I report different errors", func() {
+
+			r.reportError(ctx, fmt.Errorf("oh no"), nil)
+			r.reportError(ctx, elemental.NewError("Oh No", "oh no", "test", 500), nil)
+			r.reportError(ctx, elemental.NewError("Oh No Again", "oh no", "test", 500), nil)
+			r.reportPanic(ctx, "oh no", nil)
+
+			Convey("Then they should all have been reported", func() {
+				reports := mr.Reports()
+				So(len(reports), ShouldEqual, 4)
+				So(reports[0].Fingerprint, ShouldNotEqual, reports[1].Fingerprint)
+				So(reports[1].Fingerprint, ShouldNotEqual, reports[2].Fingerprint)
+				So(reports[0].Fingerprint, ShouldNotEqual, reports[3].Fingerprint)
+			})
+		})
+	})
+
+	Convey("Given I have an error reporter with a low limit", t, func() {
+
+		mr := &mockErrorReporter{}
+		r := newErrorReporter(mr, 0.001, 2, time.Minute)
+		ctx := makeErrorReporterContext()
+
+		Convey("When I report more errors than the burst", func() {
+
+			for i := 0; i < 5; i++ {
+				r.reportError(ctx, elemental.NewError(fmt.Sprintf("Error %d", i), "oh no", "test", 500), nil)
+			}
+
+			Convey("Then only the burst should have been reported", func() {
+				reports := mr.Reports()
+				So(len(reports), ShouldEqual, 2)
+				So(reports[0].Value.(elemental.Error).Title, ShouldEqual, "Error 0")
+				So(reports[1].Value.(elemental.Error).Title, ShouldEqual, "Error 1")
+			})
+		})
+	})
+}
+
+func TestErrorReporter_shouldReport(t *testing.T) {
+
+	Convey("Given I have an error reporter", t, func() {
+
+		r := newErrorReporter(&mockErrorReporter{}, 100, 100, time.Minute)
+		now := time.Now()
+
+		Convey("When I check a fingerprint within and after the dedup window", func() {
+
+			first := r.shouldReport("a", now)
+			within := r.shouldReport("a", now.Add(30*time.Second))
+			after := r.shouldReport("a", now.Add(2*time.Minute))
+
+			Convey("Then it should only be reported outside of the window", func() {
+				So(first, ShouldBeTrue)
+				So(within, ShouldBeFalse)
+				So(after, ShouldBeTrue)
+			})
+		})
+
+		Convey("When the dedup window has passed", func() {
+
+			r.shouldReport("a", now)
+			r.shouldReport("b", now.Add(90*time.Second))
+
+			Convey("Then the expired fingerprints should have been pruned", func() {
+				So(len(r.seen), ShouldEqual, 1)
+				So(r.seen, ShouldContainKey, "b")
+			})
+		})
+	})
+}
+
+func TestErrorReporter_panicLocation(t *testing.T) {
+
+	Convey("Given I have the stack of a recovered panic", t, func() {
+
+		stack := []byte("goroutine 7 [running]:\n" +
+			"runtime/debug.Stack()\n\t/go/src/runtime/debug/stack.go:24 +0x5e\n" +
+			"go.aporeto.io/bahamut.runDispatcher.func1()\n\t/bahamut/handlers.go:157 +0x4d\n" +
+			"panic({0x8a5e20?, 0xc000012345?})\n\t/go/src/runtime/panic.go:914 +0x21f\n" +
+			"main.(*handler).handle(0xc000012345, {0x0, 0x0})\n\t/app/main.go:12 +0x1d\n" +
+			"created by main.main in goroutine 1\n\t/app/main.go:20 +0x3e\n")
+
+		Convey("When I call panicLocation", func() {
+
+			location := panicLocation(stack)
+
+			Convey("Then only the stable parts of the frames from the panic should be returned", func() {
+				So(location, ShouldEqual, "main.(*handler).handle /app/main.go:12\ncreated by main.main /app/main.go:20")
+			})
+		})
+	})
+}
+
+func TestHandlers_runDispatcherWithErrorReporter(t *testing.T) {
+
+	Convey("Given I have an error reporter", t, func() {
+
+		mr := &mockErrorReporter{}
+		reporter := newErrorReporter(mr, 100, 100, time.Minute)
+
+		ctx := makeErrorReporterContext()
+		response := elemental.NewResponse(ctx.request)
+
+		Convey("When I call runDispatcher and it panics", func() {
+
+			r := runDispatcher(ctx, response, func() error { panic("booom!") }, false, nil, nil, reporter)
+
+			Convey("Then the panic should have been reported", func() {
+				So(r.StatusCode, ShouldEqual, 500)
+				reports := mr.Reports()
+				So(len(reports), ShouldEqual, 1)
+				So(reports[0].Panic, ShouldBeTrue)
+				So(reports[0].Value, ShouldEqual, "booom!")
+				So(reports[0].Stack, ShouldContainSubstring, "runDispatcher")
+			})
+		})
+
+		Convey("When I call runDispatcher and it panics with no recovery", func() {
+
+			So(func() { runDispatcher(ctx, response, func() error { panic("booom!") }, true, nil, nil, reporter) }, ShouldPanic)
+
+			Convey("Then the panic should have been reported", func() {
+				So(len(mr.Reports()), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I call runDispatcher and it returns an internal error", func() {
+
+			r := runDispatcher(ctx, response, func() error { return fmt.Errorf("oh no") }, false, nil, nil, reporter)
+
+			Convey("Then the error should have been reported", func() {
+				So(r.StatusCode, ShouldEqual, 500)
+				reports := mr.Reports()
+				So(len(reports), ShouldEqual, 1)
+				So(reports[0].Panic, ShouldBeFalse)
+				So(reports[0].Message, ShouldEqual, "oh no")
+				So(reports[0].Stack, ShouldContainSubstring, "runDispatcher")
+			})
+		})
+
+		Convey("When I call runDispatcher and the request is canceled", func() {
+
+			r := runDispatcher(ctx, response, func() error { return context.Canceled }, false, nil, nil, reporter)
+
+			Convey("Then nothing should have been reported", func() {
+				So(r, ShouldBeNil)
+				So(len(mr.Reports()), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I call runDispatcher and it returns a client error", func() {
+
+			r := runDispatcher(ctx, response, func() error { return elemental.NewError("nop", "nope", "test", 403) }, false, nil, nil, reporter)
+
+			Convey("Then nothing should have been reported", func() {
+				So(r.StatusCode, ShouldEqual, 403)
+				So(len(mr.Reports()), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestFileErrorReporter(t *testing.T) {
+
+	Convey("Given I have a file error reporter", t, func() {
+
+		dir, err := ioutil.TempDir("", "bahamut-errors")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		path := filepath.Join(dir, "errors.log")
+
+		r, err := NewFileErrorReporter(path)
+		So(err, ShouldBeNil)
+
+		Convey("When I report some errors", func() {
+
+			r.ReportError(ErrorReport{Panic: true, Value: "boom", Message: "panic: boom", Identity: "list"})
+			r.ReportError(ErrorReport{Message: "oh no", Identity: "task"})
+
+			So(r.Close(), ShouldBeNil)
+
+			r.ReportError(ErrorReport{Message: "dropped"})
+
+			Convey("Then the file should contain the reports", func() {
+
+				f, err := os.Open(path)
+				So(err, ShouldBeNil)
+				defer f.Close() // nolint: errcheck
+
+				var reports []ErrorReport
+				scanner := bufio.NewScanner(f)
+				for scanner.Scan() {
+					report := ErrorReport{}
+					So(json.Unmarshal(scanner.Bytes(), &report), ShouldBeNil)
+					reports = append(reports, report)
+				}
+
+				So(len(reports), ShouldEqual, 2)
+				So(reports[0].Panic, ShouldBeTrue)
+				So(reports[0].Message, ShouldEqual, "panic: boom")
+				So(reports[0].Value, ShouldBeNil)
+				So(reports[0].Identity, ShouldEqual, "list")
+				So(reports[1].Message, ShouldEqual, "oh no")
+			})
+		})
+
+		Convey("When I close it twice", func() {
+
+			So(r.Close(), ShouldBeNil)
+
+			Convey("Then it should not fail", func() {
+				So(r.Close(), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I create a file error reporter in a missing directory", t, func() {
+
+		_, err := NewFileErrorReporter("/not/a/dir/errors.log")
+
+		Convey("Then it should fail", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
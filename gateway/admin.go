@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// An InterceptorsState represents the paths for which
+// an interceptor is registered.
+type InterceptorsState struct {
+	Exact  []string `json:"exact"`
+	Prefix []string `json:"prefix"`
+	Suffix []string `json:"suffix"`
+}
+
+// A State represents a snapshot of the state of the gateway,
+// as exposed by the admin server.
+type State struct {
+	Maintenance   bool                `json:"maintenance"`
+	Interceptors  InterceptorsState   `json:"interceptors"`
+	SourceLimiter *SourceLimiterState `json:"sourceLimiter,omitempty"`
+	Upstreamer    interface{}         `json:"upstreamer,omitempty"`
+}
+
+func (s *gateway) state() State {
+
+	cfg := s.gatewayConfig
+
+	state := State{
+		Maintenance: cfg.maintenance,
+		Interceptors: InterceptorsState{
+			Exact:  sortedKeys(cfg.exactInterceptors),
+			Prefix: sortedKeys(cfg.prefixInterceptors),
+			Suffix: sortedKeys(cfg.suffixInterceptors),
+		},
+	}
+
+	if s.sourceLimiter != nil {
+		sls := s.sourceLimiter.state()
+		state.SourceLimiter = &sls
+	}
+
+	if r, ok := s.upstreamer.(UpstreamerStateReporter); ok {
+		state.Upstreamer = r.UpstreamerState()
+	}
+
+	return state
+}
+
+func (s *gateway) makeAdminServer(listenAddress string) *http.Server {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", func(w http.ResponseWriter, req *http.Request) {
+
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := json.MarshalIndent(s.state(), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	})
+
+	return &http.Server{
+		Addr:    listenAddress,
+		Handler: mux,
+	}
+}
+
+func (s *gateway) startAdminServer() {
+
+	if s.adminServer == nil {
+		return
+	}
+
+	go func() {
+		if err := s.adminServer.ListenAndServe(); err != nil {
+			if err == http.ErrServerClosed {
+				return
+			}
+			zap.L().Error("Unable to start admin server", zap.Error(err))
+		}
+	}()
+
+	zap.L().Info("Admin server started", zap.String("address", s.adminServer.Addr))
+}
+
+func (s *gateway) stopAdminServer() {
+
+	if s.adminServer == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	if err := s.adminServer.Shutdown(ctx); err != nil {
+		zap.L().Error("Could not gracefully stop admin server", zap.Error(err))
+	}
+}
+
+func sortedKeys(m map[string]InterceptorFunc) []string {
+
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+
+	sort.Strings(out)
+
+	return out
+}
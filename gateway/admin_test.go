@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/time/rate"
+)
+
+type stateReporterUpstreamer struct {
+	simpleUpstreamer
+}
+
+func (u *stateReporterUpstreamer) UpstreamerState() interface{} {
+	return map[string]interface{}{"peersCount": 2}
+}
+
+func TestAdminServer(t *testing.T) {
+
+	Convey("Given I have a gateway with a source limiter and a reporting upstreamer", t, func() {
+
+		cfg := newGatewayConfig()
+		OptionEnableMaintenance(true)(cfg)
+		OptionRegisterExactInterceptor("/b", nil)(cfg)
+		OptionRegisterExactInterceptor("/a", nil)(cfg)
+		OptionRegisterPrefixInterceptor("/c", nil)(cfg)
+
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+		s := &gateway{
+			gatewayConfig: cfg,
+			upstreamer:    &stateReporterUpstreamer{},
+			sourceLimiter: newSourceLimiter(next, rate.Limit(10), 20, &simpleLimiter{}, nil, nil, &errorHandler{}),
+		}
+
+		s.sourceLimiter.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/cats", nil))
+
+		Convey("When I retrieve the state", func() {
+
+			state := s.state()
+
+			Convey("Then the state should be correct", func() {
+				So(state.Maintenance, ShouldBeTrue)
+				So(state.Interceptors.Exact, ShouldResemble, []string{"/a", "/b"})
+				So(state.Interceptors.Prefix, ShouldResemble, []string{"/c"})
+				So(state.Interceptors.Suffix, ShouldResemble, []string{})
+				So(state.SourceLimiter, ShouldNotBeNil)
+				So(state.SourceLimiter.DefaultLimit, ShouldEqual, 10)
+				So(state.SourceLimiter.DefaultBurst, ShouldEqual, 20)
+				So(len(state.SourceLimiter.Buckets), ShouldEqual, 1)
+				So(state.SourceLimiter.Buckets[0].Source, ShouldEqual, "default")
+				So(state.SourceLimiter.Buckets[0].Limit, ShouldEqual, 10)
+				So(state.SourceLimiter.Buckets[0].Burst, ShouldEqual, 20)
+				So(state.Upstreamer, ShouldResemble, map[string]interface{}{"peersCount": 2})
+			})
+		})
+
+		Convey("When I call the admin server", func() {
+
+			h := s.makeAdminServer("127.0.0.1:0").Handler
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/state", nil))
+
+			Convey("Then the response should be correct", func() {
+
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+
+				out := map[string]interface{}{}
+				So(json.Unmarshal(w.Body.Bytes(), &out), ShouldBeNil)
+				So(out["maintenance"], ShouldEqual, true)
+				So(out["upstreamer"], ShouldResemble, map[string]interface{}{"peersCount": 2.0})
+			})
+		})
+
+		Convey("When I call the admin server with a POST", func() {
+
+			h := s.makeAdminServer("127.0.0.1:0").Handler
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/state", nil))
+
+			Convey("Then the response should be correct", func() {
+				So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			})
+		})
+	})
+
+	Convey("Given I have a gateway with no limiter and a simple upstreamer", t, func() {
+
+		s := &gateway{
+			gatewayConfig: newGatewayConfig(),
+			upstreamer:    &simpleUpstreamer{},
+		}
+
+		Convey("When I retrieve the state", func() {
+
+			state := s.state()
+
+			Convey("Then the state should be correct", func() {
+				So(state.SourceLimiter, ShouldBeNil)
+				So(state.Upstreamer, ShouldBeNil)
+			})
+		})
+	})
+}
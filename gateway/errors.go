@@ -56,6 +56,13 @@ var (
 		http.StatusTooManyRequests,
 	)
 
+	errUnauthorized = elemental.NewError(
+		"Unauthorized",
+		"You are not authorized to access this resource.",
+		"gateway",
+		http.StatusUnauthorized,
+	)
+
 	errConnLimit = elemental.NewError(
 		"Too Many Connections",
 		"Please retry in a moment.",
@@ -33,6 +33,10 @@ type gateway struct {
 	listener          net.Listener
 	goodbyeServer     *http.Server
 	gatewayConfig     *gwconfig
+	jwtVerifier       *jwtVerifier
+	stopJWKSPolling   context.CancelFunc
+	sourceLimiter     *sourceLimiter
+	adminServer       *http.Server
 }
 
 // New returns a new Gateway.
@@ -113,6 +117,12 @@ func New(listenAddr string, upstreamer Upstreamer, options ...Option) (Gateway,
 		s.upstreamerLatency = u
 	}
 
+	if cfg.jwtJWKSLocation != "" {
+		if s.jwtVerifier, err = newJWTVerifier(cfg); err != nil {
+			return nil, fmt.Errorf("unable to load jwks: %s", err)
+		}
+	}
+
 	s.server = &http.Server{
 		ReadTimeout:  cfg.httpReadTimeout,
 		WriteTimeout: cfg.httpWriteTimeout,
@@ -204,8 +214,33 @@ func New(listenAddr string, upstreamer Upstreamer, options ...Option) (Gateway,
 		return nil, fmt.Errorf("unable to initialize forwarder: %s", err)
 	}
 
+	var forwardHandler http.Handler = s.forwarder
+
+	if cfg.mirrorUpstream != "" {
+		forwardHandler = newMirrorHandler(
+			forwardHandler,
+			cfg.mirrorUpstream,
+			cfg.upstreamURLScheme,
+			cfg.mirrorPercentage,
+			cfg.mirrorIdentities,
+			cfg.mirrorMethods,
+			cfg.mirrorRecorder,
+			&http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:   30 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSClientConfig:     cfg.upstreamTLSConfig,
+				DisableCompression:  !cfg.upstreamEnableCompression,
+				MaxIdleConnsPerHost: cfg.upstreamMaxIdleConnsPerHost,
+				TLSHandshakeTimeout: cfg.upstreamTLSHandshakeTimeout,
+				IdleConnTimeout:     cfg.upstreamIdleConnTimeout,
+			},
+		)
+	}
+
 	if topProxyHandler, err = buffer.New(
-		s.forwarder,
+		forwardHandler,
 		buffer.MaxRequestBodyBytes(1024*1024),
 		buffer.MemRequestBodyBytes(1024*1024*1024),
 		buffer.ErrorHandler(&errorHandler{corsOriginInjector: corsOriginInjectorFunc}),
@@ -229,14 +264,16 @@ func New(listenAddr string, upstreamer Upstreamer, options ...Option) (Gateway,
 	}
 
 	if cfg.sourceRateLimitingEnabled {
-		topProxyHandler = newSourceLimiter(
+		s.sourceLimiter = newSourceLimiter(
 			topProxyHandler,
 			cfg.sourceRateLimitingRPS,
 			cfg.sourceRateLimitingBurst,
 			cfg.sourceExtractor,
 			cfg.sourceRateExtractor,
+			cfg.sourcePeersCounter,
 			&errorHandler{corsOriginInjector: corsOriginInjectorFunc},
 		)
+		topProxyHandler = s.sourceLimiter
 	}
 
 	if cfg.upstreamCircuitBreakerCond != "" {
@@ -251,12 +288,24 @@ func New(listenAddr string, upstreamer Upstreamer, options ...Option) (Gateway,
 
 	s.proxyHandler = topProxyHandler
 
+	if cfg.adminServerListenAddress != "" {
+		s.adminServer = s.makeAdminServer(cfg.adminServerListenAddress)
+	}
+
 	return s, nil
 }
 
 // Start starts the http server
 func (s *gateway) Start() {
 
+	if s.jwtVerifier != nil {
+		var ctx context.Context
+		ctx, s.stopJWKSPolling = context.WithCancel(context.Background())
+		go s.jwtVerifier.keys.Poll(ctx, s.gatewayConfig.jwtJWKSRefreshInterval)
+	}
+
+	s.startAdminServer()
+
 	go func() {
 
 		if err := s.server.Serve(s.listener); err != nil {
@@ -270,6 +319,12 @@ func (s *gateway) Start() {
 
 func (s *gateway) Stop() {
 
+	if s.stopJWKSPolling != nil {
+		s.stopJWKSPolling()
+	}
+
+	s.stopAdminServer()
+
 	stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 
 	// Stopping main server
@@ -379,6 +434,23 @@ func (s *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.jwtVerifier != nil {
+		if err := s.jwtVerifier.verifyRequest(r); err != nil {
+			injectCORSHeader(
+				w.Header(),
+				s.gatewayConfig.corsOrigin,
+				s.gatewayConfig.additionalCorsOrigin,
+				s.gatewayConfig.corsAllowCredentials,
+				r.Header.Get("Origin"),
+				r.Method,
+			)
+			// We don't tell the caller why the token has been rejected.
+			zap.L().Debug("Unable to verify jwt", zap.String("path", r.URL.Path), zap.Error(err))
+			writeError(w, r, errUnauthorized)
+			return
+		}
+	}
+
 	path := r.URL.Path
 
 	var upstream string
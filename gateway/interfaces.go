@@ -54,6 +54,13 @@ type RateExtractor interface {
 	ExtractRates(r *http.Request) (rate.Limit, int, error)
 }
 
+// A PeersCounter is used to retrieve the number of live peers
+// of the gateway, not including itself. This allows to share
+// a rate limit across several instances of the gateway.
+type PeersCounter interface {
+	PeersCount() int
+}
+
 // A LatencyBasedUpstreamer is the interface that can circle back
 // response time as an input for Upstreamer decision.
 type LatencyBasedUpstreamer interface {
@@ -61,6 +68,14 @@ type LatencyBasedUpstreamer interface {
 	Upstreamer
 }
 
+// An UpstreamerStateReporter is an Upstreamer that can report
+// a snapshot of its internal state. If the Upstreamer used by the
+// gateway implements this interface, its state will be exposed by the
+// admin server. The returned value must be encodable in JSON.
+type UpstreamerStateReporter interface {
+	UpstreamerState() interface{}
+}
+
 // A Gateway can be used as an api gateway.
 type Gateway interface {
 	Start()
@@ -0,0 +1,16 @@
+// Package limits holds the helpers shared by the gateway
+// and the upstreamers to report the rate limits.
+package limits // import "go.aporeto.io/bahamut/gateway/internal/limits"
+
+import "math"
+
+// Finite converts an infinite limit to -1
+// so it can be encoded.
+func Finite(l float64) float64 {
+
+	if math.IsInf(l, 0) {
+		return -1
+	}
+
+	return l
+}
@@ -0,0 +1,25 @@
+package limits
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFinite(t *testing.T) {
+	tests := []struct {
+		name string
+		l    float64
+		want float64
+	}{
+		{"finite", 10, 10},
+		{"zero", 0, 0},
+		{"infinite", math.Inf(1), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Finite(tt.l); got != tt.want {
+				t.Errorf("Finite() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+// Package routing holds the helpers shared by the gateway
+// and the upstreamers to route the requests.
+package routing // import "go.aporeto.io/bahamut/gateway/internal/routing"
+
+import (
+	"regexp"
+	"strings"
+)
+
+var vregexp = regexp.MustCompile(`^/v/\d+`)
+
+// TargetIdentity returns the elemental identity
+// targeted by the given path.
+func TargetIdentity(path string) string {
+
+	parts := strings.Split(
+		strings.TrimPrefix(
+			vregexp.ReplaceAllString(path, ""),
+			"/",
+		),
+		"/",
+	)
+
+	switch len(parts) {
+
+	case 1, 2:
+		return parts[0]
+	default:
+		return parts[2]
+	}
+}
@@ -0,0 +1,73 @@
+package routing
+
+import (
+	"testing"
+)
+
+func TestTargetIdentity(t *testing.T) {
+	type args struct {
+		path string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			"/",
+			args{
+				"/",
+			},
+			"",
+		},
+		{
+			"/users",
+			args{
+				"/users",
+			},
+			"users",
+		},
+		{
+			"/users/id",
+			args{
+				"/users/id",
+			},
+			"users",
+		},
+		{
+			"/users/id/groups",
+			args{
+				"/users/id/groups",
+			},
+			"groups",
+		},
+		{
+			"/v/1/users",
+			args{
+				"/v/1/users",
+			},
+			"users",
+		},
+		{
+			"/v/1/users/id",
+			args{
+				"/v/1/users/id",
+			},
+			"users",
+		},
+		{
+			"/v/1/users/id/groups",
+			args{
+				"/v/1/users/id/groups",
+			},
+			"groups",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TargetIdentity(tt.args.path); got != tt.want {
+				t.Errorf("TargetIdentity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
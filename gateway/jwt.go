@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	bjwt "go.aporeto.io/bahamut/authorizer/jwt"
+)
+
+var jwtValidMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+type jwtVerifier struct {
+	keys              *bjwt.JWKS
+	parser            *jwt.Parser
+	cookieName        string
+	issuer            string
+	audience          string
+	ignoredPrefixes   []string
+	forwardHeader     string
+	forwardSigningKey []byte
+	forwardTTL        time.Duration
+}
+
+func newJWTVerifier(cfg *gwconfig) (*jwtVerifier, error) {
+
+	keys, err := bjwt.NewJWKS(cfg.jwtJWKSLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwtVerifier{
+		keys:              keys,
+		parser:            jwt.NewParser(jwt.WithValidMethods(jwtValidMethods)),
+		cookieName:        cfg.jwtCookieName,
+		issuer:            cfg.jwtIssuer,
+		audience:          cfg.jwtAudience,
+		ignoredPrefixes:   cfg.jwtIgnoredPrefixes,
+		forwardHeader:     cfg.jwtForwardClaimsHeader,
+		forwardSigningKey: cfg.jwtForwardClaimsKey,
+		forwardTTL:        cfg.jwtForwardClaimsTTL,
+	}, nil
+}
+
+// verifyRequest verifies the token carried by the given request.
+// If the verification succeeds and claims forwarding is configured,
+// the verified claims are set in the request, in the forward header.
+func (v *jwtVerifier) verifyRequest(req *http.Request) error {
+
+	// We always remove the forward header to make sure
+	// it cannot be sent by the clients.
+	if v.forwardHeader != "" {
+		req.Header.Del(v.forwardHeader)
+	}
+
+	for _, prefix := range v.ignoredPrefixes {
+		if strings.HasPrefix(req.URL.Path, prefix) {
+			return nil
+		}
+	}
+
+	token := v.extractToken(req)
+	if token == "" {
+		return fmt.Errorf("missing token")
+	}
+
+	claims, err := v.verify(token)
+	if err != nil {
+		return err
+	}
+
+	if v.forwardHeader == "" {
+		return nil
+	}
+
+	// The forwarded claims never outlive the token.
+	exp := time.Now().Add(v.forwardTTL)
+	if tokenExp, ok := claims["exp"].(float64); ok && time.Unix(int64(tokenExp), 0).Before(exp) {
+		exp = time.Unix(int64(tokenExp), 0)
+	}
+
+	value, err := signForwardedClaims(claims, v.forwardSigningKey, exp)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(v.forwardHeader, value)
+
+	return nil
+}
+
+func (v *jwtVerifier) extractToken(req *http.Request) string {
+
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		parts := strings.SplitN(auth, " ", 2)
+		if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+
+	if v.cookieName != "" {
+		if c, err := req.Cookie(v.cookieName); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+
+	// Push sessions pass the token as a query parameter.
+	return req.URL.Query().Get("token")
+}
+
+func (v *jwtVerifier) verify(token string) (jwt.MapClaims, error) {
+
+	claims := jwt.MapClaims{}
+
+	if _, err := v.parser.ParseWithClaims(
+		token,
+		claims,
+		func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			return v.keys.Key(kid)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("invalid token: %s", err)
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("invalid token: missing expiration time")
+	}
+
+	if v.issuer != "" && !claims.VerifyIssuer(v.issuer, true) {
+		return nil, fmt.Errorf("invalid token: unexpected issuer")
+	}
+
+	if v.audience != "" && !claims.VerifyAudience(v.audience, true) {
+		return nil, fmt.Errorf("invalid token: unexpected audience")
+	}
+
+	return claims, nil
+}
+
+// signForwardedClaims returns the given claims and expiration
+// time, encoded and signed with the given key.
+func signForwardedClaims(claims map[string]interface{}, key []byte, exp time.Time) (string, error) {
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode forwarded claims: %s", err)
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data) + "." + strconv.FormatInt(exp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(payload))
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyForwardedClaims verifies the value of the header set by a Gateway
+// configured with OptionJWTValidationForwardClaims, using the same key,
+// and returns the claims of the JWT verified by the gateway.
+// Expired values are rejected.
+//
+// This can be used by the upstream services to trust the
+// claims verified by the gateway.
+func VerifyForwardedClaims(value string, key []byte) (map[string]interface{}, error) {
+
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid forwarded claims: malformed value")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid forwarded claims: unable to decode signature: %s", err)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(parts[0] + "." + parts[1]))
+
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, fmt.Errorf("invalid forwarded claims: signature mismatch")
+	}
+
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid forwarded claims: unable to decode expiration: %s", err)
+	}
+
+	if time.Now().Unix() > exp {
+		return nil, fmt.Errorf("invalid forwarded claims: expired")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid forwarded claims: unable to decode payload: %s", err)
+	}
+
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("invalid forwarded claims: unable to decode claims: %s", err)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,301 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func makeToken(kid string, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	s, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func makeJWKSData(kid string, key *rsa.PrivateKey) []byte {
+
+	data, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+
+func TestJWTVerifier(t *testing.T) {
+
+	Convey("Given I have a jwt verifier", t, func() {
+
+		key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+		dir, _ := ioutil.TempDir("", "jwks")
+		defer os.RemoveAll(dir) // nolint
+
+		path := filepath.Join(dir, "jwks.json")
+		_ = ioutil.WriteFile(path, makeJWKSData("1", key), 0600)
+
+		cfg := newGatewayConfig()
+		OptionJWTValidation(path, 0)(cfg)
+		OptionJWTValidationCookie("token")(cfg)
+		OptionJWTValidationRequirements("me", "you")(cfg)
+		OptionJWTValidationIgnoredPrefixes("/issue")(cfg)
+		OptionJWTValidationForwardClaims("X-Claims", []byte("secret"), time.Minute)(cfg)
+
+		v, err := newJWTVerifier(cfg)
+		So(err, ShouldBeNil)
+
+		validClaims := jwt.MapClaims{
+			"sub": "bob",
+			"iss": "me",
+			"aud": "you",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+
+		Convey("When I verify a request with a valid token in the Authorization header", func() {
+
+			req := httptest.NewRequest(http.MethodGet, "/cats", nil)
+			req.Header.Set("Authorization", "Bearer "+makeToken("1", key, validClaims))
+			req.Header.Set("X-Claims", "spoofed")
+
+			err := v.verifyRequest(req)
+
+			Convey("Then the claims should be forwarded", func() {
+				So(err, ShouldBeNil)
+				claims, err := VerifyForwardedClaims(req.Header.Get("X-Claims"), []byte("secret"))
+				So(err, ShouldBeNil)
+				So(claims["sub"], ShouldEqual, "bob")
+			})
+
+			Convey("Then the forwarded claims should not be verifiable with another key", func() {
+				_, err := VerifyForwardedClaims(req.Header.Get("X-Claims"), []byte("not-secret"))
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid forwarded claims: signature mismatch")
+			})
+
+			Convey("Then the forwarded claims should expire after the ttl", func() {
+				parts := strings.Split(req.Header.Get("X-Claims"), ".")
+				So(len(parts), ShouldEqual, 3)
+				exp, _ := strconv.ParseInt(parts[1], 10, 64)
+				So(exp, ShouldBeBetweenOrEqual, time.Now().Add(time.Minute-time.Second).Unix(), time.Now().Add(time.Minute).Unix())
+			})
+		})
+
+		Convey("When I verify a request with a token expiring before the ttl", func() {
+
+			tokenExp := time.Now().Add(10 * time.Second).Unix()
+
+			req := httptest.NewRequest(http.MethodGet, "/cats", nil)
+			req.Header.Set("Authorization", "Bearer "+makeToken("1", key, jwt.MapClaims{"iss": "me", "aud": "you", "exp": tokenExp}))
+
+			err := v.verifyRequest(req)
+
+			Convey("Then the forwarded claims should expire with the token", func() {
+				So(err, ShouldBeNil)
+				parts := strings.Split(req.Header.Get("X-Claims"), ".")
+				So(len(parts), ShouldEqual, 3)
+				So(parts[1], ShouldEqual, strconv.FormatInt(tokenExp, 10))
+			})
+		})
+
+		Convey("When I verify a request with a valid token in a cookie", func() {
+
+			req := httptest.NewRequest(http.MethodGet, "/cats", nil)
+			req.AddCookie(&http.Cookie{Name: "token", Value: makeToken("1", key, validClaims)})
+
+			err := v.verifyRequest(req)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When I verify a request with a valid token in the query", func() {
+
+			req := httptest.NewRequest(http.MethodGet, "/events?token="+makeToken("1", key, validClaims), nil)
+
+			err := v.verifyRequest(req)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When I verify a request on an ignored prefix", func() {
+
+			req := httptest.NewRequest(http.MethodPost, "/issue", nil)
+			req.Header.Set("X-Claims", "spoofed")
+
+			err := v.verifyRequest(req)
+
+			Convey("Then err should be nil and the forward header removed", func() {
+				So(err, ShouldBeNil)
+				So(req.Header.Get("X-Claims"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I verify a request with no token", func() {
+
+			err := v.verifyRequest(httptest.NewRequest(http.MethodGet, "/cats", nil))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "missing token")
+			})
+		})
+
+		Convey("When I verify an expired token", func() {
+
+			claims := jwt.MapClaims{"iss": "me", "aud": "you", "exp": time.Now().Add(-time.Hour).Unix()}
+			_, err := v.verify(makeToken("1", key, claims))
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When I verify a token with no expiration", func() {
+
+			claims := jwt.MapClaims{"iss": "me", "aud": "you"}
+			_, err := v.verify(makeToken("1", key, claims))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid token: missing expiration time")
+			})
+		})
+
+		Convey("When I verify a token with the wrong issuer", func() {
+
+			claims := jwt.MapClaims{"iss": "not-me", "aud": "you", "exp": time.Now().Add(time.Hour).Unix()}
+			_, err := v.verify(makeToken("1", key, claims))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid token: unexpected issuer")
+			})
+		})
+
+		Convey("When I verify a token with the wrong audience", func() {
+
+			claims := jwt.MapClaims{"iss": "me", "aud": "not-you", "exp": time.Now().Add(time.Hour).Unix()}
+			_, err := v.verify(makeToken("1", key, claims))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid token: unexpected audience")
+			})
+		})
+
+		Convey("When I verify a token signed by an unknown key", func() {
+
+			other, _ := rsa.GenerateKey(rand.Reader, 2048)
+			_, err := v.verify(makeToken("1", other, validClaims))
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When I verify a token signed with HMAC", func() {
+
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims)
+			token.Header["kid"] = "1"
+			s, _ := token.SignedString([]byte("secret"))
+
+			_, err := v.verify(s)
+
+			Convey("Then err should not be nil", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestVerifyForwardedClaims(t *testing.T) {
+
+	Convey("Given I have some signed claims", t, func() {
+
+		value, err := signForwardedClaims(map[string]interface{}{"sub": "bob"}, []byte("secret"), time.Now().Add(time.Minute))
+		So(err, ShouldBeNil)
+
+		Convey("When I verify them", func() {
+
+			claims, err := VerifyForwardedClaims(value, []byte("secret"))
+
+			Convey("Then claims should be correct", func() {
+				So(err, ShouldBeNil)
+				So(claims, ShouldResemble, map[string]interface{}{"sub": "bob"})
+			})
+		})
+
+		Convey("When I verify a malformed value", func() {
+
+			_, err := VerifyForwardedClaims("nope", []byte("secret"))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid forwarded claims: malformed value")
+			})
+		})
+
+		Convey("When I verify a value with a tampered expiration", func() {
+
+			parts := strings.Split(value, ".")
+			parts[1] = strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+			_, err := VerifyForwardedClaims(strings.Join(parts, "."), []byte("secret"))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid forwarded claims: signature mismatch")
+			})
+		})
+	})
+
+	Convey("Given I have some expired signed claims", t, func() {
+
+		value, err := signForwardedClaims(map[string]interface{}{"sub": "bob"}, []byte("secret"), time.Now().Add(-time.Second))
+		So(err, ShouldBeNil)
+
+		Convey("When I verify them", func() {
+
+			_, err := VerifyForwardedClaims(value, []byte("secret"))
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid forwarded claims: expired")
+			})
+		})
+	})
+}
@@ -3,9 +3,11 @@ package gateway
 import (
 	"errors"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/karlseguin/ccache/v2"
+	"go.aporeto.io/bahamut/gateway/internal/limits"
 	"golang.org/x/time/rate"
 )
 
@@ -18,6 +20,7 @@ type sourceLimiter struct {
 	rls             *ccache.Cache
 	sourceExtractor SourceExtractor
 	rateExtractor   RateExtractor
+	peersCounter    PeersCounter
 	errorHandler    *errorHandler
 	defaultLimit    rate.Limit
 	defaultBurst    int
@@ -29,6 +32,7 @@ func newSourceLimiter(
 	defaultBurst int,
 	sourceExtractor SourceExtractor,
 	rateExtractor RateExtractor,
+	peersCounter PeersCounter,
 	errorHandler *errorHandler,
 ) *sourceLimiter {
 
@@ -46,6 +50,7 @@ func newSourceLimiter(
 		defaultBurst:    defaultBurst,
 		sourceExtractor: sourceExtractor,
 		rateExtractor:   rateExtractor,
+		peersCounter:    peersCounter,
 		errorHandler:    errorHandler,
 		rls:             ccache.New(ccache.Configure().MaxSize(maxCacheSize)),
 	}
@@ -75,6 +80,12 @@ func (l *sourceLimiter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		burst = l.defaultBurst
 	}
 
+	// If we know about our peers, we share the limit
+	// across all of them, so a source sees one global limit.
+	if l.peersCounter != nil {
+		limit, burst = shareRates(limit, burst, l.peersCounter.PeersCount()+1) // that's us!
+	}
+
 	if item := l.rls.Get(key); item == nil || item.Value() == nil || item.Expired() {
 		rl = rate.NewLimiter(limit, burst)
 		l.rls.Set(key, rl, time.Hour)
@@ -96,3 +107,70 @@ func (l *sourceLimiter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	l.next.ServeHTTP(w, req)
 }
+
+// A SourceLimiterBucket represents the state of
+// the rate limiter of a single source.
+type SourceLimiterBucket struct {
+	Source  string    `json:"source"`
+	Limit   float64   `json:"limit"`
+	Burst   int       `json:"burst"`
+	Expires time.Time `json:"expires"`
+}
+
+// A SourceLimiterState represents the state of the source limiter.
+type SourceLimiterState struct {
+	DefaultLimit float64               `json:"defaultLimit"`
+	DefaultBurst int                   `json:"defaultBurst"`
+	Dynamic      bool                  `json:"dynamic"`
+	Buckets      []SourceLimiterBucket `json:"buckets"`
+}
+
+func (l *sourceLimiter) state() SourceLimiterState {
+
+	state := SourceLimiterState{
+		DefaultLimit: limits.Finite(float64(l.defaultLimit)),
+		DefaultBurst: l.defaultBurst,
+		Dynamic:      l.rateExtractor != nil,
+		Buckets:      []SourceLimiterBucket{},
+	}
+
+	l.rls.ForEachFunc(func(key string, item *ccache.Item) bool {
+
+		rl, ok := item.Value().(*rate.Limiter)
+		if !ok || item.Expired() {
+			return true
+		}
+
+		state.Buckets = append(state.Buckets, SourceLimiterBucket{
+			Source:  key,
+			Limit:   limits.Finite(float64(rl.Limit())),
+			Burst:   rl.Burst(),
+			Expires: item.Expires(),
+		})
+
+		return true
+	})
+
+	sort.Slice(state.Buckets, func(i, j int) bool { return state.Buckets[i].Source < state.Buckets[j].Source })
+
+	return state
+}
+
+// shareRates divides the given rates across the given number
+// of instances. The burst is never lower than 1.
+func shareRates(limit rate.Limit, burst int, instances int) (rate.Limit, int) {
+
+	if instances <= 1 {
+		return limit, burst
+	}
+
+	if limit != rate.Inf {
+		limit = limit / rate.Limit(instances)
+	}
+
+	if burst = burst / instances; burst < 1 {
+		burst = 1
+	}
+
+	return limit, burst
+}
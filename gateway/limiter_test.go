@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/time/rate"
+)
+
+type simplePeersCounter struct {
+	count int64
+}
+
+func (p *simplePeersCounter) PeersCount() int {
+	return int(atomic.LoadInt64(&p.count))
+}
+
+func Test_shareRates(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     rate.Limit
+		burst     int
+		instances int
+		wantLimit rate.Limit
+		wantBurst int
+	}{
+		{"single instance", 10, 20, 1, 10, 20},
+		{"no instance", 10, 20, 0, 10, 20},
+		{"two instances", 10, 20, 2, 5, 10},
+		{"small burst", 10, 2, 4, 2.5, 1},
+		{"infinite limit", rate.Inf, 20, 2, rate.Inf, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, burst := shareRates(tt.limit, tt.burst, tt.instances)
+			if limit != tt.wantLimit {
+				t.Errorf("shareRates() limit = %v, want %v", limit, tt.wantLimit)
+			}
+			if burst != tt.wantBurst {
+				t.Errorf("shareRates() burst = %v, want %v", burst, tt.wantBurst)
+			}
+		})
+	}
+}
+
+func TestSourceLimiter(t *testing.T) {
+
+	Convey("Given I have a source limiter with a peers counter", t, func() {
+
+		var called int
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called++ })
+
+		pc := &simplePeersCounter{}
+		l := newSourceLimiter(next, rate.Limit(10), 4, &simpleLimiter{}, nil, pc, &errorHandler{})
+
+		Convey("When there is no peer", func() {
+
+			for i := 0; i < 5; i++ {
+				l.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/cats", nil))
+			}
+
+			Convey("Then the whole burst should be available", func() {
+				So(called, ShouldEqual, 4)
+				So(l.state().Buckets[0].Limit, ShouldEqual, 10)
+				So(l.state().Buckets[0].Burst, ShouldEqual, 4)
+			})
+		})
+
+		Convey("When there is one peer", func() {
+
+			atomic.StoreInt64(&pc.count, 1)
+
+			var lastCode int
+			for i := 0; i < 3; i++ {
+				w := httptest.NewRecorder()
+				l.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cats", nil))
+				lastCode = w.Code
+			}
+
+			Convey("Then only half of the burst should be available", func() {
+				So(called, ShouldEqual, 2)
+				So(lastCode, ShouldEqual, http.StatusTooManyRequests)
+				So(l.state().Buckets[0].Limit, ShouldEqual, 5)
+				So(l.state().Buckets[0].Burst, ShouldEqual, 2)
+			})
+		})
+	})
+}
@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.aporeto.io/bahamut/gateway/internal/routing"
+	"go.uber.org/zap"
+)
+
+const (
+	mirrorMaxBodySize = 1024 * 1024
+	mirrorMaxInFlight = 256
+	mirrorTimeout     = 30 * time.Second
+)
+
+// A MirrorRecorder is a function that is called for
+// every mirrored request once the shadow upstream responded,
+// or failed to respond. The response of the shadow upstream
+// is always discarded.
+type MirrorRecorder func(method string, path string, status int, latency time.Duration, err error)
+
+type mirrorHandler struct {
+	next       http.Handler
+	client     *http.Client
+	upstream   string
+	scheme     string
+	percentage float64
+	identities map[string]struct{}
+	methods    map[string]struct{}
+	recorder   MirrorRecorder
+	sem        chan struct{}
+
+	randomizer *rand.Rand
+	lock       sync.Mutex
+}
+
+func newMirrorHandler(
+	next http.Handler,
+	upstream string,
+	scheme string,
+	percentage float64,
+	identities []string,
+	methods []string,
+	recorder MirrorRecorder,
+	transport http.RoundTripper,
+) *mirrorHandler {
+
+	h := &mirrorHandler{
+		next:       next,
+		upstream:   upstream,
+		scheme:     scheme,
+		percentage: percentage,
+		recorder:   recorder,
+		identities: make(map[string]struct{}, len(identities)),
+		methods:    make(map[string]struct{}, len(methods)),
+		sem:        make(chan struct{}, mirrorMaxInFlight),
+		randomizer: rand.New(rand.NewSource(time.Now().UnixNano())),
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   mirrorTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	for _, i := range identities {
+		h.identities[i] = struct{}{}
+	}
+
+	for _, m := range methods {
+		h.methods[strings.ToUpper(m)] = struct{}{}
+	}
+
+	return h
+}
+
+func (h *mirrorHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	if h.shouldMirror(req) {
+		h.mirror(req)
+	}
+
+	h.next.ServeHTTP(w, req)
+}
+
+func (h *mirrorHandler) shouldMirror(req *http.Request) bool {
+
+	if len(h.methods) > 0 {
+		if _, ok := h.methods[req.Method]; !ok {
+			return false
+		}
+	}
+
+	if len(h.identities) > 0 {
+		if _, ok := h.identities[routing.TargetIdentity(req.URL.Path)]; !ok {
+			return false
+		}
+	}
+
+	h.lock.Lock()
+	draw := h.randomizer.Float64() * 100
+	h.lock.Unlock()
+
+	return draw < h.percentage
+}
+
+func (h *mirrorHandler) mirror(req *http.Request) {
+
+	// We don't want to pile up mirrored requests
+	// if the shadow upstream is slow. We simply drop
+	// the ones we cannot handle.
+	select {
+	case h.sem <- struct{}{}:
+	default:
+		return
+	}
+
+	var body []byte
+
+	if req.Body != nil && req.Body != http.NoBody {
+
+		data, err := ioutil.ReadAll(io.LimitReader(req.Body, mirrorMaxBodySize+1))
+
+		// We always give back everything we read
+		// to the actual request, and the rest of the
+		// body, if any.
+		req.Body = &mirrorBody{
+			Reader: io.MultiReader(bytes.NewReader(data), req.Body),
+			Closer: req.Body,
+		}
+
+		if err != nil || len(data) > mirrorMaxBodySize {
+			<-h.sem
+			return
+		}
+
+		body = data
+	}
+
+	sreq := req.Clone(context.Background())
+	sreq.RequestURI = ""
+	sreq.URL.Scheme = h.scheme
+	sreq.URL.Host = h.upstream
+	sreq.Host = h.upstream
+	sreq.Body = http.NoBody
+	sreq.ContentLength = int64(len(body))
+	if body != nil {
+		sreq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	go func() {
+
+		defer func() { <-h.sem }()
+
+		var status int
+
+		start := time.Now()
+		resp, err := h.client.Do(sreq)
+		if err == nil {
+			status = resp.StatusCode
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		latency := time.Since(start)
+
+		if h.recorder != nil {
+			h.recorder(sreq.Method, sreq.URL.Path, status, latency, err)
+			return
+		}
+
+		zap.L().Debug("Mirrored request",
+			zap.String("method", sreq.Method),
+			zap.String("path", sreq.URL.Path),
+			zap.String("mirror", h.upstream),
+			zap.Int("status", status),
+			zap.Duration("latency", latency),
+			zap.Error(err),
+		)
+	}()
+}
+
+type mirrorBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mirrorResult struct {
+	method  string
+	path    string
+	status  int
+	latency time.Duration
+	err     error
+}
+
+func TestMirrorHandler(t *testing.T) {
+
+	Convey("Given I have a shadow upstream and a mirror handler", t, func() {
+
+		shadowBodies := make(chan string, 10)
+		shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := ioutil.ReadAll(r.Body)
+			shadowBodies <- string(data)
+			w.WriteHeader(http.StatusTeapot)
+		}))
+		defer shadow.Close()
+
+		var nextBody string
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := ioutil.ReadAll(r.Body)
+			nextBody = string(data)
+			w.WriteHeader(http.StatusOK)
+		})
+
+		results := make(chan mirrorResult, 10)
+		recorder := func(method string, path string, status int, latency time.Duration, err error) {
+			results <- mirrorResult{method, path, status, latency, err}
+		}
+
+		h := newMirrorHandler(
+			next,
+			strings.Replace(shadow.URL, "http://", "", 1),
+			"http",
+			100,
+			[]string{"cats"},
+			[]string{"post"},
+			recorder,
+			http.DefaultTransport,
+		)
+
+		Convey("When I send a request that should be mirrored", func() {
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/cats", bytes.NewBufferString(`{"name":"chris"}`))
+			h.ServeHTTP(w, req)
+
+			Convey("Then the actual request should be complete", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(nextBody, ShouldEqual, `{"name":"chris"}`)
+			})
+
+			Convey("Then the shadow upstream should have received the request", func() {
+
+				var r mirrorResult
+				select {
+				case r = <-results:
+				case <-time.After(3 * time.Second):
+					panic("no mirror result in time")
+				}
+
+				So(<-shadowBodies, ShouldEqual, `{"name":"chris"}`)
+				So(r.err, ShouldBeNil)
+				So(r.method, ShouldEqual, http.MethodPost)
+				So(r.path, ShouldEqual, "/cats")
+				So(r.status, ShouldEqual, http.StatusTeapot)
+			})
+		})
+
+		Convey("When I send a request with a method that should not be mirrored", func() {
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/cats", nil)
+			h.ServeHTTP(w, req)
+
+			Convey("Then the shadow upstream should not have received the request", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(h.shouldMirror(req), ShouldBeFalse)
+				So(len(results), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I send a request with an identity that should not be mirrored", func() {
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/dogs", bytes.NewBufferString(`{"name":"rex"}`))
+			h.ServeHTTP(w, req)
+
+			Convey("Then the shadow upstream should not have received the request", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(nextBody, ShouldEqual, `{"name":"rex"}`)
+				So(h.shouldMirror(req), ShouldBeFalse)
+				So(len(results), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When I send a request with a body that is too large", func() {
+
+			body := strings.Repeat("a", mirrorMaxBodySize+10)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/cats", bytes.NewBufferString(body))
+			h.ServeHTTP(w, req)
+
+			Convey("Then the actual request should be complete", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(len(nextBody), ShouldEqual, len(body))
+				So(len(results), ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given I have a mirror handler with a percentage of 0", t, func() {
+
+		h := newMirrorHandler(nil, "127.0.0.1:1", "http", 0, nil, nil, nil, http.DefaultTransport)
+
+		Convey("Then no request should be mirrored", func() {
+			So(h.shouldMirror(httptest.NewRequest(http.MethodGet, "/cats", nil)), ShouldBeFalse)
+		})
+	})
+}
@@ -88,6 +88,7 @@ type gwconfig struct {
 	sourceRateLimitingRPS     rate.Limit
 	sourceRateLimitingEnabled bool
 	sourceRateExtractor       RateExtractor
+	sourcePeersCounter        PeersCounter
 
 	tcpClientMaxConnectionsEnabled bool
 	tcpClientMaxConnections        int
@@ -112,6 +113,24 @@ type gwconfig struct {
 	corsAllowCredentials         bool
 	additionalCorsOrigin         map[string]struct{}
 	trustForwardHeader           bool
+
+	mirrorUpstream   string
+	mirrorPercentage float64
+	mirrorIdentities []string
+	mirrorMethods    []string
+	mirrorRecorder   MirrorRecorder
+
+	jwtJWKSLocation        string
+	jwtJWKSRefreshInterval time.Duration
+	jwtCookieName          string
+	jwtIssuer              string
+	jwtAudience            string
+	jwtIgnoredPrefixes     []string
+	jwtForwardClaimsHeader string
+	jwtForwardClaimsKey    []byte
+	jwtForwardClaimsTTL    time.Duration
+
+	adminServerListenAddress string
 }
 
 func newGatewayConfig() *gwconfig {
@@ -205,6 +224,21 @@ func OptionSourceRateLimitingDynamic(rateExtractor RateExtractor) Option {
 	}
 }
 
+// OptionSourceRateLimitingPeersCounter configures the source rate limiter
+// to share the rates across all the instances of the gateway, using the given
+// PeersCounter to know about the number of live peers. The rates, coming either from
+// OptionSourceRateLimiting or from the RateExtractor set by OptionSourceRateLimitingDynamic,
+// are then considered global and divided by the number of live instances.
+//
+// The push.Upstreamer implements PeersCounter. Note that its own ExtractRates
+// already divides the rates by the number of peers, so it should not be used
+// as a RateExtractor in combination with this option.
+func OptionSourceRateLimitingPeersCounter(peersCounter PeersCounter) Option {
+	return func(cfg *gwconfig) {
+		cfg.sourcePeersCounter = peersCounter
+	}
+}
+
 // OptionSourceRateLimitingSourceExtractor configures a custom SourceExtractor
 // to decide how to uniquely identify a client.
 // The default one uses a hash of the authorization header.
@@ -395,3 +429,117 @@ func OptionTrustForwardHeader(trust bool) Option {
 		cfg.trustForwardHeader = trust
 	}
 }
+
+// OptionTrafficMirroring configures the gateway to asynchronously
+// send a copy of the given percentage (from 0 to 100) of the requests
+// to the given shadow upstream. The responses of the shadow upstream
+// are discarded and never affect the response sent to the client.
+//
+// If identities or methods are not empty, only the requests targeting
+// one of the given identities, with one of the given methods, will be mirrored.
+//
+// Only the requests handled by the buffered proxy are mirrored. Requests forwarded
+// directly or as websockets by an interceptor, as well as requests whose body
+// is larger than 1MiB, are never mirrored. Mirrored requests do not count
+// toward rate limiting nor latency collection.
+func OptionTrafficMirroring(upstream string, percentage float64, identities []string, methods []string) Option {
+	return func(cfg *gwconfig) {
+		if percentage < 0 || percentage > 100 {
+			panic("percentage must be between 0 and 100")
+		}
+		cfg.mirrorUpstream = upstream
+		cfg.mirrorPercentage = percentage
+		cfg.mirrorIdentities = identities
+		cfg.mirrorMethods = methods
+	}
+}
+
+// OptionTrafficMirroringRecorder sets the MirrorRecorder that will be
+// called with the status and latency of every mirrored request.
+// If not set, they are logged at the debug level.
+func OptionTrafficMirroringRecorder(recorder MirrorRecorder) Option {
+	return func(cfg *gwconfig) {
+		cfg.mirrorRecorder = recorder
+	}
+}
+
+// OptionJWTValidation enables the validation of the JWT carried by
+// the incoming requests, before any upstream selection. The tokens are looked
+// up in the Authorization header (as a Bearer token), then in the cookie set by
+// OptionJWTValidationCookie if any, then in the token query parameter used by
+// push sessions. Requests with a missing, invalid or expired token are
+// rejected with a 401 Unauthorized error.
+//
+// The signature is verified using the keys from the JSON Web Key Set located
+// at jwksLocation, which can either be a path to a file or an http(s) URL. The key
+// set is reloaded every refreshInterval, and when a token signed by an unknown
+// key is received, in order to support key rotation. A refreshInterval of 0
+// disables periodic reload.
+func OptionJWTValidation(jwksLocation string, refreshInterval time.Duration) Option {
+	return func(cfg *gwconfig) {
+		cfg.jwtJWKSLocation = jwksLocation
+		cfg.jwtJWKSRefreshInterval = refreshInterval
+	}
+}
+
+// OptionJWTValidationCookie sets the name of the cookie that can
+// carry the token when the Authorization header is not set.
+func OptionJWTValidationCookie(name string) Option {
+	return func(cfg *gwconfig) {
+		cfg.jwtCookieName = name
+	}
+}
+
+// OptionJWTValidationRequirements sets the issuer and audience the
+// tokens must have. Empty values are not checked.
+func OptionJWTValidationRequirements(issuer string, audience string) Option {
+	return func(cfg *gwconfig) {
+		cfg.jwtIssuer = issuer
+		cfg.jwtAudience = audience
+	}
+}
+
+// OptionJWTValidationIgnoredPrefixes sets the path prefixes for which
+// the gateway will not validate the token, like the ones used
+// to issue tokens.
+func OptionJWTValidationIgnoredPrefixes(prefixes ...string) Option {
+	return func(cfg *gwconfig) {
+		cfg.jwtIgnoredPrefixes = append(cfg.jwtIgnoredPrefixes, prefixes...)
+	}
+}
+
+// OptionJWTValidationForwardClaims configures the gateway to forward the
+// verified claims to the upstreams in the given header, signed with
+// the given key. The upstreams can then use VerifyForwardedClaims to retrieve
+// them. The header is always removed from the incoming requests.
+//
+// The signed header expires after the given ttl, or when the token
+// expires if sooner, so a leaked header cannot be replayed forever.
+// The ttl should account for the clock skew between the gateway
+// and the upstreams.
+func OptionJWTValidationForwardClaims(header string, key []byte, ttl time.Duration) Option {
+	return func(cfg *gwconfig) {
+		if len(key) == 0 {
+			panic("key must not be empty")
+		}
+		if ttl <= 0 {
+			panic("ttl must be positive")
+		}
+		cfg.jwtForwardClaimsHeader = header
+		cfg.jwtForwardClaimsKey = key
+		cfg.jwtForwardClaimsTTL = ttl
+	}
+}
+
+// OptionAdminServer enables the read-only admin server on the given
+// listen address. It serves a JSON snapshot of the state of the gateway
+// on GET /state, including the routing state of the Upstreamer if it
+// implements UpstreamerStateReporter.
+//
+// The admin server is not authenticated, so it should only listen
+// on a local or private address.
+func OptionAdminServer(listenAddress string) Option {
+	return func(cfg *gwconfig) {
+		cfg.adminServerListenAddress = listenAddress
+	}
+}
@@ -220,4 +220,70 @@ func Test_Options(t *testing.T) {
 		OptionCORSAllowCredentials(false)(c)
 		So(c.corsAllowCredentials, ShouldBeFalse)
 	})
+
+	Convey("Calling OptionTrafficMirroring should work", t, func() {
+		c := newGatewayConfig()
+		OptionTrafficMirroring("10.0.0.1:443", 5, []string{"cats"}, []string{"POST"})(c)
+		So(c.mirrorUpstream, ShouldEqual, "10.0.0.1:443")
+		So(c.mirrorPercentage, ShouldEqual, 5)
+		So(c.mirrorIdentities, ShouldResemble, []string{"cats"})
+		So(c.mirrorMethods, ShouldResemble, []string{"POST"})
+		So(func() { OptionTrafficMirroring("10.0.0.1:443", 101, nil, nil)(c) }, ShouldPanicWith, "percentage must be between 0 and 100")
+	})
+
+	Convey("Calling OptionTrafficMirroringRecorder should work", t, func() {
+		c := newGatewayConfig()
+		f := func(string, string, int, time.Duration, error) {}
+		OptionTrafficMirroringRecorder(f)(c)
+		So(c.mirrorRecorder, ShouldEqual, f)
+	})
+
+	Convey("Calling OptionJWTValidation should work", t, func() {
+		c := newGatewayConfig()
+		OptionJWTValidation("/jwks.json", time.Minute)(c)
+		So(c.jwtJWKSLocation, ShouldEqual, "/jwks.json")
+		So(c.jwtJWKSRefreshInterval, ShouldEqual, time.Minute)
+	})
+
+	Convey("Calling OptionJWTValidationCookie should work", t, func() {
+		c := newGatewayConfig()
+		OptionJWTValidationCookie("token")(c)
+		So(c.jwtCookieName, ShouldEqual, "token")
+	})
+
+	Convey("Calling OptionJWTValidationRequirements should work", t, func() {
+		c := newGatewayConfig()
+		OptionJWTValidationRequirements("iss", "aud")(c)
+		So(c.jwtIssuer, ShouldEqual, "iss")
+		So(c.jwtAudience, ShouldEqual, "aud")
+	})
+
+	Convey("Calling OptionJWTValidationIgnoredPrefixes should work", t, func() {
+		c := newGatewayConfig()
+		OptionJWTValidationIgnoredPrefixes("/issue", "/_meta")(c)
+		So(c.jwtIgnoredPrefixes, ShouldResemble, []string{"/issue", "/_meta"})
+	})
+
+	Convey("Calling OptionJWTValidationForwardClaims should work", t, func() {
+		c := newGatewayConfig()
+		OptionJWTValidationForwardClaims("X-Claims", []byte("secret"), time.Minute)(c)
+		So(c.jwtForwardClaimsHeader, ShouldEqual, "X-Claims")
+		So(c.jwtForwardClaimsKey, ShouldResemble, []byte("secret"))
+		So(c.jwtForwardClaimsTTL, ShouldEqual, time.Minute)
+		So(func() { OptionJWTValidationForwardClaims("X-Claims", nil, time.Minute)(c) }, ShouldPanicWith, "key must not be empty")
+		So(func() { OptionJWTValidationForwardClaims("X-Claims", []byte("secret"), 0)(c) }, ShouldPanicWith, "ttl must be positive")
+	})
+
+	Convey("Calling OptionSourceRateLimitingPeersCounter should work", t, func() {
+		c := newGatewayConfig()
+		pc := &simplePeersCounter{}
+		OptionSourceRateLimitingPeersCounter(pc)(c)
+		So(c.sourcePeersCounter, ShouldEqual, pc)
+	})
+
+	Convey("Calling OptionAdminServer should work", t, func() {
+		c := newGatewayConfig()
+		OptionAdminServer("127.0.0.1:8080")(c)
+		So(c.adminServerListenAddress, ShouldEqual, "127.0.0.1:8080")
+	})
 }
@@ -18,6 +18,7 @@ type Notifier struct {
 	endpoint           string
 	serviceStatusTopic string
 	limiters           IdentityToAPILimitersRegistry
+	labels             map[string]string
 	frequency          time.Duration
 }
 
@@ -41,6 +42,7 @@ func NewNotifier(
 		endpoint:           endpoint,
 		serviceStatusTopic: serviceStatusTopic,
 		limiters:           cfg.rateLimits,
+		labels:             cfg.labels,
 		frequency:          cfg.pingInterval,
 	}
 }
@@ -63,6 +65,7 @@ func (w *Notifier) MakeStartHook(ctx context.Context) func(server bahamut.Server
 			Versions:     server.VersionsInfo(),
 			PushEndpoint: server.PushEndpoint(),
 			APILimiters:  w.limiters,
+			Labels:       w.labels,
 		}
 
 		pct, err := p.CPUPercent()
@@ -4,6 +4,7 @@ import "time"
 
 type notifierConfig struct {
 	rateLimits   IdentityToAPILimitersRegistry
+	labels       map[string]string
 	pingInterval time.Duration
 }
 
@@ -39,3 +40,16 @@ func OptionNotifierAnnounceRateLimits(rls IdentityToAPILimitersRegistry) Notifie
 		}
 	}
 }
+
+// OptionNotifierAnnounceLabels can be used to announce a set of labels
+// (like a version, or a canary marker) for the current instance of the service.
+// The gateways can then use them to route traffic according to their
+// routing rules.
+func OptionNotifierAnnounceLabels(labels map[string]string) NotifierOption {
+	return func(c *notifierConfig) {
+		c.labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			c.labels[k] = v
+		}
+	}
+}
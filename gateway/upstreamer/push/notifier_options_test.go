@@ -21,6 +21,13 @@ func Test_NotiferOptions(t *testing.T) {
 		So(c.rateLimits, ShouldNotEqual, rls)
 	})
 
+	Convey("Calling OptionNotifierAnnounceLabels should work", t, func() {
+		labels := map[string]string{"version": "2"}
+		OptionNotifierAnnounceLabels(labels)(&c)
+		So(c.labels, ShouldResemble, labels)
+		So(c.labels, ShouldNotEqual, labels)
+	})
+
 	Convey("Calling OptionNotifierPingInterval should work", t, func() {
 		OptionNotifierPingInterval(3 * time.Hour)(&c)
 		So(c.pingInterval, ShouldEqual, 3*time.Hour)
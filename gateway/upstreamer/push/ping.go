@@ -37,6 +37,7 @@ type servicePing struct {
 	Versions     map[string]interface{}
 	Load         float64
 	APILimiters  IdentityToAPILimitersRegistry
+	Labels       map[string]string
 }
 
 type peerPing struct {
@@ -0,0 +1,162 @@
+package push
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// A RoutingRule describes how a subset of the endpoints of an identity,
+// selected by the labels they announce, should receive traffic.
+//
+// A rule applies to a request if the requested identity is part
+// of Identities (or if Identities is empty) and either:
+//   - all the Headers are matching the request headers, or
+//   - the request has been drawn in the Weight percentage of the traffic.
+//
+// When a rule applies, the request will be routed to one of the
+// endpoints announcing all the labels in Selector. The endpoints
+// matched by the Selector of any applicable rule will never receive
+// traffic that has not been routed to them by a rule.
+type RoutingRule struct {
+	// Identities is the list of identities the rule applies to.
+	// If empty, the rule applies to all identities.
+	Identities []string `json:"identities,omitempty"`
+
+	// Selector is the list of labels an endpoint must announce
+	// to be selected by the rule.
+	Selector map[string]string `json:"selector"`
+
+	// Headers is a list of header values that must all be
+	// present in the request for the rule to apply.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Weight is the percentage (from 0 to 100) of the requests
+	// that will be routed to the selected endpoints.
+	Weight int `json:"weight,omitempty"`
+}
+
+func (r RoutingRule) validate() error {
+
+	if len(r.Selector) == 0 {
+		return fmt.Errorf("selector must not be empty")
+	}
+
+	if r.Weight < 0 || r.Weight > 100 {
+		return fmt.Errorf("weight must be between 0 and 100")
+	}
+
+	if r.Weight == 0 && len(r.Headers) == 0 {
+		return fmt.Errorf("either weight or headers must be set")
+	}
+
+	return nil
+}
+
+func (r RoutingRule) appliesToIdentity(identity string) bool {
+
+	if len(r.Identities) == 0 {
+		return true
+	}
+
+	for _, i := range r.Identities {
+		if i == identity {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r RoutingRule) matchesHeaders(req *http.Request) bool {
+
+	if len(r.Headers) == 0 {
+		return false
+	}
+
+	for k, v := range r.Headers {
+		if req.Header.Get(k) != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r RoutingRule) selects(epi *endpointInfo) bool {
+
+	for k, v := range r.Selector {
+		if epi.labels[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// routeEndpoints returns the endpoints from the given list that can
+// receive the given request, according to the given routing rules.
+// If the endpoints targeted by a rule are all gone, the request is routed
+// to the default endpoints and if there is no default endpoints, to all of them.
+func routeEndpoints(rules []RoutingRule, randomizer Randomizer, req *http.Request, identity string, endpoints []*endpointInfo) []*endpointInfo {
+
+	if len(rules) == 0 || len(endpoints) == 0 {
+		return endpoints
+	}
+
+	var applicable []RoutingRule
+	var selected []*endpointInfo
+
+	for _, rule := range rules {
+
+		if !rule.appliesToIdentity(identity) {
+			continue
+		}
+
+		applicable = append(applicable, rule)
+
+		if selected != nil {
+			continue
+		}
+
+		if !rule.matchesHeaders(req) && (rule.Weight == 0 || randomizer.Intn(100) >= rule.Weight) {
+			continue
+		}
+
+		selected = []*endpointInfo{}
+		for _, epi := range endpoints {
+			if rule.selects(epi) {
+				selected = append(selected, epi)
+			}
+		}
+
+		if len(selected) == 0 {
+			selected = nil
+		}
+	}
+
+	if len(applicable) == 0 {
+		return endpoints
+	}
+
+	if selected != nil {
+		return selected
+	}
+
+	defaults := make([]*endpointInfo, 0, len(endpoints))
+
+L:
+	for _, epi := range endpoints {
+		for _, rule := range applicable {
+			if rule.selects(epi) {
+				continue L
+			}
+		}
+		defaults = append(defaults, epi)
+	}
+
+	if len(defaults) == 0 {
+		return endpoints
+	}
+
+	return defaults
+}
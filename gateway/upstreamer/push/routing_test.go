@@ -0,0 +1,213 @@
+package push
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRoutingRule_validate(t *testing.T) {
+
+	Convey("Given I have a valid weighted rule", t, func() {
+		r := RoutingRule{Selector: map[string]string{"canary": "true"}, Weight: 5}
+		So(r.validate(), ShouldBeNil)
+	})
+
+	Convey("Given I have a valid header rule", t, func() {
+		r := RoutingRule{Selector: map[string]string{"canary": "true"}, Headers: map[string]string{"X-Canary": "true"}}
+		So(r.validate(), ShouldBeNil)
+	})
+
+	Convey("Given I have a rule with no selector", t, func() {
+		r := RoutingRule{Weight: 5}
+		So(r.validate(), ShouldNotBeNil)
+		So(r.validate().Error(), ShouldEqual, "selector must not be empty")
+	})
+
+	Convey("Given I have a rule with an invalid weight", t, func() {
+		r := RoutingRule{Selector: map[string]string{"canary": "true"}, Weight: 101}
+		So(r.validate(), ShouldNotBeNil)
+		So(r.validate().Error(), ShouldEqual, "weight must be between 0 and 100")
+	})
+
+	Convey("Given I have a rule with no weight nor headers", t, func() {
+		r := RoutingRule{Selector: map[string]string{"canary": "true"}}
+		So(r.validate(), ShouldNotBeNil)
+		So(r.validate().Error(), ShouldEqual, "either weight or headers must be set")
+	})
+}
+
+func TestRouteEndpoints(t *testing.T) {
+
+	Convey("Given I have some endpoints", t, func() {
+
+		stable1 := &endpointInfo{address: "1.1.1.1:1", labels: map[string]string{"version": "1"}}
+		stable2 := &endpointInfo{address: "2.2.2.2:1"}
+		canary := &endpointInfo{address: "3.3.3.3:1", labels: map[string]string{"version": "2", "canary": "true"}}
+		endpoints := []*endpointInfo{stable1, stable2, canary}
+
+		canaryRule := RoutingRule{
+			Selector: map[string]string{"canary": "true"},
+			Headers:  map[string]string{"X-Canary": "true"},
+			Weight:   5,
+		}
+
+		req := &http.Request{URL: &url.URL{Path: "/cats"}, Header: http.Header{}}
+
+		Convey("When I route with no rules", func() {
+
+			out := routeEndpoints(nil, deterministicRandom{value: 1}, req, "cats", endpoints)
+
+			Convey("Then all endpoints should be returned", func() {
+				So(out, ShouldResemble, endpoints)
+			})
+		})
+
+		Convey("When I route with a rule for another identity", func() {
+
+			rule := canaryRule
+			rule.Identities = []string{"dogs"}
+
+			out := routeEndpoints([]RoutingRule{rule}, deterministicRandom{value: 1}, req, "cats", endpoints)
+
+			Convey("Then all endpoints should be returned", func() {
+				So(out, ShouldResemble, endpoints)
+			})
+		})
+
+		Convey("When I route a request drawn in the weight", func() {
+
+			out := routeEndpoints([]RoutingRule{canaryRule}, deterministicRandom{value: 4}, req, "cats", endpoints)
+
+			Convey("Then only the canary should be returned", func() {
+				So(out, ShouldResemble, []*endpointInfo{canary})
+			})
+		})
+
+		Convey("When I route a request not drawn in the weight", func() {
+
+			out := routeEndpoints([]RoutingRule{canaryRule}, deterministicRandom{value: 5}, req, "cats", endpoints)
+
+			Convey("Then only the stable endpoints should be returned", func() {
+				So(out, ShouldResemble, []*endpointInfo{stable1, stable2})
+			})
+		})
+
+		Convey("When I route a request with the matching header", func() {
+
+			req.Header.Set("X-Canary", "true")
+			out := routeEndpoints([]RoutingRule{canaryRule}, deterministicRandom{value: 99}, req, "cats", endpoints)
+
+			Convey("Then only the canary should be returned", func() {
+				So(out, ShouldResemble, []*endpointInfo{canary})
+			})
+		})
+
+		Convey("When I route a request matching a rule with no endpoints", func() {
+
+			rule := RoutingRule{
+				Selector: map[string]string{"version": "3"},
+				Headers:  map[string]string{"X-Canary": "true"},
+			}
+
+			req.Header.Set("X-Canary", "true")
+			out := routeEndpoints([]RoutingRule{rule, canaryRule}, deterministicRandom{value: 99}, req, "cats", endpoints)
+
+			Convey("Then the next matching rule should be used", func() {
+				So(out, ShouldResemble, []*endpointInfo{canary})
+			})
+		})
+
+		Convey("When I route a request and all endpoints are selected by rules", func() {
+
+			rule := RoutingRule{
+				Selector: map[string]string{"version": "1"},
+				Weight:   1,
+			}
+
+			out := routeEndpoints([]RoutingRule{rule, canaryRule}, deterministicRandom{value: 99}, req, "cats", []*endpointInfo{stable1, canary})
+
+			Convey("Then all the endpoints should be returned", func() {
+				So(out, ShouldResemble, []*endpointInfo{stable1, canary})
+			})
+		})
+	})
+}
+
+func TestUpstreamerRoutingRules(t *testing.T) {
+
+	Convey("Given I have an upstreamer with a canary and a stable endpoint", t, func() {
+
+		u := NewUpstreamer(nil, "topic", "topic2", OptionUpstreamerRandomizer(deterministicRandom{value: 50}))
+		u.apis = map[string][]*endpointInfo{
+			"cats": {
+				{
+					address:  "1.1.1.1:1",
+					lastLoad: 0.1,
+				},
+				{
+					address:  "2.2.2.2:1",
+					lastLoad: 0.1,
+					labels:   map[string]string{"canary": "true"},
+				},
+			},
+		}
+
+		Convey("When I call upstream with no rules", func() {
+
+			So(u.RoutingRules(), ShouldBeEmpty)
+
+			upstream, err := u.Upstream(&http.Request{
+				URL:    &url.URL{Path: "/cats"},
+				Header: http.Header{"X-Canary": []string{"true"}},
+			})
+
+			Convey("Then upstream should be any endpoint", func() {
+				So(err, ShouldBeNil)
+				So(upstream, ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When I set a header based rule", func() {
+
+			err := u.SetRoutingRules(RoutingRule{
+				Selector: map[string]string{"canary": "true"},
+				Headers:  map[string]string{"X-Canary": "true"},
+			})
+
+			So(err, ShouldBeNil)
+			So(len(u.RoutingRules()), ShouldEqual, 1)
+
+			Convey("Then requests with the header should go to the canary", func() {
+				upstream, err := u.Upstream(&http.Request{
+					URL:    &url.URL{Path: "/cats"},
+					Header: http.Header{"X-Canary": []string{"true"}},
+				})
+				So(err, ShouldBeNil)
+				So(upstream, ShouldEqual, "2.2.2.2:1")
+			})
+
+			Convey("Then requests without the header should go to the stable endpoint", func() {
+				upstream, err := u.Upstream(&http.Request{
+					URL:    &url.URL{Path: "/cats"},
+					Header: http.Header{},
+				})
+				So(err, ShouldBeNil)
+				So(upstream, ShouldEqual, "1.1.1.1:1")
+			})
+		})
+
+		Convey("When I set an invalid rule", func() {
+
+			err := u.SetRoutingRules(RoutingRule{Weight: 10})
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "invalid routing rule at index 0: selector must not be empty")
+				So(u.RoutingRules(), ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -13,6 +13,7 @@ type endpointInfo struct {
 	lastSeen          time.Time
 	lastLoad          float64
 	limiters          IdentityToAPILimitersRegistry
+	labels            map[string]string
 	lastLimiterAdjust time.Time
 
 	sync.RWMutex
@@ -54,7 +55,7 @@ func (b *service) hasEndpoint(ep string) bool {
 	return ok
 }
 
-func (b *service) registerEndpoint(address string, load float64, apilimiters IdentityToAPILimitersRegistry) {
+func (b *service) registerEndpoint(address string, load float64, apilimiters IdentityToAPILimitersRegistry, labels map[string]string) {
 
 	if apilimiters == nil {
 		apilimiters = IdentityToAPILimitersRegistry{}
@@ -71,6 +72,7 @@ func (b *service) registerEndpoint(address string, load float64, apilimiters Ide
 		lastLoad: load,
 		address:  address,
 		limiters: apilimiters,
+		labels:   labels,
 	}
 }
 
@@ -31,8 +31,8 @@ func Test_Services(t *testing.T) {
 				"identity-c": {Limit: 100, Burst: 200},
 			}
 
-			srv.registerEndpoint("1.1.1.1:4443", 0.3, rls1)
-			srv.registerEndpoint("2.2.2.2:4443", 0.4, rls2)
+			srv.registerEndpoint("1.1.1.1:4443", 0.3, rls1, nil)
+			srv.registerEndpoint("2.2.2.2:4443", 0.4, rls2, map[string]string{"version": "2"})
 
 			Convey("Then they should be registered", func() {
 
@@ -62,6 +62,7 @@ func Test_Services(t *testing.T) {
 				So(eps[1].lastLoad, ShouldEqual, 0.4)
 				So(eps[1].lastSeen.Round(time.Second), ShouldEqual, time.Now().Round(time.Second))
 				So(eps[1].limiters, ShouldEqual, rls2)
+				So(eps[1].labels, ShouldResemble, map[string]string{"version": "2"})
 				So(eps[1].limiters["identity-c"].limiter, ShouldHaveSameTypeAs, &rate.Limiter{})
 				So(eps[1].limiters["identity-c"].limiter.Limit(), ShouldEqual, rate.Limit(100))
 				So(eps[1].limiters["identity-c"].limiter.Burst(), ShouldEqual, rate.Limit(200))
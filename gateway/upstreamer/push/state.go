@@ -0,0 +1,103 @@
+package push
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"go.aporeto.io/bahamut/gateway/internal/limits"
+)
+
+// An APILimiterState represents the state of a rate limiter
+// announced by an endpoint for an identity.
+type APILimiterState struct {
+	AnnouncedLimit float64 `json:"announcedLimit"`
+	AnnouncedBurst int     `json:"announcedBurst"`
+	Limit          float64 `json:"limit"`
+	Burst          int     `json:"burst"`
+}
+
+// An EndpointState represents the state of an endpoint,
+// as seen by the Upstreamer.
+type EndpointState struct {
+	Address        string                     `json:"address"`
+	LastLoad       float64                    `json:"lastLoad"`
+	LastSeen       time.Time                  `json:"lastSeen"`
+	LatencyAverage float64                    `json:"latencyAverage"`
+	Labels         map[string]string          `json:"labels,omitempty"`
+	Limiters       map[string]APILimiterState `json:"limiters,omitempty"`
+}
+
+// An UpstreamerState represents a snapshot of the
+// state of the Upstreamer.
+type UpstreamerState struct {
+	PeersCount   int64                      `json:"peersCount"`
+	APIs         map[string][]EndpointState `json:"apis"`
+	RoutingRules []RoutingRule              `json:"routingRules,omitempty"`
+}
+
+// UpstreamerState returns a snapshot of the current state of the Upstreamer.
+// It implements the gateway.UpstreamerStateReporter interface.
+func (c *Upstreamer) UpstreamerState() interface{} {
+	return c.State()
+}
+
+// State returns a snapshot of the current state of the Upstreamer.
+// The latency average is expressed in microseconds and is 0 until
+// enough samples have been collected.
+func (c *Upstreamer) State() UpstreamerState {
+
+	state := UpstreamerState{
+		PeersCount:   atomic.LoadInt64(&c.peersCount),
+		RoutingRules: c.RoutingRules(),
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	state.APIs = make(map[string][]EndpointState, len(c.apis))
+
+	for identity, endpoints := range c.apis {
+
+		eps := make([]EndpointState, len(endpoints))
+
+		for i, epi := range endpoints {
+
+			epi.RLock()
+			eps[i] = EndpointState{
+				Address:  epi.address,
+				LastLoad: epi.lastLoad,
+				LastSeen: epi.lastSeen,
+				Labels:   epi.labels,
+			}
+
+			if len(epi.limiters) > 0 {
+				eps[i].Limiters = make(map[string]APILimiterState, len(epi.limiters))
+				for name, l := range epi.limiters {
+					ls := APILimiterState{
+						AnnouncedLimit: limits.Finite(float64(l.Limit)),
+						AnnouncedBurst: l.Burst,
+					}
+					if l.limiter != nil {
+						ls.Limit = limits.Finite(float64(l.limiter.Limit()))
+						ls.Burst = l.limiter.Burst()
+					}
+					eps[i].Limiters[name] = ls
+				}
+			}
+			epi.RUnlock()
+
+			if ma, ok := c.latencies.Load(eps[i].Address); ok {
+				if v, err := ma.(movingAverage).average(); err == nil {
+					eps[i].LatencyAverage = v
+				}
+			}
+		}
+
+		sort.Slice(eps, func(i, j int) bool { return eps[i].Address < eps[j].Address })
+
+		state.APIs[identity] = eps
+	}
+
+	return state
+}
@@ -0,0 +1,80 @@
+package push
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/time/rate"
+)
+
+func TestUpstreamerState(t *testing.T) {
+
+	Convey("Given I have an upstreamer with some registered apis", t, func() {
+
+		now := time.Now()
+
+		u := NewUpstreamer(nil, "topic", "topic2")
+		u.config.latencySampleSize = 1
+		u.peersCount = 2
+		u.apis = map[string][]*endpointInfo{
+			"cats": {
+				{
+					address:  "2.2.2.2:1",
+					lastLoad: 0.2,
+					lastSeen: now,
+					labels:   map[string]string{"canary": "true"},
+				},
+				{
+					address:  "1.1.1.1:1",
+					lastLoad: 0.1,
+					lastSeen: now,
+					limiters: IdentityToAPILimitersRegistry{
+						"cats": {
+							Limit:   10,
+							Burst:   20,
+							limiter: rate.NewLimiter(5, 10),
+						},
+						"dogs": {
+							Limit: rate.Inf,
+							Burst: 20,
+						},
+					},
+				},
+			},
+		}
+
+		u.CollectLatency("1.1.1.1:1", 3*time.Microsecond)
+
+		Convey("When I retrieve the state", func() {
+
+			state := u.State()
+
+			Convey("Then the state should be correct", func() {
+
+				So(state.PeersCount, ShouldEqual, 2)
+				So(u.PeersCount(), ShouldEqual, 2)
+				So(len(state.APIs), ShouldEqual, 1)
+				So(len(state.APIs["cats"]), ShouldEqual, 2)
+
+				ep1 := state.APIs["cats"][0]
+				So(ep1.Address, ShouldEqual, "1.1.1.1:1")
+				So(ep1.LastLoad, ShouldEqual, 0.1)
+				So(ep1.LastSeen, ShouldEqual, now)
+				So(ep1.LatencyAverage, ShouldEqual, 3)
+				So(ep1.Limiters, ShouldResemble, map[string]APILimiterState{
+					"cats": {AnnouncedLimit: 10, AnnouncedBurst: 20, Limit: 5, Burst: 10},
+					"dogs": {AnnouncedLimit: -1, AnnouncedBurst: 20},
+				})
+
+				ep2 := state.APIs["cats"][1]
+				So(ep2.Address, ShouldEqual, "2.2.2.2:1")
+				So(ep2.LatencyAverage, ShouldEqual, 0)
+				So(ep2.Labels, ShouldResemble, map[string]string{"canary": "true"})
+				So(ep2.Limiters, ShouldBeNil)
+
+				So(u.UpstreamerState(), ShouldResemble, state)
+			})
+		})
+	})
+}
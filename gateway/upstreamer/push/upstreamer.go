@@ -2,6 +2,7 @@ package push
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"sync"
@@ -11,6 +12,7 @@ import (
 	"github.com/gofrs/uuid"
 	"go.aporeto.io/bahamut"
 	"go.aporeto.io/bahamut/gateway"
+	"go.aporeto.io/bahamut/gateway/internal/routing"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 )
@@ -38,6 +40,7 @@ type Upstreamer struct {
 	peersCount         int64
 	lastPeerChangeDate atomic.Value // time.Time
 	lastRateSet        atomic.Value // *rateSet
+	routingRules       atomic.Value // []RoutingRule
 }
 
 // NewUpstreamer returns a new push backed upstreamer latency based
@@ -53,13 +56,41 @@ func NewUpstreamer(
 		opt(&cfg)
 	}
 
-	return &Upstreamer{
+	u := &Upstreamer{
 		pubsub:             pubsub,
 		apis:               map[string][]*endpointInfo{},
 		serviceStatusTopic: serviceStatusTopic,
 		peerStatusTopic:    peerStatusTopic,
 		config:             cfg,
 	}
+
+	u.routingRules.Store(cfg.routingRules)
+
+	return u
+}
+
+// SetRoutingRules replaces the current routing rules by the given ones.
+// It is safe to call this function while the Upstreamer is running.
+// Passing no rule disables label based routing.
+func (c *Upstreamer) SetRoutingRules(rules ...RoutingRule) error {
+
+	for i, r := range rules {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("invalid routing rule at index %d: %w", i, err)
+		}
+	}
+
+	c.routingRules.Store(append([]RoutingRule{}, rules...))
+
+	return nil
+}
+
+// RoutingRules returns the current routing rules.
+func (c *Upstreamer) RoutingRules() []RoutingRule {
+
+	rules, _ := c.routingRules.Load().([]RoutingRule)
+
+	return append([]RoutingRule{}, rules...)
 }
 
 // ExtractRates implements the gateway.Limiter interface.
@@ -84,12 +115,15 @@ func (c *Upstreamer) ExtractRates(r *http.Request) (rate.Limit, int, error) {
 // Upstream returns the upstream to go for the given path
 func (c *Upstreamer) Upstream(req *http.Request) (string, error) {
 
-	identity := getTargetIdentity(req.URL.Path)
+	identity := routing.TargetIdentity(req.URL.Path)
 
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
-	l := len(c.apis[identity])
+	rules, _ := c.routingRules.Load().([]RoutingRule)
+	endpoints := routeEndpoints(rules, c.config.randomizer, req, identity, c.apis[identity])
+
+	l := len(endpoints)
 
 	var n1, n2 int
 
@@ -99,7 +133,7 @@ func (c *Upstreamer) Upstream(req *http.Request) (string, error) {
 		return "", nil
 
 	case 1:
-		ep := c.apis[identity][0]
+		ep := endpoints[0]
 		ep.RLock()
 		defer ep.RUnlock()
 
@@ -112,8 +146,8 @@ func (c *Upstreamer) Upstream(req *http.Request) (string, error) {
 		n1, n2 = pick(c.config.randomizer, l)
 	}
 
-	epi1 := c.apis[identity][n1]
-	epi2 := c.apis[identity][n2]
+	epi1 := endpoints[n1]
+	epi2 := endpoints[n2]
 
 	addresses := [2]string{}
 	loads := [2]float64{}
@@ -535,6 +569,12 @@ func (c *Upstreamer) listenPeers(ctx context.Context) {
 	}
 }
 
+// PeersCount returns the number of live peers of the Upstreamer,
+// not including itself. It implements the gateway.PeersCounter interface.
+func (c *Upstreamer) PeersCount() int {
+	return int(atomic.LoadInt64(&c.peersCount))
+}
+
 // CollectLatency implement the LatencyBasedUpstreamer interface to add new
 // samples into the latencies sync map
 func (c *Upstreamer) CollectLatency(address string, responseTime time.Duration) {
@@ -1,6 +1,7 @@
 package push
 
 import (
+	"fmt"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -25,6 +26,7 @@ type upstreamConfig struct {
 	tokenLimitingBurst          int
 	tokenLimitingRPS            rate.Limit
 	globalServiceTopic          string
+	routingRules                []RoutingRule
 }
 
 func newUpstreamConfig() upstreamConfig {
@@ -148,3 +150,18 @@ func OptionUpstreamerGlobalServiceTopic(topic string) UpstreamerOption {
 		cfg.globalServiceTopic = topic
 	}
 }
+
+// OptionUpstreamerRoutingRules sets the initial routing rules used to route
+// the requests to endpoints based on the labels they announce.
+// The rules can be updated at runtime using Upstreamer.SetRoutingRules.
+// This option panics if any of the rules is invalid.
+func OptionUpstreamerRoutingRules(rules ...RoutingRule) UpstreamerOption {
+	return func(cfg *upstreamConfig) {
+		for i, r := range rules {
+			if err := r.validate(); err != nil {
+				panic(fmt.Sprintf("invalid routing rule at index %d: %s", i, err))
+			}
+		}
+		cfg.routingRules = append([]RoutingRule{}, rules...)
+	}
+}
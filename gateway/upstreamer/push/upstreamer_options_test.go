@@ -75,4 +75,16 @@ func Test_Options(t *testing.T) {
 		OptionUpstreamerGlobalServiceTopic("global")(&c)
 		So(c.globalServiceTopic, ShouldEqual, "global")
 	})
+
+	Convey("Calling OptionUpstreamerRoutingRules should work", t, func() {
+		rule := RoutingRule{Selector: map[string]string{"canary": "true"}, Weight: 5}
+		OptionUpstreamerRoutingRules(rule)(&c)
+		So(c.routingRules, ShouldResemble, []RoutingRule{rule})
+
+		So(
+			func() { OptionUpstreamerRoutingRules(RoutingRule{Weight: 5})(&c) },
+			ShouldPanicWith,
+			`invalid routing rule at index 0: selector must not be empty`,
+		)
+	})
 }
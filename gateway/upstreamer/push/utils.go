@@ -1,33 +1,5 @@
 package push
 
-import (
-	"regexp"
-	"strings"
-)
-
-var vregexp = regexp.MustCompile(`^/v/\d+`)
-
-func getTargetIdentity(path string) string {
-
-	parts := strings.Split(
-		strings.TrimPrefix(
-			vregexp.ReplaceAllString(path, ""),
-			"/",
-		),
-		"/",
-	)
-
-	switch len(parts) {
-
-	case 1:
-		return parts[0]
-	case 2:
-		return parts[0]
-	default:
-		return parts[2]
-	}
-}
-
 func pick(randomizer Randomizer, len int) (int, int) {
 
 	if len < 2 {
@@ -70,7 +42,7 @@ func handleAddServicePing(services servicesConfig, sp servicePing) bool {
 	srv.versions = sp.Versions
 
 	// We register the new endpoint.
-	srv.registerEndpoint(sp.Endpoint, sp.Load, sp.APILimiters)
+	srv.registerEndpoint(sp.Endpoint, sp.Load, sp.APILimiters, sp.Labels)
 
 	return true
 }
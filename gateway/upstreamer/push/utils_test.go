@@ -12,74 +12,6 @@ import (
 	"go.aporeto.io/bahamut"
 )
 
-func Test_getTargetIdentity(t *testing.T) {
-	type args struct {
-		path string
-	}
-	tests := []struct {
-		name string
-		args args
-		want string
-	}{
-		{
-			"/",
-			args{
-				"/",
-			},
-			"",
-		},
-		{
-			"/users",
-			args{
-				"/users",
-			},
-			"users",
-		},
-		{
-			"/users/id",
-			args{
-				"/users/id",
-			},
-			"users",
-		},
-		{
-			"/users/id/groups",
-			args{
-				"/users/id/groups",
-			},
-			"groups",
-		},
-		{
-			"/v/1/users",
-			args{
-				"/v/1/users",
-			},
-			"users",
-		},
-		{
-			"/v/1/users/id",
-			args{
-				"/v/1/users/id",
-			},
-			"users",
-		},
-		{
-			"/v/1/users/id/groups",
-			args{
-				"/v/1/users/id/groups",
-			},
-			"groups",
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := getTargetIdentity(tt.args.path); got != tt.want {
-				t.Errorf("getTargetIdentity() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func TestHandleServicePings(t *testing.T) {
 
 	// TODO: CHECK ROUTES AND VERSIONS
@@ -16,10 +16,12 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/log"
 	"go.aporeto.io/elemental"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -38,6 +40,9 @@ func makeResponse(ctx *bcontext, response *elemental.Response, marshallers map[e
 			span.LogFields(fields...)
 			span.SetTag("status.code", response.StatusCode)
 		}
+		if span := otelSpanFromContext(ctx.ctx); span != nil {
+			span.SetAttributes(attribute.Int("status.code", response.StatusCode))
+		}
 	}()
 
 	response.StatusCode = ctx.statusCode
@@ -143,17 +148,35 @@ func makeErrorResponse(ctx context.Context, response *elemental.Response, err er
 	return response
 }
 
-func runDispatcher(ctx *bcontext, r *elemental.Response, d func() error, disablePanicRecovery bool, marshallers map[elemental.Identity]CustomMarshaller, errorTransformer func(error) error) (out *elemental.Response) {
+func runDispatcher(ctx *bcontext, r *elemental.Response, d func() error, disablePanicRecovery bool, marshallers map[elemental.Identity]CustomMarshaller, errorTransformer func(error) error, errorReporter *errorReporter) (out *elemental.Response) {
 
 	defer func() {
-		if err := handleRecoveredPanic(ctx.ctx, recover(), disablePanicRecovery); err != nil {
+
+		rec := recover()
+		if rec != nil && errorReporter != nil {
+			errorReporter.reportPanic(ctx, rec, debug.Stack())
+		}
+
+		if err := handleRecoveredPanic(ctx.ctx, rec, disablePanicRecovery); err != nil {
 			// out is the named returned value. This switches the output of the function.
 			out = makeErrorResponse(ctx.ctx, r, err, marshallers, errorTransformer)
 		}
 	}()
 
-	if err := d(); err != nil {
-		return makeErrorResponse(ctx.ctx, r, err, marshallers, errorTransformer)
+	err := d()
+
+	defer ctx.addTiming(&ctx.timings.encoding, time.Now())
+
+	if err != nil {
+
+		out = makeErrorResponse(ctx.ctx, r, err, marshallers, errorTransformer)
+
+		// out is nil when the request has been canceled.
+		if errorReporter != nil && out != nil && out.StatusCode == http.StatusInternalServerError {
+			errorReporter.reportError(ctx, err, debug.Stack())
+		}
+
+		return out
 	}
 
 	return makeResponse(ctx, r, marshallers)
@@ -199,6 +222,7 @@ func handleRetrieveMany(ctx *bcontext, cfg config, processorFinder processorFind
 		cfg.general.panicRecoveryDisabled,
 		cfg.model.marshallers,
 		cfg.hooks.errorTransformer,
+		cfg.hooks.errorReporter,
 	)
 }
 
@@ -241,6 +265,7 @@ func handleRetrieve(ctx *bcontext, cfg config, processorFinder processorFinderFu
 		cfg.general.panicRecoveryDisabled,
 		cfg.model.marshallers,
 		cfg.hooks.errorTransformer,
+		cfg.hooks.errorReporter,
 	)
 }
 
@@ -287,6 +312,7 @@ func handleCreate(ctx *bcontext, cfg config, processorFinder processorFinderFunc
 		cfg.general.panicRecoveryDisabled,
 		cfg.model.marshallers,
 		cfg.hooks.errorTransformer,
+		cfg.hooks.errorReporter,
 	)
 }
 
@@ -333,6 +359,7 @@ func handleUpdate(ctx *bcontext, cfg config, processorFinder processorFinderFunc
 		cfg.general.panicRecoveryDisabled,
 		cfg.model.marshallers,
 		cfg.hooks.errorTransformer,
+		cfg.hooks.errorReporter,
 	)
 }
 
@@ -377,6 +404,7 @@ func handleDelete(ctx *bcontext, cfg config, processorFinder processorFinderFunc
 		cfg.general.panicRecoveryDisabled,
 		cfg.model.marshallers,
 		cfg.hooks.errorTransformer,
+		cfg.hooks.errorReporter,
 	)
 }
 
@@ -419,6 +447,7 @@ func handleInfo(ctx *bcontext, cfg config, processorFinder processorFinderFunc,
 		cfg.general.panicRecoveryDisabled,
 		cfg.model.marshallers,
 		cfg.hooks.errorTransformer,
+		cfg.hooks.errorReporter,
 	)
 }
 
@@ -466,5 +495,6 @@ func handlePatch(ctx *bcontext, cfg config, processorFinder processorFinderFunc,
 		cfg.general.panicRecoveryDisabled,
 		cfg.model.marshallers,
 		cfg.hooks.errorTransformer,
+		cfg.hooks.errorReporter,
 	)
 }
@@ -501,7 +501,7 @@ func TestHandlers_runDispatcher(t *testing.T) {
 			return nil
 		}
 
-		r := runDispatcher(ctx, response, d, true, nil, nil)
+		r := runDispatcher(ctx, response, d, true, nil, nil, nil)
 
 		Convey("Then the code should be 204", func() {
 			So(r.StatusCode, ShouldEqual, 204)
@@ -530,7 +530,7 @@ func TestHandlers_runDispatcher(t *testing.T) {
 			return elemental.NewError("nop", "nope", "test", 42)
 		}
 
-		r := runDispatcher(ctx, response, d, true, nil, nil)
+		r := runDispatcher(ctx, response, d, true, nil, nil, nil)
 
 		Convey("Then the code should be 42", func() {
 			So(r.StatusCode, ShouldEqual, 42)
@@ -559,7 +559,7 @@ func TestHandlers_runDispatcher(t *testing.T) {
 			panic("booom!")
 		}
 
-		r := runDispatcher(ctx, response, d, false, nil, nil)
+		r := runDispatcher(ctx, response, d, false, nil, nil, nil)
 
 		Convey("Then the code should be 500", func() {
 			So(r.StatusCode, ShouldEqual, 500)
@@ -589,7 +589,7 @@ func TestHandlers_runDispatcher(t *testing.T) {
 		}
 
 		Convey("Then the code should be 500", func() {
-			So(func() { runDispatcher(ctx, response, d, true, nil, nil) }, ShouldPanic)
+			So(func() { runDispatcher(ctx, response, d, true, nil, nil, nil) }, ShouldPanic)
 		})
 
 	})
@@ -612,7 +612,7 @@ func TestHandlers_runDispatcher(t *testing.T) {
 		}
 
 		r := elemental.NewResponse(elemental.NewRequest())
-		go func() { runDispatcher(ctx, r, d, true, nil, nil) }()
+		go func() { runDispatcher(ctx, r, d, true, nil, nil, nil) }()
 		time.Sleep(30 * time.Millisecond)
 		cancel()
 
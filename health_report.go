@@ -0,0 +1,213 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PingStatusUnknown represents the status of a Pinger
+// that has not been checked yet.
+const PingStatusUnknown = "unknown"
+
+// A HealthCheckResult holds the cached result
+// of the last check of a Pinger.
+type HealthCheckResult struct {
+	Status      string     `json:"status"`
+	Latency     string     `json:"latency,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastCheck   *time.Time `json:"lastCheck,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	NonCritical bool       `json:"nonCritical,omitempty"`
+}
+
+// A HealthReport is the report served by the health
+// server when the pingers have been configured using
+// OptHealthServerPingers.
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Ready  bool                         `json:"ready"`
+	Checks map[string]HealthCheckResult `json:"checks"`
+}
+
+// a healthChecker periodically checks the configured
+// pingers and caches their results.
+type healthChecker struct {
+	pingers  map[string]Pinger
+	interval time.Duration
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+
+	results  map[string]HealthCheckResult
+	inflight map[string]*int32
+	lock     sync.RWMutex
+}
+
+// newHealthChecker returns a new healthChecker.
+func newHealthChecker(pingers map[string]Pinger, interval time.Duration, timeout time.Duration, timeouts map[string]time.Duration) *healthChecker {
+
+	hc := &healthChecker{
+		pingers:  pingers,
+		interval: interval,
+		timeout:  timeout,
+		timeouts: timeouts,
+		results:  make(map[string]HealthCheckResult, len(pingers)),
+		inflight: make(map[string]*int32, len(pingers)),
+	}
+
+	for name := range pingers {
+		hc.results[name] = HealthCheckResult{
+			Status:      PingStatusUnknown,
+			NonCritical: !isCritical(pingers[name]),
+		}
+		hc.inflight[name] = new(int32)
+	}
+
+	return hc
+}
+
+// run checks the pingers every interval until
+// the given context is canceled.
+func (c *healthChecker) run(ctx context.Context) {
+
+	c.checkAll()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.checkAll()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkAll concurrently checks all the pingers and
+// waits for their results.
+func (c *healthChecker) checkAll() {
+
+	var wg sync.WaitGroup
+	wg.Add(len(c.pingers))
+
+	for name, pinger := range c.pingers {
+		go func(name string, pinger Pinger) {
+			defer wg.Done()
+			c.check(name, pinger)
+		}(name, pinger)
+	}
+
+	wg.Wait()
+}
+
+// check checks the given pinger and records the result.
+// A pinger that does not return within its timeout is
+// considered timed out, and is not pinged again until
+// its pending ping returns.
+func (c *healthChecker) check(name string, pinger Pinger) {
+
+	timeout := c.timeout
+	if t, ok := c.timeouts[name]; ok {
+		timeout = t
+	}
+
+	var err error
+	start := time.Now()
+
+	inflight := c.inflight[name]
+	if !atomic.CompareAndSwapInt32(inflight, 0, 1) {
+		err = errors.New(PingStatusTimeout)
+	} else {
+
+		errCh := make(chan error, 1)
+		go func() {
+			defer atomic.StoreInt32(inflight, 0)
+			errCh <- pinger.Ping(timeout)
+		}()
+
+		select {
+		case err = <-errCh:
+		case <-time.After(timeout):
+			err = errors.New(PingStatusTimeout)
+		}
+	}
+
+	now := time.Now()
+	latency := now.Sub(start)
+	status := stringifyStatus(err)
+
+	c.lock.Lock()
+	result := c.results[name]
+	result.Status = status
+	result.Latency = latency.String()
+	result.LastCheck = &now
+	if err != nil {
+		result.LastError = err.Error()
+	} else {
+		result.LastSuccess = &now
+	}
+	c.results[name] = result
+	c.lock.Unlock()
+
+	if err != nil {
+		zap.L().Warn("Ping failed",
+			zap.String("service", name),
+			zap.String("status", status),
+			zap.Duration("duration", latency),
+			zap.Error(err),
+		)
+	}
+}
+
+// report returns the current HealthReport. Failing non
+// critical pingers only degrade the report, while failing
+// critical ones make it not ready.
+func (c *healthChecker) report(ready bool) HealthReport {
+
+	r := HealthReport{
+		Status: PingStatusOK,
+		Ready:  ready,
+		Checks: make(map[string]HealthCheckResult, len(c.pingers)),
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for name, result := range c.results {
+
+		r.Checks[name] = result
+
+		if result.Status == PingStatusOK {
+			continue
+		}
+
+		if result.NonCritical {
+			if r.Status == PingStatusOK {
+				r.Status = PingStatusDegraded
+			}
+			continue
+		}
+
+		r.Status = PingStatusError
+		r.Ready = false
+	}
+
+	return r
+}
@@ -0,0 +1,209 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type blockingPinger struct {
+	unblock chan struct{}
+}
+
+func (p blockingPinger) Ping(timeout time.Duration) error {
+	<-p.unblock
+	return nil
+}
+
+func TestHealthChecker(t *testing.T) {
+
+	Convey("Given I have a health checker", t, func() {
+
+		hc := newHealthChecker(
+			map[string]Pinger{
+				"ok":  MockPinger{},
+				"err": MockPinger{PingStatus: fmt.Errorf("boom")},
+			},
+			time.Second,
+			time.Second,
+			nil,
+		)
+
+		Convey("When I get the report before any check", func() {
+
+			r := hc.report(true)
+
+			Convey("Then all checks should be unknown", func() {
+				So(r.Status, ShouldEqual, PingStatusError)
+				So(r.Ready, ShouldBeFalse)
+				So(r.Checks["ok"].Status, ShouldEqual, PingStatusUnknown)
+				So(r.Checks["err"].Status, ShouldEqual, PingStatusUnknown)
+			})
+		})
+
+		Convey("When I check all pingers", func() {
+
+			hc.checkAll()
+			r := hc.report(true)
+
+			Convey("Then the report should be correct", func() {
+				So(r.Status, ShouldEqual, PingStatusError)
+				So(r.Ready, ShouldBeFalse)
+
+				So(r.Checks["ok"].Status, ShouldEqual, PingStatusOK)
+				So(r.Checks["ok"].LastError, ShouldBeEmpty)
+				So(r.Checks["ok"].LastCheck, ShouldNotBeNil)
+				So(r.Checks["ok"].LastSuccess, ShouldNotBeNil)
+				So(r.Checks["ok"].Latency, ShouldNotBeEmpty)
+
+				So(r.Checks["err"].Status, ShouldEqual, PingStatusError)
+				So(r.Checks["err"].LastError, ShouldEqual, "boom")
+				So(r.Checks["err"].LastCheck, ShouldNotBeNil)
+				So(r.Checks["err"].LastSuccess, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have a health checker with only healthy pingers", t, func() {
+
+		hc := newHealthChecker(
+			map[string]Pinger{
+				"p1": MockPinger{},
+				"p2": MockPinger{},
+			},
+			time.Second,
+			time.Second,
+			nil,
+		)
+
+		hc.checkAll()
+
+		Convey("When I get the report while ready", func() {
+
+			r := hc.report(true)
+
+			Convey("Then the report should be correct", func() {
+				So(r.Status, ShouldEqual, PingStatusOK)
+				So(r.Ready, ShouldBeTrue)
+				So(len(r.Checks), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I get the report while not ready", func() {
+
+			r := hc.report(false)
+
+			Convey("Then the report should be correct", func() {
+				So(r.Status, ShouldEqual, PingStatusOK)
+				So(r.Ready, ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given I have a health checker with a failing non critical pinger", t, func() {
+
+		hc := newHealthChecker(
+			map[string]Pinger{
+				"p1": MockPinger{},
+				"p2": NewNonCriticalPinger(MockPinger{PingStatus: fmt.Errorf("boom")}),
+			},
+			time.Second,
+			time.Second,
+			nil,
+		)
+
+		hc.checkAll()
+
+		Convey("When I get the report", func() {
+
+			r := hc.report(true)
+
+			Convey("Then the report should be degraded but ready", func() {
+				So(r.Status, ShouldEqual, PingStatusDegraded)
+				So(r.Ready, ShouldBeTrue)
+				So(r.Checks["p1"].NonCritical, ShouldBeFalse)
+				So(r.Checks["p2"].NonCritical, ShouldBeTrue)
+				So(r.Checks["p2"].Status, ShouldEqual, PingStatusError)
+			})
+		})
+	})
+
+	Convey("Given I have a health checker with a pinger that never returns", t, func() {
+
+		p := blockingPinger{unblock: make(chan struct{})}
+		defer close(p.unblock)
+
+		hc := newHealthChecker(
+			map[string]Pinger{"slow": p},
+			time.Second,
+			time.Second,
+			map[string]time.Duration{"slow": 10 * time.Millisecond},
+		)
+
+		Convey("When I check it", func() {
+
+			start := time.Now()
+			hc.checkAll()
+			elapsed := time.Since(start)
+
+			Convey("Then it should time out using the pinger timeout", func() {
+				So(elapsed, ShouldBeLessThan, time.Second)
+				So(hc.report(true).Checks["slow"].Status, ShouldEqual, PingStatusTimeout)
+				So(hc.report(true).Checks["slow"].LastError, ShouldEqual, PingStatusTimeout)
+			})
+
+			Convey("When I check it again while the previous ping is pending", func() {
+
+				start := time.Now()
+				hc.checkAll()
+				elapsed := time.Since(start)
+
+				Convey("Then it should time out immediately", func() {
+					So(elapsed, ShouldBeLessThan, 10*time.Millisecond)
+					So(hc.report(true).Checks["slow"].Status, ShouldEqual, PingStatusTimeout)
+				})
+			})
+		})
+	})
+
+	Convey("Given I have a health checker running", t, func() {
+
+		hc := newHealthChecker(
+			map[string]Pinger{"p1": MockPinger{}},
+			10*time.Millisecond,
+			time.Second,
+			nil,
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go hc.run(ctx)
+
+		Convey("Then the pingers should eventually be checked", func() {
+			So(func() bool {
+				for i := 0; i < 100; i++ {
+					if hc.report(true).Status == PingStatusOK {
+						return true
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+				return false
+			}(), ShouldBeTrue)
+		})
+	})
+}
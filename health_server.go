@@ -15,6 +15,7 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -22,8 +23,10 @@ import (
 
 // an healthServer is the structure serving the health check endpoint.
 type healthServer struct {
-	cfg    config
-	server *http.Server
+	cfg     config
+	server  *http.Server
+	checker *healthChecker
+	ready   int32
 }
 
 // newHealthServer returns a new healthServer.
@@ -36,9 +39,35 @@ func newHealthServer(cfg config) *healthServer {
 
 	s.server.Handler = s
 
+	if cfg.healthServer.pingers != nil {
+		s.checker = newHealthChecker(
+			cfg.healthServer.pingers,
+			cfg.healthServer.pingInterval,
+			cfg.healthServer.pingTimeout,
+			cfg.healthServer.pingTimeouts,
+		)
+	}
+
 	return s
 }
 
+// setReady sets the readiness of the server.
+func (s *healthServer) setReady(ready bool) {
+
+	var v int32
+	if ready {
+		v = 1
+	}
+
+	atomic.StoreInt32(&s.ready, v)
+}
+
+// isReady returns the readiness of the server.
+func (s *healthServer) isReady() bool {
+
+	return atomic.LoadInt32(&s.ready) == 1
+}
+
 func (s *healthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodGet {
@@ -50,6 +79,16 @@ func (s *healthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	case "/":
 
+		if s.checker != nil {
+			report := s.checker.report(s.isReady())
+			code := http.StatusOK
+			if report.Status == PingStatusError {
+				code = http.StatusServiceUnavailable
+			}
+			writeJSON(w, code, report)
+			return
+		}
+
 		if s.cfg.healthServer.healthHandler == nil {
 			w.WriteHeader(http.StatusNoContent)
 			return
@@ -62,6 +101,39 @@ func (s *healthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		w.WriteHeader(http.StatusNoContent)
 
+	case "/live":
+
+		if s.checker == nil {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		report := HealthReport{Status: PingStatusOK, Ready: s.isReady()}
+		code := http.StatusOK
+		if h := s.cfg.healthServer.healthHandler; h != nil {
+			if err := h(); err != nil {
+				report.Status = PingStatusError
+				code = http.StatusServiceUnavailable
+			}
+		}
+
+		writeJSON(w, code, report)
+
+	case "/ready":
+
+		if s.checker == nil {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		report := s.checker.report(s.isReady())
+		code := http.StatusOK
+		if !report.Ready {
+			code = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, code, report)
+
 	case "/metrics":
 		if s.cfg.healthServer.metricsManager == nil {
 			w.WriteHeader(http.StatusNotImplemented)
@@ -91,6 +163,10 @@ func (s *healthServer) start(ctx context.Context) {
 
 	zap.L().Debug("Health server enabled", zap.String("listen", s.cfg.healthServer.listenAddress))
 
+	if s.checker != nil {
+		go s.checker.run(ctx)
+	}
+
 	go func() {
 		if err := s.server.ListenAndServe(); err != nil {
 			if err == http.ErrServerClosed {
@@ -13,6 +13,7 @@ package bahamut
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
@@ -241,3 +242,155 @@ func TestHealthServerWithCustomHandler(t *testing.T) {
 		})
 	})
 }
+
+func TestHealthServerWithPingers(t *testing.T) {
+
+	Convey("Given I have a health server with pingers", t, func() {
+
+		port := freePort()
+		cfg := config{}
+		cfg.healthServer.listenAddress = fmt.Sprintf("127.0.0.1:%d", port)
+		cfg.healthServer.pingInterval = time.Second
+		cfg.healthServer.pingTimeout = time.Second
+		cfg.healthServer.pingers = map[string]Pinger{
+			"p1": MockPinger{},
+		}
+
+		hs := newHealthServer(cfg)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		go hs.start(ctx)
+		defer hs.stop()
+
+		time.Sleep(1 * time.Second)
+
+		Convey("When I get /live", func() {
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/live", port))
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then code should be 200", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When I get /ready before the server is ready", func() {
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/ready", port))
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then code should be 503", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			})
+		})
+
+		Convey("When I get /ready after the server is ready", func() {
+
+			hs.setReady(true)
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/ready", port))
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then code should be 200", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			})
+
+			Convey("Then the report should be correct", func() {
+				report := HealthReport{}
+				So(json.NewDecoder(resp.Body).Decode(&report), ShouldBeNil)
+				So(report.Status, ShouldEqual, PingStatusOK)
+				So(report.Ready, ShouldBeTrue)
+				So(report.Checks["p1"].Status, ShouldEqual, PingStatusOK)
+			})
+		})
+
+		Convey("When I get /", func() {
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d", port))
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then code should be 200", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			})
+
+			Convey("Then the report should be correct", func() {
+				report := HealthReport{}
+				So(json.NewDecoder(resp.Body).Decode(&report), ShouldBeNil)
+				So(report.Status, ShouldEqual, PingStatusOK)
+				So(report.Ready, ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given I have a health server with failing pingers", t, func() {
+
+		port := freePort()
+		cfg := config{}
+		cfg.healthServer.listenAddress = fmt.Sprintf("127.0.0.1:%d", port)
+		cfg.healthServer.healthHandler = func() error { return fmt.Errorf("boom") }
+		cfg.healthServer.pingInterval = time.Second
+		cfg.healthServer.pingTimeout = time.Second
+		cfg.healthServer.pingers = map[string]Pinger{
+			"p1": MockPinger{PingStatus: fmt.Errorf("boom")},
+		}
+
+		hs := newHealthServer(cfg)
+		hs.setReady(true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		go hs.start(ctx)
+		defer hs.stop()
+
+		time.Sleep(1 * time.Second)
+
+		Convey("When I get /live", func() {
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/live", port))
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then code should be 503", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			})
+		})
+
+		Convey("When I get /ready", func() {
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/ready", port))
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then code should be 503", func() {
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			})
+
+			Convey("Then the report should be correct", func() {
+				report := HealthReport{}
+				So(json.NewDecoder(resp.Body).Decode(&report), ShouldBeNil)
+				So(report.Status, ShouldEqual, PingStatusError)
+				So(report.Ready, ShouldBeFalse)
+				So(report.Checks["p1"].LastError, ShouldEqual, "boom")
+			})
+		})
+	})
+}
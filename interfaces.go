@@ -14,6 +14,7 @@ package bahamut
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"net/http"
 
 	"go.aporeto.io/elemental"
@@ -21,7 +22,7 @@ import (
 
 type processorFinderFunc func(identity elemental.Identity) (Processor, error)
 
-type eventPusherFunc func(...*elemental.Event)
+type eventPusherFunc func(context.Context, ...*elemental.Event)
 
 type retrieveHandlersFunc func() map[string]http.HandlerFunc
 
@@ -47,6 +48,21 @@ const (
 	AuthActionContinue
 )
 
+// String returns the string representation of the AuthAction.
+func (a AuthAction) String() string {
+
+	switch a {
+	case AuthActionOK:
+		return "ok"
+	case AuthActionKO:
+		return "ko"
+	case AuthActionContinue:
+		return "continue"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(a))
+	}
+}
+
 // Server is the interface of a bahamut server.
 type Server interface {
 
@@ -302,11 +318,22 @@ type PushPublishHandler interface {
 }
 
 // Auditer is the interface an object must implement in order to handle
-// audit traces.
+// audit traces. The decisions of the authenticators and authorizers
+// consulted for the request can be retrieved using AuthDecisions.
+// See FileAuditer and PubSubAuditer for the provided implementations.
 type Auditer interface {
 	Audit(Context, error)
 }
 
+// An ErrorReporter is the interface an object must implement in order to
+// forward the recovered panics and the internal errors to an error tracker.
+// ReportError is called synchronously while handling the request, so
+// implementations should not block. See FileErrorReporter for the
+// provided implementation.
+type ErrorReporter interface {
+	ReportError(ErrorReport)
+}
+
 // A RateLimiter is the interface an object must implement in order to
 // limit the rate of the incoming requests.
 type RateLimiter interface {
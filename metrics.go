@@ -16,11 +16,30 @@ import (
 	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
+	"go.aporeto.io/elemental"
+)
+
+// FailureStage represents the stage of the request
+// pipeline that rejected a request.
+type FailureStage string
+
+// Various values of FailureStage.
+const (
+	FailureStageNone           FailureStage = ""
+	FailureStageAuthentication FailureStage = "authentication"
+	FailureStageAuthorization  FailureStage = "authorization"
+	FailureStageRateLimit      FailureStage = "rate_limit"
+	FailureStageReadOnly       FailureStage = "read_only"
 )
 
 // FinishMeasurementFunc is the kind of functinon returned by MetricsManager.MeasureRequest().
 type FinishMeasurementFunc func(code int, span opentracing.Span) time.Duration
 
+// FinishAPIMeasurementFunc is the kind of function returned by APIMetricsManager.MeasureAPIRequest().
+// It must be called with the response status code, the FailureStage that rejected the
+// request, if any, and the size of the response body.
+type FinishAPIMeasurementFunc func(code int, stage FailureStage, responseSize int) time.Duration
+
 // A MetricsManager handles Prometheus Metrics Management
 type MetricsManager interface {
 	MeasureRequest(method string, url string) FinishMeasurementFunc
@@ -30,3 +49,52 @@ type MetricsManager interface {
 	UnregisterTCPConnection()
 	Write(w http.ResponseWriter, r *http.Request)
 }
+
+// An APIMetricsManager measures the elemental requests per identity and operation.
+//
+// If the MetricsManager given to OptHealthServerMetricsManager also
+// implements APIMetricsManager, the rest server will use it to measure
+// the requests it has been able to decode.
+type APIMetricsManager interface {
+	MeasureAPIRequest(request *elemental.Request) FinishAPIMeasurementFunc
+}
+
+// PushFilterReason represents the reason why an event
+// has not been dispatched to a push session.
+type PushFilterReason string
+
+// Various values of PushFilterReason.
+const (
+	PushFilterReasonPushConfig     PushFilterReason = "push_config"
+	PushFilterReasonShouldDispatch PushFilterReason = "should_dispatch"
+)
+
+// PushSessionCloseReason represents the reason why
+// a push session has been closed.
+type PushSessionCloseReason string
+
+// Various values of PushSessionCloseReason.
+const (
+	PushSessionCloseReasonInvalidPushConfig PushSessionCloseReason = "invalid_push_config"
+	PushSessionCloseReasonConnectionClosed  PushSessionCloseReason = "connection_closed"
+	PushSessionCloseReasonServerShutdown    PushSessionCloseReason = "server_shutdown"
+)
+
+// A PushMetricsManager handles the metrics of the push subsystem.
+//
+// If the MetricsManager given to OptHealthServerMetricsManager also
+// implements PushMetricsManager, the push server will use it to report
+// the lifecycle of the events, from their publication to their
+// delivery to the websocket sessions.
+type PushMetricsManager interface {
+	RegisterEventPublished(identity string, eventType elemental.EventType)
+	RegisterEventPublishFailure(identity string, eventType elemental.EventType)
+	RegisterEventPublishRetry(identity string, eventType elemental.EventType)
+	RegisterPublicationReceived()
+	RegisterPublicationDecodeFailure()
+	RegisterEventFiltered(identity string, reason PushFilterReason)
+	RegisterEventDropped(identity string)
+	MeasureEventDispatchLatency(identity string, latency time.Duration)
+	ObservePushSessionQueueDepth(depth int)
+	RegisterPushSessionClosed(reason PushSessionCloseReason)
+}
@@ -21,8 +21,13 @@ import (
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.aporeto.io/elemental"
 )
 
+// sizeBuckets are the buckets used to observe body sizes,
+// from 64B to 16MiB.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 10)
+
 var vregexp = regexp.MustCompile(`^/v/\d+`)
 
 func sanitizeURL(url string) string {
@@ -43,10 +48,27 @@ type prometheusMetricsManager struct {
 	reqDurationMetric    *prometheus.SummaryVec
 	reqTotalMetric       *prometheus.CounterVec
 	errorMetric          *prometheus.CounterVec
+	apiReqTotalMetric    *prometheus.CounterVec
+	apiReqDurationMetric *prometheus.HistogramVec
+	apiReqSizeMetric     *prometheus.HistogramVec
+	apiRespSizeMetric    *prometheus.HistogramVec
 	tcpConnTotalMetric   prometheus.Counter
 	tcpConnCurrentMetric prometheus.Gauge
 	wsConnTotalMetric    prometheus.Counter
 	wsConnCurrentMetric  prometheus.Gauge
+	authzCacheHitMetric  prometheus.Counter
+	authzCacheMissMetric prometheus.Counter
+
+	pushPublishedMetric      *prometheus.CounterVec
+	pushPublishFailureMetric *prometheus.CounterVec
+	pushPublishRetryMetric   *prometheus.CounterVec
+	pushReceivedMetric       prometheus.Counter
+	pushDecodeFailureMetric  prometheus.Counter
+	pushFilteredMetric       *prometheus.CounterVec
+	pushDroppedMetric        *prometheus.CounterVec
+	pushLatencyMetric        *prometheus.HistogramVec
+	pushQueueDepthMetric     prometheus.Histogram
+	pushSessionsClosedMetric *prometheus.CounterVec
 
 	handler http.Handler
 }
@@ -106,6 +128,118 @@ func newPrometheusMetricsManager(registerer prometheus.Registerer) MetricsManage
 			},
 			[]string{"trace", "method", "url", "code"},
 		),
+		authzCacheHitMetric: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "authorization_cache_hits_total",
+				Help: "The total number of authorization decisions found in cache.",
+			},
+		),
+		authzCacheMissMetric: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "authorization_cache_misses_total",
+				Help: "The total number of authorization decisions not found in cache.",
+			},
+		),
+		apiReqTotalMetric: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_api_requests_total",
+				Help: "The total number of API requests per identity and operation.",
+			},
+			[]string{"identity", "operation", "version", "code", "failure_stage"},
+		),
+		apiReqDurationMetric: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_api_requests_duration_seconds",
+				Help:    "The duration of the API requests per identity and operation.",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"identity", "operation"},
+		),
+		apiReqSizeMetric: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_api_request_size_bytes",
+				Help:    "The size of the API request bodies per identity and operation.",
+				Buckets: sizeBuckets,
+			},
+			[]string{"identity", "operation"},
+		),
+		apiRespSizeMetric: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_api_response_size_bytes",
+				Help:    "The size of the API response bodies per identity and operation.",
+				Buckets: sizeBuckets,
+			},
+			[]string{"identity", "operation"},
+		),
+		pushPublishedMetric: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "push_events_published_total",
+				Help: "The total number of events published.",
+			},
+			[]string{"identity", "type"},
+		),
+		pushPublishFailureMetric: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "push_events_publish_failures_total",
+				Help: "The total number of events that could not be published.",
+			},
+			[]string{"identity", "type"},
+		),
+		pushPublishRetryMetric: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "push_events_publish_retries_total",
+				Help: "The total number of event publication retries.",
+			},
+			[]string{"identity", "type"},
+		),
+		pushReceivedMetric: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "push_publications_received_total",
+				Help: "The total number of publications received by the push server.",
+			},
+		),
+		pushDecodeFailureMetric: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "push_publications_decode_failures_total",
+				Help: "The total number of publications that could not be decoded into an event.",
+			},
+		),
+		pushFilteredMetric: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "push_events_filtered_total",
+				Help: "The total number of events not dispatched to a session.",
+			},
+			[]string{"identity", "reason"},
+		),
+		pushDroppedMetric: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "push_events_dropped_total",
+				Help: "The total number of events dropped because of a slow session.",
+			},
+			[]string{"identity"},
+		),
+		pushLatencyMetric: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "push_events_dispatch_latency_seconds",
+				Help:    "The time between the event creation and its write to a session.",
+				Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+			},
+			[]string{"identity"},
+		),
+		pushQueueDepthMetric: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "push_session_queue_depth",
+				Help:    "The number of messages waiting to be written to a session.",
+				Buckets: prometheus.LinearBuckets(0, 8, 9),
+			},
+		),
+		pushSessionsClosedMetric: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "push_sessions_closed_total",
+				Help: "The total number of push sessions closed.",
+			},
+			[]string{"reason"},
+		),
 	}
 
 	registerer.MustRegister(mc.tcpConnCurrentMetric)
@@ -115,6 +249,22 @@ func newPrometheusMetricsManager(registerer prometheus.Registerer) MetricsManage
 	registerer.MustRegister(mc.wsConnTotalMetric)
 	registerer.MustRegister(mc.wsConnCurrentMetric)
 	registerer.MustRegister(mc.errorMetric)
+	registerer.MustRegister(mc.authzCacheHitMetric)
+	registerer.MustRegister(mc.authzCacheMissMetric)
+	registerer.MustRegister(mc.apiReqTotalMetric)
+	registerer.MustRegister(mc.apiReqDurationMetric)
+	registerer.MustRegister(mc.apiReqSizeMetric)
+	registerer.MustRegister(mc.apiRespSizeMetric)
+	registerer.MustRegister(mc.pushPublishedMetric)
+	registerer.MustRegister(mc.pushPublishFailureMetric)
+	registerer.MustRegister(mc.pushPublishRetryMetric)
+	registerer.MustRegister(mc.pushReceivedMetric)
+	registerer.MustRegister(mc.pushDecodeFailureMetric)
+	registerer.MustRegister(mc.pushFilteredMetric)
+	registerer.MustRegister(mc.pushDroppedMetric)
+	registerer.MustRegister(mc.pushLatencyMetric)
+	registerer.MustRegister(mc.pushQueueDepthMetric)
+	registerer.MustRegister(mc.pushSessionsClosedMetric)
 
 	return mc
 }
@@ -158,6 +308,45 @@ func (c *prometheusMetricsManager) MeasureRequest(method string, url string) Fin
 	}
 }
 
+func (c *prometheusMetricsManager) MeasureAPIRequest(request *elemental.Request) FinishAPIMeasurementFunc {
+
+	// Labels only use values coming from the model
+	// or from a closed set, to keep cardinality bounded.
+	identity := request.Identity.Name
+	if identity == "" {
+		identity = "unknown"
+	}
+	operation := string(request.Operation)
+	version := strconv.Itoa(request.Version)
+	requestSize := len(request.Data)
+
+	start := time.Now()
+
+	return func(code int, stage FailureStage, responseSize int) time.Duration {
+
+		d := time.Since(start)
+
+		c.apiReqTotalMetric.With(prometheus.Labels{
+			"identity":      identity,
+			"operation":     operation,
+			"version":       version,
+			"code":          strconv.Itoa(code),
+			"failure_stage": string(stage),
+		}).Inc()
+
+		labels := prometheus.Labels{
+			"identity":  identity,
+			"operation": operation,
+		}
+
+		c.apiReqDurationMetric.With(labels).Observe(d.Seconds())
+		c.apiReqSizeMetric.With(labels).Observe(float64(requestSize))
+		c.apiRespSizeMetric.With(labels).Observe(float64(responseSize))
+
+		return d
+	}
+}
+
 func (c *prometheusMetricsManager) RegisterWSConnection() {
 	c.wsConnTotalMetric.Inc()
 	c.wsConnCurrentMetric.Inc()
@@ -176,6 +365,54 @@ func (c *prometheusMetricsManager) UnregisterTCPConnection() {
 	c.tcpConnCurrentMetric.Dec()
 }
 
+func (c *prometheusMetricsManager) RegisterAuthorizationCacheHit() {
+	c.authzCacheHitMetric.Inc()
+}
+
+func (c *prometheusMetricsManager) RegisterAuthorizationCacheMiss() {
+	c.authzCacheMissMetric.Inc()
+}
+
+func (c *prometheusMetricsManager) RegisterEventPublished(identity string, eventType elemental.EventType) {
+	c.pushPublishedMetric.With(prometheus.Labels{"identity": identity, "type": string(eventType)}).Inc()
+}
+
+func (c *prometheusMetricsManager) RegisterEventPublishFailure(identity string, eventType elemental.EventType) {
+	c.pushPublishFailureMetric.With(prometheus.Labels{"identity": identity, "type": string(eventType)}).Inc()
+}
+
+func (c *prometheusMetricsManager) RegisterEventPublishRetry(identity string, eventType elemental.EventType) {
+	c.pushPublishRetryMetric.With(prometheus.Labels{"identity": identity, "type": string(eventType)}).Inc()
+}
+
+func (c *prometheusMetricsManager) RegisterPublicationReceived() {
+	c.pushReceivedMetric.Inc()
+}
+
+func (c *prometheusMetricsManager) RegisterPublicationDecodeFailure() {
+	c.pushDecodeFailureMetric.Inc()
+}
+
+func (c *prometheusMetricsManager) RegisterEventFiltered(identity string, reason PushFilterReason) {
+	c.pushFilteredMetric.With(prometheus.Labels{"identity": identity, "reason": string(reason)}).Inc()
+}
+
+func (c *prometheusMetricsManager) RegisterEventDropped(identity string) {
+	c.pushDroppedMetric.With(prometheus.Labels{"identity": identity}).Inc()
+}
+
+func (c *prometheusMetricsManager) MeasureEventDispatchLatency(identity string, latency time.Duration) {
+	c.pushLatencyMetric.With(prometheus.Labels{"identity": identity}).Observe(latency.Seconds())
+}
+
+func (c *prometheusMetricsManager) ObservePushSessionQueueDepth(depth int) {
+	c.pushQueueDepthMetric.Observe(float64(depth))
+}
+
+func (c *prometheusMetricsManager) RegisterPushSessionClosed(reason PushSessionCloseReason) {
+	c.pushSessionsClosedMetric.With(prometheus.Labels{"reason": string(reason)}).Inc()
+}
+
 func (c *prometheusMetricsManager) Write(w http.ResponseWriter, r *http.Request) {
 	c.handler.ServeHTTP(w, r)
 }
@@ -13,9 +13,12 @@ package bahamut
 
 import (
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	testmodel "go.aporeto.io/elemental/test/model"
 )
 
 func Test_sanitizeURL(t *testing.T) {
@@ -94,11 +97,11 @@ func TestMeasureRequest(t *testing.T) {
 			data, _ := r.Gather()
 
 			Convey("Then the data should collected", func() {
-				So(data[1].GetName(), ShouldEqual, "http_requests_total")
-				So(data[1].GetMetric()[0].Counter.String(), ShouldEqual, "value:1 ")
-				So(data[1].GetMetric()[0].Label[0].String(), ShouldEqual, `name:"code" value:"200" `)
-				So(data[1].GetMetric()[0].Label[1].String(), ShouldEqual, `name:"method" value:"GET" `)
-				So(data[1].GetMetric()[0].Label[2].String(), ShouldEqual, `name:"url" value:"/toto/:id" `)
+				So(data[3].GetName(), ShouldEqual, "http_requests_total")
+				So(data[3].GetMetric()[0].Counter.String(), ShouldEqual, "value:1 ")
+				So(data[3].GetMetric()[0].Label[0].String(), ShouldEqual, `name:"code" value:"200" `)
+				So(data[3].GetMetric()[0].Label[1].String(), ShouldEqual, `name:"method" value:"GET" `)
+				So(data[3].GetMetric()[0].Label[2].String(), ShouldEqual, `name:"url" value:"/toto/:id" `)
 			})
 		})
 
@@ -110,11 +113,66 @@ func TestMeasureRequest(t *testing.T) {
 			data, _ := r.Gather()
 
 			Convey("Then the data should collected", func() {
-				So(data[0].GetName(), ShouldEqual, "http_errors_5xx_total")
-				So(data[0].GetMetric()[0].Label[0].String(), ShouldEqual, `name:"code" value:"502" `)
-				So(data[0].GetMetric()[0].Label[1].String(), ShouldEqual, `name:"method" value:"GET" `)
-				So(data[0].GetMetric()[0].Label[2].String(), ShouldEqual, `name:"trace" value:"unknown" `)
-				So(data[0].GetMetric()[0].Label[3].String(), ShouldEqual, `name:"url" value:"http://:id/id/toto" `)
+				So(data[2].GetName(), ShouldEqual, "http_errors_5xx_total")
+				So(data[2].GetMetric()[0].Label[0].String(), ShouldEqual, `name:"code" value:"502" `)
+				So(data[2].GetMetric()[0].Label[1].String(), ShouldEqual, `name:"method" value:"GET" `)
+				So(data[2].GetMetric()[0].Label[2].String(), ShouldEqual, `name:"trace" value:"unknown" `)
+				So(data[2].GetMetric()[0].Label[3].String(), ShouldEqual, `name:"url" value:"http://:id/id/toto" `)
+			})
+		})
+	})
+}
+
+func TestMeasureAPIRequest(t *testing.T) {
+
+	Convey("Given I have a PrometheusMetricsManager", t, func() {
+
+		r := prometheus.NewRegistry()
+		pmm := newPrometheusMetricsManager(r).(*prometheusMetricsManager)
+
+		Convey("Then it should implement APIMetricsManager", func() {
+			So(pmm, ShouldImplement, (*APIMetricsManager)(nil))
+		})
+
+		Convey("When I measure an API request rejected during authorization", func() {
+
+			req := elemental.NewRequest()
+			req.Identity = testmodel.ListIdentity
+			req.Operation = elemental.OperationPatch
+			req.Version = 1
+			req.Data = []byte("hello")
+
+			f := pmm.MeasureAPIRequest(req)
+			f(403, FailureStageAuthorization, 100)
+
+			data, _ := r.Gather()
+
+			Convey("Then the request size should be collected", func() {
+				So(data[2].GetName(), ShouldEqual, "http_api_request_size_bytes")
+				So(data[2].GetMetric()[0].Histogram.GetSampleCount(), ShouldEqual, 1)
+				So(data[2].GetMetric()[0].Histogram.GetSampleSum(), ShouldEqual, 5)
+				So(data[2].GetMetric()[0].Label[0].String(), ShouldEqual, `name:"identity" value:"list" `)
+				So(data[2].GetMetric()[0].Label[1].String(), ShouldEqual, `name:"operation" value:"patch" `)
+			})
+
+			Convey("Then the duration should be collected", func() {
+				So(data[3].GetName(), ShouldEqual, "http_api_requests_duration_seconds")
+				So(data[3].GetMetric()[0].Histogram.GetSampleCount(), ShouldEqual, 1)
+			})
+
+			Convey("Then the total should be collected", func() {
+				So(data[4].GetName(), ShouldEqual, "http_api_requests_total")
+				So(data[4].GetMetric()[0].Counter.String(), ShouldEqual, "value:1 ")
+				So(data[4].GetMetric()[0].Label[0].String(), ShouldEqual, `name:"code" value:"403" `)
+				So(data[4].GetMetric()[0].Label[1].String(), ShouldEqual, `name:"failure_stage" value:"authorization" `)
+				So(data[4].GetMetric()[0].Label[2].String(), ShouldEqual, `name:"identity" value:"list" `)
+				So(data[4].GetMetric()[0].Label[3].String(), ShouldEqual, `name:"operation" value:"patch" `)
+				So(data[4].GetMetric()[0].Label[4].String(), ShouldEqual, `name:"version" value:"1" `)
+			})
+
+			Convey("Then the response size should be collected", func() {
+				So(data[5].GetName(), ShouldEqual, "http_api_response_size_bytes")
+				So(data[5].GetMetric()[0].Histogram.GetSampleSum(), ShouldEqual, 100)
 			})
 		})
 	})
@@ -135,10 +193,10 @@ func TestRegisterWSConnection(t *testing.T) {
 			data, _ := r.Gather()
 
 			Convey("Then the total should increase", func() {
-				So(data[0].GetName(), ShouldEqual, "http_ws_connections_current")
-				So(data[0].GetMetric()[0].String(), ShouldEqual, "gauge:<value:2 > ")
-				So(data[1].GetName(), ShouldEqual, "http_ws_connections_total")
-				So(data[1].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
+				So(data[2].GetName(), ShouldEqual, "http_ws_connections_current")
+				So(data[2].GetMetric()[0].String(), ShouldEqual, "gauge:<value:2 > ")
+				So(data[3].GetName(), ShouldEqual, "http_ws_connections_total")
+				So(data[3].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
 			})
 
 			Convey("When I call UnregisterWSConnection", func() {
@@ -148,10 +206,10 @@ func TestRegisterWSConnection(t *testing.T) {
 				data, _ := r.Gather()
 
 				Convey("Then the total should increase", func() {
-					So(data[0].GetName(), ShouldEqual, "http_ws_connections_current")
-					So(data[0].GetMetric()[0].String(), ShouldEqual, "gauge:<value:1 > ")
-					So(data[1].GetName(), ShouldEqual, "http_ws_connections_total")
-					So(data[1].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
+					So(data[2].GetName(), ShouldEqual, "http_ws_connections_current")
+					So(data[2].GetMetric()[0].String(), ShouldEqual, "gauge:<value:1 > ")
+					So(data[3].GetName(), ShouldEqual, "http_ws_connections_total")
+					So(data[3].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
 				})
 			})
 		})
@@ -173,10 +231,10 @@ func TestRegisterTCPConnection(t *testing.T) {
 			data, _ := r.Gather()
 
 			Convey("Then the total should increase", func() {
-				So(data[2].GetName(), ShouldEqual, "tcp_connections_current")
-				So(data[2].GetMetric()[0].String(), ShouldEqual, "gauge:<value:2 > ")
-				So(data[3].GetName(), ShouldEqual, "tcp_connections_total")
-				So(data[3].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
+				So(data[7].GetName(), ShouldEqual, "tcp_connections_current")
+				So(data[7].GetMetric()[0].String(), ShouldEqual, "gauge:<value:2 > ")
+				So(data[8].GetName(), ShouldEqual, "tcp_connections_total")
+				So(data[8].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
 			})
 
 			Convey("When I call UnregisterTCPConnection", func() {
@@ -186,12 +244,103 @@ func TestRegisterTCPConnection(t *testing.T) {
 				data, _ := r.Gather()
 
 				Convey("Then the total should increase", func() {
-					So(data[2].GetName(), ShouldEqual, "tcp_connections_current")
-					So(data[2].GetMetric()[0].String(), ShouldEqual, "gauge:<value:1 > ")
-					So(data[3].GetName(), ShouldEqual, "tcp_connections_total")
-					So(data[3].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
+					So(data[7].GetName(), ShouldEqual, "tcp_connections_current")
+					So(data[7].GetMetric()[0].String(), ShouldEqual, "gauge:<value:1 > ")
+					So(data[8].GetName(), ShouldEqual, "tcp_connections_total")
+					So(data[8].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
 				})
 			})
 		})
 	})
 }
+
+func TestRegisterAuthorizationCache(t *testing.T) {
+
+	Convey("Given I have a PrometheusMetricsManager", t, func() {
+
+		r := prometheus.NewRegistry()
+		pmm := newPrometheusMetricsManager(r).(*prometheusMetricsManager)
+
+		Convey("When I call RegisterAuthorizationCacheHit twice and RegisterAuthorizationCacheMiss once", func() {
+
+			pmm.RegisterAuthorizationCacheHit()
+			pmm.RegisterAuthorizationCacheHit()
+			pmm.RegisterAuthorizationCacheMiss()
+
+			data, _ := r.Gather()
+
+			Convey("Then the totals should increase", func() {
+				So(data[0].GetName(), ShouldEqual, "authorization_cache_hits_total")
+				So(data[0].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
+				So(data[1].GetName(), ShouldEqual, "authorization_cache_misses_total")
+				So(data[1].GetMetric()[0].String(), ShouldEqual, "counter:<value:1 > ")
+			})
+		})
+	})
+}
+
+func TestPushMetrics(t *testing.T) {
+
+	Convey("Given I have a PrometheusMetricsManager", t, func() {
+
+		r := prometheus.NewRegistry()
+		pmm := newPrometheusMetricsManager(r).(*prometheusMetricsManager)
+
+		Convey("Then it should implement PushMetricsManager", func() {
+			So(pmm, ShouldImplement, (*PushMetricsManager)(nil))
+		})
+
+		Convey("When I report the publication of events", func() {
+
+			pmm.RegisterEventPublished("list", elemental.EventCreate)
+			pmm.RegisterEventPublishRetry("list", elemental.EventCreate)
+			pmm.RegisterEventPublishFailure("list", elemental.EventDelete)
+
+			data, _ := r.Gather()
+
+			Convey("Then the data should be collected", func() {
+				So(data[4].GetName(), ShouldEqual, "push_events_publish_failures_total")
+				So(data[4].GetMetric()[0].Label[0].String(), ShouldEqual, `name:"identity" value:"list" `)
+				So(data[4].GetMetric()[0].Label[1].String(), ShouldEqual, `name:"type" value:"delete" `)
+				So(data[5].GetName(), ShouldEqual, "push_events_publish_retries_total")
+				So(data[5].GetMetric()[0].Counter.String(), ShouldEqual, "value:1 ")
+				So(data[6].GetName(), ShouldEqual, "push_events_published_total")
+				So(data[6].GetMetric()[0].Label[1].String(), ShouldEqual, `name:"type" value:"create" `)
+			})
+		})
+
+		Convey("When I report the dispatch of events", func() {
+
+			pmm.RegisterPublicationReceived()
+			pmm.RegisterPublicationReceived()
+			pmm.RegisterPublicationDecodeFailure()
+			pmm.RegisterEventFiltered("list", PushFilterReasonPushConfig)
+			pmm.RegisterEventFiltered("list", PushFilterReasonShouldDispatch)
+			pmm.RegisterEventDropped("list")
+			pmm.MeasureEventDispatchLatency("list", 3*time.Millisecond)
+			pmm.ObservePushSessionQueueDepth(12)
+			pmm.RegisterPushSessionClosed(PushSessionCloseReasonConnectionClosed)
+
+			data, _ := r.Gather()
+
+			Convey("Then the data should be collected", func() {
+				So(data[4].GetName(), ShouldEqual, "push_events_dispatch_latency_seconds")
+				So(data[4].GetMetric()[0].Histogram.GetSampleSum(), ShouldEqual, 0.003)
+				So(data[5].GetName(), ShouldEqual, "push_events_dropped_total")
+				So(data[5].GetMetric()[0].Counter.String(), ShouldEqual, "value:1 ")
+				So(data[6].GetName(), ShouldEqual, "push_events_filtered_total")
+				So(len(data[6].GetMetric()), ShouldEqual, 2)
+				So(data[6].GetMetric()[0].Label[1].String(), ShouldEqual, `name:"reason" value:"push_config" `)
+				So(data[6].GetMetric()[1].Label[1].String(), ShouldEqual, `name:"reason" value:"should_dispatch" `)
+				So(data[7].GetName(), ShouldEqual, "push_publications_decode_failures_total")
+				So(data[7].GetMetric()[0].String(), ShouldEqual, "counter:<value:1 > ")
+				So(data[8].GetName(), ShouldEqual, "push_publications_received_total")
+				So(data[8].GetMetric()[0].String(), ShouldEqual, "counter:<value:2 > ")
+				So(data[9].GetName(), ShouldEqual, "push_session_queue_depth")
+				So(data[9].GetMetric()[0].Histogram.GetSampleSum(), ShouldEqual, 12)
+				So(data[10].GetName(), ShouldEqual, "push_sessions_closed_total")
+				So(data[10].GetMetric()[0].Label[0].String(), ShouldEqual, `name:"reason" value:"connection_closed" `)
+			})
+		})
+	})
+}
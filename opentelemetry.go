@@ -0,0 +1,163 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.aporeto.io/elemental"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelInstrumentationName is the name of the OpenTelemetry
+// instrumentation library reported by bahamut.
+const otelInstrumentationName = "go.aporeto.io/bahamut"
+
+// otelPropagator is the propagator used to carry the span
+// context across the wire, using the W3C traceparent format.
+var otelPropagator = propagation.TraceContext{}
+
+type otelSpanContextKeyType struct{}
+
+// otelSpanContextKey is the context key used to hold the span
+// created by bahamut, so it only ever ends spans it owns.
+var otelSpanContextKey = otelSpanContextKeyType{}
+
+// otelSpanFromContext returns the OpenTelemetry span started
+// by bahamut for the given context, or nil.
+func otelSpanFromContext(ctx context.Context) trace.Span {
+
+	if ctx == nil {
+		return nil
+	}
+
+	span, _ := ctx.Value(otelSpanContextKey).(trace.Span)
+
+	return span
+}
+
+// traceRequestOTel starts an OpenTelemetry span for the request.
+func traceRequestOTel(ctx context.Context, r *elemental.Request, tp trace.TracerProvider, exludedIdentities map[string]struct{}, cleaner TraceCleaner) context.Context {
+
+	if tp == nil {
+		return ctx
+	}
+
+	if _, ok := exludedIdentities[r.Identity.Name]; ok {
+		return ctx
+	}
+
+	ctx = otelPropagator.Extract(ctx, propagation.HeaderCarrier(r.Headers))
+
+	attrs := []attribute.KeyValue{
+		attribute.Int("req.api_version", r.Version),
+		attribute.String("req.id", r.RequestID),
+		attribute.String("req.identity", r.Identity.Name),
+		attribute.Bool("req.recursive", r.Recursive),
+		attribute.String("req.operation", string(r.Operation)),
+		attribute.Bool("req.override_protection", r.OverrideProtection),
+	}
+
+	if r.ExternalTrackingID != "" {
+		attrs = append(attrs, attribute.String("req.external_tracking_id", r.ExternalTrackingID))
+	}
+
+	if r.ExternalTrackingType != "" {
+		attrs = append(attrs, attribute.String("req.external_tracking_type", r.ExternalTrackingType))
+	}
+
+	if r.Namespace != "" {
+		attrs = append(attrs, attribute.String("req.namespace", r.Namespace))
+	}
+
+	if r.ObjectID != "" {
+		attrs = append(attrs, attribute.String("req.object.id", r.ObjectID))
+	}
+
+	if r.ParentID != "" {
+		attrs = append(attrs, attribute.String("req.parent.id", r.ParentID))
+	}
+
+	if !r.ParentIdentity.IsEmpty() {
+		attrs = append(attrs, attribute.String("req.parent.identity", r.ParentIdentity.Name))
+	}
+
+	ctx, span := tp.Tracer(otelInstrumentationName).Start(
+		ctx,
+		tracingName(r),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+
+	data := append([]byte{}, r.Data...)
+	if cleaner != nil {
+		data = cleaner(r.Identity, data)
+	}
+
+	span.AddEvent(
+		"request",
+		trace.WithAttributes(
+			attribute.Int("req.page.number", r.Page),
+			attribute.Int("req.page.size", r.PageSize),
+			attribute.String("req.headers", fmt.Sprintf("%v", safeHeaders(r))),
+			attribute.String("req.claims", extractClaims(r)),
+			attribute.String("req.client_ip", r.ClientIP),
+			attribute.String("req.parameters", fmt.Sprintf("%v", safeParameters(r))),
+			attribute.StringSlice("req.order_by", r.Order),
+			attribute.String("req.payload", string(data)),
+		),
+	)
+
+	return context.WithValue(ctx, otelSpanContextKey, span)
+}
+
+// traceWSSessionOTel extracts the W3C trace context from the given
+// websocket upgrade request and starts a span covering the session.
+// The returned context carries the session span and is meant to be
+// used as the session's context. The span is ended by finishTracing.
+func traceWSSessionOTel(ctx context.Context, req *http.Request, tp trace.TracerProvider) context.Context {
+
+	if tp == nil {
+		return ctx
+	}
+
+	ctx = otelPropagator.Extract(ctx, propagation.HeaderCarrier(req.Header))
+
+	ctx, span := tp.Tracer(otelInstrumentationName).Start(
+		ctx,
+		"bahamut.push.session",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("session.path", req.URL.Path),
+			attribute.String("session.remote_addr", req.RemoteAddr),
+		),
+	)
+
+	return context.WithValue(ctx, otelSpanContextKey, span)
+}
+
+// recordOTelError records the given error on the OpenTelemetry span, if any.
+func recordOTelError(span trace.Span, err elemental.Errors) {
+
+	if span == nil {
+		return
+	}
+
+	span.SetAttributes(attribute.Int("status.code", err.Code()))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,227 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.aporeto.io/elemental"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func newTestTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+
+	exporter := tracetest.NewInMemoryExporter()
+
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+func attributeValue(attrs []attribute.KeyValue, key string) (attribute.Value, bool) {
+
+	for _, a := range attrs {
+		if string(a.Key) == key {
+			return a.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func TestOTel_traceRequestOTel(t *testing.T) {
+
+	Convey("Given I have a request", t, func() {
+
+		req := elemental.NewRequest()
+		req.Identity = elemental.MakeIdentity("list", "lists")
+		req.Operation = elemental.OperationCreate
+		req.Namespace = "/ns"
+		req.RequestID = "xxx"
+		req.Data = []byte(`{"secret":"s"}`)
+		req.Headers.Set("traceparent", testTraceParent)
+		req.Headers.Set("Authorization", "secret")
+
+		Convey("When I call traceRequestOTel with no tracer provider", func() {
+
+			ctx := traceRequestOTel(context.Background(), req, nil, nil, nil)
+
+			Convey("Then the context should be unchanged", func() {
+				So(otelSpanFromContext(ctx), ShouldBeNil)
+				So(trace.SpanContextFromContext(ctx).IsValid(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When I call traceRequestOTel with an excluded identity", func() {
+
+			tp, exporter := newTestTracerProvider()
+
+			ctx := traceRequestOTel(context.Background(), req, tp, map[string]struct{}{"list": {}}, nil)
+			finishTracing(ctx)
+
+			Convey("Then no span should be recorded", func() {
+				So(otelSpanFromContext(ctx), ShouldBeNil)
+				So(exporter.GetSpans(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I call traceRequestOTel and finish the trace", func() {
+
+			tp, exporter := newTestTracerProvider()
+
+			cleaner := func(identity elemental.Identity, data []byte) []byte {
+				return []byte("cleaned")
+			}
+
+			ctx := traceRequestOTel(context.Background(), req, tp, nil, cleaner)
+			finishTracing(ctx)
+
+			spans := exporter.GetSpans()
+
+			Convey("Then a server span should be recorded", func() {
+				So(len(spans), ShouldEqual, 1)
+				So(spans[0].Name, ShouldEqual, "bahamut.handle.create.lists")
+				So(spans[0].SpanKind, ShouldEqual, trace.SpanKindServer)
+			})
+
+			Convey("Then the span should continue the W3C trace", func() {
+				So(spans[0].SpanContext.TraceID().String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+				So(spans[0].Parent.SpanID().String(), ShouldEqual, "00f067aa0ba902b7")
+				So(spans[0].Parent.IsRemote(), ShouldBeTrue)
+			})
+
+			Convey("Then the span should have the request attributes", func() {
+				v, ok := attributeValue(spans[0].Attributes, "req.identity")
+				So(ok, ShouldBeTrue)
+				So(v.AsString(), ShouldEqual, "list")
+				v, ok = attributeValue(spans[0].Attributes, "req.namespace")
+				So(ok, ShouldBeTrue)
+				So(v.AsString(), ShouldEqual, "/ns")
+				_, ok = attributeValue(spans[0].Attributes, "req.object.id")
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("Then the payload should be cleaned and the headers snipped", func() {
+				So(len(spans[0].Events), ShouldEqual, 1)
+				v, _ := attributeValue(spans[0].Events[0].Attributes, "req.payload")
+				So(v.AsString(), ShouldEqual, "cleaned")
+				v, _ = attributeValue(spans[0].Events[0].Attributes, "req.headers")
+				So(v.AsString(), ShouldContainSubstring, "Authorization:[[snip]]")
+				So(v.AsString(), ShouldNotContainSubstring, "secret")
+			})
+		})
+	})
+}
+
+func TestOTel_finishTracing(t *testing.T) {
+
+	Convey("Given I have a context holding a span not started by bahamut", t, func() {
+
+		tp, exporter := newTestTracerProvider()
+		ctx, span := tp.Tracer("test").Start(context.Background(), "foreign")
+
+		Convey("When I call finishTracing", func() {
+
+			finishTracing(ctx)
+
+			Convey("Then the foreign span should not be ended", func() {
+				So(exporter.GetSpans(), ShouldBeEmpty)
+				So(span.IsRecording(), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestOTel_processError(t *testing.T) {
+
+	Convey("Given I have a traced request", t, func() {
+
+		tp, exporter := newTestTracerProvider()
+
+		req := elemental.NewRequest()
+		req.Identity = elemental.MakeIdentity("list", "lists")
+		req.Operation = elemental.OperationRetrieve
+
+		ctx := traceRequestOTel(context.Background(), req, tp, nil, nil)
+
+		Convey("When I process an error", func() {
+
+			out := processError(ctx, elemental.NewError("nope", "nope", "test", http.StatusForbidden))
+			finishTracing(ctx)
+
+			spans := exporter.GetSpans()
+
+			Convey("Then the error should carry the trace ID", func() {
+				So(out[0].Trace, ShouldEqual, spans[0].SpanContext.TraceID().String())
+			})
+
+			Convey("Then the span should record the error", func() {
+				So(spans[0].Status.Code, ShouldEqual, codes.Error)
+				v, _ := attributeValue(spans[0].Attributes, "status.code")
+				So(v.AsInt64(), ShouldEqual, http.StatusForbidden)
+			})
+		})
+	})
+}
+
+func TestOTel_traceWSSessionOTel(t *testing.T) {
+
+	Convey("Given I have a websocket upgrade request", t, func() {
+
+		req, _ := http.NewRequest(http.MethodGet, "http://server/events", nil)
+		req.Header.Set("traceparent", testTraceParent)
+
+		Convey("When I call traceWSSessionOTel with no tracer provider", func() {
+
+			ctx := traceWSSessionOTel(context.Background(), req, nil)
+
+			Convey("Then the context should be unchanged", func() {
+				So(otelSpanFromContext(ctx), ShouldBeNil)
+				So(trace.SpanContextFromContext(ctx).IsValid(), ShouldBeFalse)
+			})
+		})
+
+		Convey("When I call traceWSSessionOTel and finish the trace", func() {
+
+			tp, exporter := newTestTracerProvider()
+
+			ctx := traceWSSessionOTel(context.Background(), req, tp)
+
+			pub := NewPublication("topic")
+			pub.StartOTelTracingFromContext(ctx, "child")
+			pub.finishOTelTracing(errors.New("boom"))
+
+			finishTracing(ctx)
+
+			spans := exporter.GetSpans()
+
+			Convey("Then the session span should continue the W3C trace", func() {
+				So(len(spans), ShouldEqual, 2)
+				So(spans[1].Name, ShouldEqual, "bahamut.push.session")
+				So(spans[1].SpanContext.TraceID().String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			})
+
+			Convey("Then spans started from the session context should be its children", func() {
+				So(spans[0].Parent.SpanID(), ShouldEqual, spans[1].SpanContext.SpanID())
+				So(spans[0].Status.Code, ShouldEqual, codes.Error)
+			})
+		})
+	})
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/log"
 	"go.aporeto.io/elemental"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var snipSlice = []string{"[snip]"}
@@ -75,43 +76,55 @@ func tracingName(r *elemental.Request) string {
 	return fmt.Sprintf("Unknown operation: %s", r.Operation)
 }
 
-// StartTracing starts tracing the request.
-func traceRequest(ctx context.Context, r *elemental.Request, tracer opentracing.Tracer, exludedIdentities map[string]struct{}, cleaner TraceCleaner) context.Context {
-
-	if tracer == nil {
-		return ctx
-	}
-
-	if _, ok := exludedIdentities[r.Identity.Name]; ok {
-		return ctx
-	}
-
-	spanContext, _ := tracer.Extract(opentracing.TextMap, opentracing.HTTPHeadersCarrier(r.Headers))
-	span := tracer.StartSpan(tracingName(r), ext.RPCServerOption(spanContext))
-	trackingCtx := opentracing.ContextWithSpan(ctx, span)
+// safeParameters returns the request parameters with
+// sensitive information removed.
+func safeParameters(r *elemental.Request) url.Values {
 
-	// Remove sensitive information from parameters.
-	safeParameters := url.Values{}
+	out := url.Values{}
 	for k, p := range r.Parameters {
 		lk := strings.ToLower(k)
 		if lk == "token" || lk == "password" {
-			safeParameters[k] = snipSlice
+			out[k] = snipSlice
 			continue
 		}
-		safeParameters[k] = []string{fmt.Sprintf("%v", p.Values())}
+		out[k] = []string{fmt.Sprintf("%v", p.Values())}
 	}
 
-	// Remove sensitive information from headers.
-	safeHeaders := http.Header{}
+	return out
+}
+
+// safeHeaders returns the request headers with
+// sensitive information removed.
+func safeHeaders(r *elemental.Request) http.Header {
+
+	out := http.Header{}
 	for k, v := range r.Headers {
 		lk := strings.ToLower(k)
 		if lk == "authorization" || lk == "cookie" {
-			safeHeaders[k] = snipSlice
+			out[k] = snipSlice
 			continue
 		}
-		safeHeaders[k] = v
+		out[k] = v
+	}
+
+	return out
+}
+
+// StartTracing starts tracing the request.
+func traceRequest(ctx context.Context, r *elemental.Request, tracer opentracing.Tracer, exludedIdentities map[string]struct{}, cleaner TraceCleaner) context.Context {
+
+	if tracer == nil {
+		return ctx
+	}
+
+	if _, ok := exludedIdentities[r.Identity.Name]; ok {
+		return ctx
 	}
 
+	spanContext, _ := tracer.Extract(opentracing.TextMap, opentracing.HTTPHeadersCarrier(r.Headers))
+	span := tracer.StartSpan(tracingName(r), ext.RPCServerOption(spanContext))
+	trackingCtx := opentracing.ContextWithSpan(ctx, span)
+
 	span.SetTag("req.api_version", r.Version)
 	span.SetTag("req.id", r.RequestID)
 	span.SetTag("req.identity", r.Identity.Name)
@@ -151,10 +164,10 @@ func traceRequest(ctx context.Context, r *elemental.Request, tracer opentracing.
 	span.LogFields(
 		log.Int("req.page.number", r.Page),
 		log.Int("req.page.size", r.PageSize),
-		log.Object("req.headers", safeHeaders),
+		log.Object("req.headers", safeHeaders(r)),
 		log.Object("req.claims", extractClaims(r)),
 		log.Object("req.client_ip", r.ClientIP),
-		log.Object("req.parameters", safeParameters),
+		log.Object("req.parameters", safeParameters(r)),
 		log.Object("req.order_by", r.Order),
 		log.String("req.payload", string(data)),
 	)
@@ -164,6 +177,10 @@ func traceRequest(ctx context.Context, r *elemental.Request, tracer opentracing.
 
 func finishTracing(ctx context.Context) {
 
+	if span, ok := ctx.Value(otelSpanContextKey).(trace.Span); ok {
+		span.End()
+	}
+
 	span := opentracing.SpanFromContext(ctx)
 	if span == nil {
 		return
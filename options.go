@@ -25,6 +25,7 @@ import (
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"go.aporeto.io/elemental"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
@@ -108,6 +109,76 @@ func OptHTTPLogger(l *log.Logger) Option {
 	}
 }
 
+// OptAccessLog enables the access logging of the requests
+// handled by the API server.
+//
+// The requests are logged with the given sampleRate, between 0 and 1.
+// The requests slower than slowThreshold are always logged with the
+// time spent in the authentication, the authorization, the processor and
+// the encoding. If slowThreshold is 0, only the sampled requests are logged.
+// The `Authorization`, `Proxy-Authorization`, `Cookie` and `X-API-Key` headers,
+// and the headers and parameters with a name containing "token", "password",
+// "secret" or "key", are always redacted. The attributes of the body are not
+// logged unless OptAccessLogAttributes is set.
+// This function will panic if sampleRate is not between 0 and 1 or
+// if slowThreshold is negative.
+func OptAccessLog(sampleRate float64, slowThreshold time.Duration) Option {
+
+	if sampleRate < 0 || sampleRate > 1 {
+		panic("sampleRate must be between 0 and 1")
+	}
+
+	if slowThreshold < 0 {
+		panic("slowThreshold must not be negative")
+	}
+
+	return func(c *config) {
+		c.accessLog.enabled = true
+		c.accessLog.sampleRate = sampleRate
+		c.accessLog.slowThreshold = slowThreshold
+	}
+}
+
+// OptAccessLogSlowThresholds overrides the slow threshold set by
+// OptAccessLog for the given identities. A threshold of 0 disables
+// the slow request logging for the identity. This function will
+// panic if any threshold is negative.
+func OptAccessLogSlowThresholds(thresholds map[elemental.Identity]time.Duration) Option {
+
+	for identity, t := range thresholds {
+		if t < 0 {
+			panic(fmt.Sprintf("threshold for identity '%s' must not be negative", identity.Name))
+		}
+	}
+
+	return func(c *config) {
+		c.accessLog.slowThresholds = thresholds
+	}
+}
+
+// OptAccessLogRedactions sets the headers and the attributes, from the query
+// parameters and the JSON body, that will be redacted in the access logs,
+// in addition to the default ones. The names are case insensitive.
+func OptAccessLogRedactions(headers []string, attributes []string) Option {
+	return func(c *config) {
+		c.accessLog.redactedHeaders = headers
+		c.accessLog.redactedAttributes = attributes
+	}
+}
+
+// OptAccessLogAttributes enables the logging of the top level attributes
+// of the JSON body of the requests in the access logs. As for the
+// parameters, the attributes with a name containing "token", "password",
+// "secret" or "key", or set by OptAccessLogRedactions, are redacted.
+//
+// Decoding the body has a cost, and the attributes may hold sensitive
+// data, so you should only enable it while investigating an issue.
+func OptAccessLogAttributes() Option {
+	return func(c *config) {
+		c.accessLog.attributes = true
+	}
+}
+
 // OptEnableCustomRoutePathPrefix enables custom routes in the server that
 // start with the given prefix. A user must also provide an API
 // prefix in this case and the two must not overlap. Otherwise,
@@ -264,6 +335,63 @@ func OptHealthCustomStats(handlers map[string]HealthStatFunc) Option {
 	}
 }
 
+// OptHealthServerPingers enables the health report mode of the health server.
+//
+// The given pingers will be checked in the background every interval,
+// and each ping must return before the given timeout or it will be considered
+// as timed out. The cached results are then served as a JSON report on `/`,
+// and the health server will also serve the `/live` and `/ready` endpoints.
+// The server is not ready before the postStart hook returns and after the
+// preStop hook returns. Pingers wrapped using NewNonCriticalPinger only
+// degrade the report when failing. This function will panic if pingers is empty,
+// if any pinger is nil or if interval or timeout are not positive.
+//
+// This option has no effect if the health server is not enabled.
+func OptHealthServerPingers(interval time.Duration, timeout time.Duration, pingers map[string]Pinger) Option {
+
+	if len(pingers) == 0 {
+		panic("pingers must not be empty")
+	}
+
+	if interval <= 0 {
+		panic("interval must be positive")
+	}
+
+	if timeout <= 0 {
+		panic("timeout must be positive")
+	}
+
+	for k, p := range pingers {
+		if p == nil {
+			panic(fmt.Sprintf("pinger for key '%s' must not be nil", k))
+		}
+	}
+
+	return func(c *config) {
+		c.healthServer.pingers = pingers
+		c.healthServer.pingInterval = interval
+		c.healthServer.pingTimeout = timeout
+	}
+}
+
+// OptHealthServerPingerTimeouts overrides the timeout of some
+// of the pingers set by OptHealthServerPingers.
+//
+// The keys of the given map are the names of the pingers. This function
+// will panic if any timeout is not positive.
+func OptHealthServerPingerTimeouts(timeouts map[string]time.Duration) Option {
+
+	for k, t := range timeouts {
+		if t <= 0 {
+			panic(fmt.Sprintf("timeout for key '%s' must be positive", k))
+		}
+	}
+
+	return func(c *config) {
+		c.healthServer.pingTimeouts = timeouts
+	}
+}
+
 // OptHealthServerTimeouts configures the health server timeouts.
 func OptHealthServerTimeouts(read, write, idle time.Duration) Option {
 	return func(c *config) {
@@ -281,6 +409,104 @@ func OptProfilingLocal(listen string) Option {
 	}
 }
 
+// OptProfilingRates sets the mutex profile fraction and the block profile
+// rate of the runtime when the profiling server starts. They can also be
+// changed at runtime using the `/debug/profiling/rates` endpoint of the
+// profiling server. See runtime.SetMutexProfileFraction and
+// runtime.SetBlockProfileRate for the meaning of the values.
+//
+// This function will panic if any value is negative.
+//
+// This option has no effect if the profiling server is not enabled.
+func OptProfilingRates(mutexProfileFraction int, blockProfileRate int) Option {
+
+	if mutexProfileFraction < 0 {
+		panic("mutexProfileFraction must not be negative")
+	}
+
+	if blockProfileRate < 0 {
+		panic("blockProfileRate must not be negative")
+	}
+
+	return func(c *config) {
+		c.profilingServer.ratesConfigured = true
+		c.profilingServer.mutexProfileFraction = mutexProfileFraction
+		c.profilingServer.blockProfileRate = blockProfileRate
+	}
+}
+
+// OptProfilingCapture enables the capture of the CPU, heap, mutex and
+// goroutine profiles by the profiling server.
+//
+// The profiles are captured every interval, if interval is not 0, and when
+// one of the triggers set by OptProfilingTriggers fires. The CPU profile is
+// captured during cpuDuration. Each capture is stored in its own directory
+// inside the given directory, where only the last maxCount captures younger
+// than maxAge are kept. If maxCount or maxAge is 0, the corresponding
+// retention rule is disabled. The captures can be listed, triggered and
+// downloaded using the `/debug/profiling/captures` endpoint of the profiling
+// server. This function will panic if directory is empty, if interval,
+// maxCount or maxAge is negative or if cpuDuration is not positive.
+//
+// This option has no effect if the profiling server is not enabled.
+func OptProfilingCapture(directory string, interval time.Duration, cpuDuration time.Duration, maxCount int, maxAge time.Duration) Option {
+
+	if directory == "" {
+		panic("directory must not be empty")
+	}
+
+	if interval < 0 {
+		panic("interval must not be negative")
+	}
+
+	if cpuDuration <= 0 {
+		panic("cpuDuration must be positive")
+	}
+
+	if maxCount < 0 {
+		panic("maxCount must not be negative")
+	}
+
+	if maxAge < 0 {
+		panic("maxAge must not be negative")
+	}
+
+	return func(c *config) {
+		c.profilingServer.capture.enabled = true
+		c.profilingServer.capture.directory = directory
+		c.profilingServer.capture.interval = interval
+		c.profilingServer.capture.cpuDuration = cpuDuration
+		c.profilingServer.capture.maxCount = maxCount
+		c.profilingServer.capture.maxAge = maxAge
+	}
+}
+
+// OptProfilingTriggers sets the triggers that make the profiling server
+// capture the profiles. The triggers are checked every interval, and no
+// capture will be triggered, nor requested on the captures endpoint,
+// during the given cooldown after a capture.
+// This function will panic if interval is not positive or if cooldown
+// is negative.
+//
+// This option has no effect if the profiling capture is not enabled
+// using OptProfilingCapture.
+func OptProfilingTriggers(interval time.Duration, cooldown time.Duration, triggers ...ProfilingTrigger) Option {
+
+	if interval <= 0 {
+		panic("interval must be positive")
+	}
+
+	if cooldown < 0 {
+		panic("cooldown must not be negative")
+	}
+
+	return func(c *config) {
+		c.profilingServer.capture.triggers = triggers
+		c.profilingServer.capture.triggerInterval = interval
+		c.profilingServer.capture.cooldown = cooldown
+	}
+}
+
 // OptTLS configures server TLS.
 //
 // ServerCertificates are the TLS certficates to use for the secure api server.
@@ -365,6 +591,18 @@ func OptAuditer(auditer Auditer) Option {
 	}
 }
 
+// OptAuthDebugClaim configures the claim that makes bahamut return the
+// decisions of the authenticators and authorizers consulted during a
+// request, JSON encoded, in the X-Auth-Debug response header.
+//
+// As it discloses how the requests are authorized, the claim should only
+// be given to trusted clients. By default, the header is never returned.
+func OptAuthDebugClaim(claim string) Option {
+	return func(c *config) {
+		c.security.authDebugClaim = claim
+	}
+}
+
 // OptRateLimiting configures the global rate limiting.
 func OptRateLimiting(limit float64, burst int) Option {
 	return func(c *config) {
@@ -464,6 +702,18 @@ func OptOpentracingTracer(tracer opentracing.Tracer) Option {
 	}
 }
 
+// OptOTelTracerProvider sets the OpenTelemetry trace.TracerProvider to use.
+// It can be used alongside or instead of OptOpentracingTracer. Bahamut will
+// create a span for each request and publication hop, propagating the W3C
+// traceparent through request headers, Publication.TrackingData and
+// websocket sessions. OptOpentracingExcludedIdentities and OptTraceCleaner
+// apply to both tracing modes.
+func OptOTelTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.opentelemetry.tracerProvider = tp
+	}
+}
+
 // OptOpentracingExcludedIdentities excludes the given identity from being traced.
 func OptOpentracingExcludedIdentities(identities []elemental.Identity) Option {
 	return func(c *config) {
@@ -488,6 +738,51 @@ func OptPreStopHook(hook func(Server) error) Option {
 	}
 }
 
+// OptDrain enables the graceful draining of the server on shutdown.
+//
+// When the server is stopped, after the preStop hook returns, the server
+// becomes not ready and waits for the given preDrainDelay to let the load
+// balancers notice it. Then, the push sessions are closed with the
+// "service restart" close code, telling the clients to reconnect, and
+// the API server stops accepting new connections and lets in-flight
+// requests finish for at most the given timeout. The close reason sent
+// to the push clients asks them to wait for the given reconnectDelay
+// before reconnecting, like "server draining, reconnect after 2s".
+//
+// If you use a push.Notifier, register its MakeStopHook as the preStop
+// hook so the gateways stop routing to the server before it drains:
+//
+//	notifier := push.NewNotifier(pubsub, "serviceStatus", "myservice", "10.0.0.1:443")
+//	bahamut.New(
+//	    bahamut.OptPostStartHook(notifier.MakeStartHook(ctx)),
+//	    bahamut.OptPreStopHook(notifier.MakeStopHook()),
+//	    bahamut.OptDrain(5*time.Second, 30*time.Second, 2*time.Second),
+//	)
+//
+// This function will panic if preDrainDelay or reconnectDelay is negative,
+// or if timeout is not positive.
+func OptDrain(preDrainDelay time.Duration, timeout time.Duration, reconnectDelay time.Duration) Option {
+
+	if preDrainDelay < 0 {
+		panic("preDrainDelay must not be negative")
+	}
+
+	if timeout <= 0 {
+		panic("timeout must be positive")
+	}
+
+	if reconnectDelay < 0 {
+		panic("reconnectDelay must not be negative")
+	}
+
+	return func(c *config) {
+		c.drain.enabled = true
+		c.drain.preDrainDelay = preDrainDelay
+		c.drain.timeout = timeout
+		c.drain.reconnectDelay = reconnectDelay
+	}
+}
+
 // OptTraceCleaner registers a trace cleaner that will be called to
 // let a chance to clean up various sensitive information before
 // sending the trace to the OpenTracing server.
@@ -516,3 +811,34 @@ func OptErrorTransformer(f func(error) error) Option {
 		c.hooks.errorTransformer = f
 	}
 }
+
+// OptErrorReporter sets the ErrorReporter that will be called with the panics
+// recovered and the internal errors returned while handling the requests.
+// At most limit reports per second, with the given burst, are sent to the
+// reporter, and the reports sharing the same fingerprint are only sent once
+// per dedupWindow. The fingerprint is computed from the identity and the
+// operation of the request, and from the top frames of the stack for a panic,
+// or from the type and the elemental title of an error. The reports carry the
+// stack of the goroutine that recovered the panic or got the error, but the
+// stack of an error is not part of its fingerprint. The claims in the reports
+// have their values redacted.
+// This function will panic if reporter is nil, or if limit or dedupWindow
+// is negative.
+func OptErrorReporter(reporter ErrorReporter, limit float64, burst int, dedupWindow time.Duration) Option {
+
+	if reporter == nil {
+		panic("reporter must not be nil")
+	}
+
+	if limit < 0 {
+		panic("limit must not be negative")
+	}
+
+	if dedupWindow < 0 {
+		panic("dedupWindow must not be negative")
+	}
+
+	return func(c *config) {
+		c.hooks.errorReporter = newErrorReporter(reporter, limit, burst, dedupWindow)
+	}
+}
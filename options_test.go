@@ -132,6 +132,40 @@ func TestBahamut_Options(t *testing.T) {
 		So(c.healthServer.idleTimeout, ShouldEqual, 3*time.Second)
 	})
 
+	Convey("Calling OptHealthServerPingers should work", t, func() {
+		pingers := map[string]Pinger{"a": MockPinger{}}
+		OptHealthServerPingers(1*time.Second, 2*time.Second, pingers)(&c)
+		So(c.healthServer.pingers, ShouldResemble, pingers)
+		So(c.healthServer.pingInterval, ShouldEqual, 1*time.Second)
+		So(c.healthServer.pingTimeout, ShouldEqual, 2*time.Second)
+	})
+
+	Convey("Calling OptHealthServerPingers with empty pingers should panic", t, func() {
+		So(func() { OptHealthServerPingers(time.Second, time.Second, nil)(&c) }, ShouldPanicWith, "pingers must not be empty")
+	})
+
+	Convey("Calling OptHealthServerPingers with invalid interval should panic", t, func() {
+		So(func() { OptHealthServerPingers(0, time.Second, map[string]Pinger{"a": MockPinger{}})(&c) }, ShouldPanicWith, "interval must be positive")
+	})
+
+	Convey("Calling OptHealthServerPingers with invalid timeout should panic", t, func() {
+		So(func() { OptHealthServerPingers(time.Second, 0, map[string]Pinger{"a": MockPinger{}})(&c) }, ShouldPanicWith, "timeout must be positive")
+	})
+
+	Convey("Calling OptHealthServerPingers with nil pinger should panic", t, func() {
+		So(func() { OptHealthServerPingers(time.Second, time.Second, map[string]Pinger{"a": nil})(&c) }, ShouldPanicWith, "pinger for key 'a' must not be nil")
+	})
+
+	Convey("Calling OptHealthServerPingerTimeouts should work", t, func() {
+		timeouts := map[string]time.Duration{"a": time.Second}
+		OptHealthServerPingerTimeouts(timeouts)(&c)
+		So(c.healthServer.pingTimeouts, ShouldResemble, timeouts)
+	})
+
+	Convey("Calling OptHealthServerPingerTimeouts with invalid timeout should panic", t, func() {
+		So(func() { OptHealthServerPingerTimeouts(map[string]time.Duration{"a": 0})(&c) }, ShouldPanicWith, "timeout for key 'a' must be positive")
+	})
+
 	Convey("Calling OptHealthCustomStat should work", t, func() {
 		h := func(w http.ResponseWriter, r *http.Request) {}
 		OptHealthCustomStats(map[string]HealthStatFunc{
@@ -165,6 +199,49 @@ func TestBahamut_Options(t *testing.T) {
 		So(c.profilingServer.listenAddress, ShouldEqual, "1.2.3.4:123")
 	})
 
+	Convey("Calling OptProfilingRates should work", t, func() {
+		OptProfilingRates(1, 2)(&c)
+		So(c.profilingServer.ratesConfigured, ShouldBeTrue)
+		So(c.profilingServer.mutexProfileFraction, ShouldEqual, 1)
+		So(c.profilingServer.blockProfileRate, ShouldEqual, 2)
+	})
+
+	Convey("Calling OptProfilingRates with negative values should panic", t, func() {
+		So(func() { OptProfilingRates(-1, 0) }, ShouldPanicWith, "mutexProfileFraction must not be negative")
+		So(func() { OptProfilingRates(0, -1) }, ShouldPanicWith, "blockProfileRate must not be negative")
+	})
+
+	Convey("Calling OptProfilingCapture should work", t, func() {
+		OptProfilingCapture("/tmp/profiles", time.Minute, 10*time.Second, 5, time.Hour)(&c)
+		So(c.profilingServer.capture.enabled, ShouldBeTrue)
+		So(c.profilingServer.capture.directory, ShouldEqual, "/tmp/profiles")
+		So(c.profilingServer.capture.interval, ShouldEqual, time.Minute)
+		So(c.profilingServer.capture.cpuDuration, ShouldEqual, 10*time.Second)
+		So(c.profilingServer.capture.maxCount, ShouldEqual, 5)
+		So(c.profilingServer.capture.maxAge, ShouldEqual, time.Hour)
+	})
+
+	Convey("Calling OptProfilingCapture with invalid values should panic", t, func() {
+		So(func() { OptProfilingCapture("", 0, time.Second, 0, 0) }, ShouldPanicWith, "directory must not be empty")
+		So(func() { OptProfilingCapture("/tmp", -1, time.Second, 0, 0) }, ShouldPanicWith, "interval must not be negative")
+		So(func() { OptProfilingCapture("/tmp", 0, 0, 0, 0) }, ShouldPanicWith, "cpuDuration must be positive")
+		So(func() { OptProfilingCapture("/tmp", 0, time.Second, -1, 0) }, ShouldPanicWith, "maxCount must not be negative")
+		So(func() { OptProfilingCapture("/tmp", 0, time.Second, 0, -1) }, ShouldPanicWith, "maxAge must not be negative")
+	})
+
+	Convey("Calling OptProfilingTriggers should work", t, func() {
+		tr := NewGoroutinesProfilingTrigger(10)
+		OptProfilingTriggers(time.Second, time.Minute, tr)(&c)
+		So(c.profilingServer.capture.triggers, ShouldResemble, []ProfilingTrigger{tr})
+		So(c.profilingServer.capture.triggerInterval, ShouldEqual, time.Second)
+		So(c.profilingServer.capture.cooldown, ShouldEqual, time.Minute)
+	})
+
+	Convey("Calling OptProfilingTriggers with invalid values should panic", t, func() {
+		So(func() { OptProfilingTriggers(0, 0) }, ShouldPanicWith, "interval must be positive")
+		So(func() { OptProfilingTriggers(time.Second, -1) }, ShouldPanicWith, "cooldown must not be negative")
+	})
+
 	Convey("Calling OptTLS should work", t, func() {
 		certs := []tls.Certificate{}
 		r := func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return nil, nil }
@@ -211,6 +288,11 @@ func TestBahamut_Options(t *testing.T) {
 		So(c.security.auditer, ShouldEqual, a)
 	})
 
+	Convey("Calling OptAuthDebugClaim should work", t, func() {
+		OptAuthDebugClaim("@auth:debug=true")(&c)
+		So(c.security.authDebugClaim, ShouldEqual, "@auth:debug=true")
+	})
+
 	Convey("Calling OptRateLimiting should work", t, func() {
 		rlm := rate.NewLimiter(rate.Limit(10), 20)
 		OptRateLimiting(10, 20)(&c)
@@ -288,6 +370,65 @@ func TestBahamut_Options(t *testing.T) {
 		So(c.hooks.preStop, ShouldEqual, f)
 	})
 
+	Convey("Calling OptDrain should work", t, func() {
+		OptDrain(1*time.Second, 2*time.Second, 3*time.Second)(&c)
+		So(c.drain.enabled, ShouldBeTrue)
+		So(c.drain.preDrainDelay, ShouldEqual, 1*time.Second)
+		So(c.drain.timeout, ShouldEqual, 2*time.Second)
+		So(c.drain.reconnectDelay, ShouldEqual, 3*time.Second)
+	})
+
+	Convey("Calling OptDrain with a negative pre drain delay should panic", t, func() {
+		So(func() { OptDrain(-1, time.Second, 0) }, ShouldPanicWith, "preDrainDelay must not be negative")
+	})
+
+	Convey("Calling OptDrain with an invalid timeout should panic", t, func() {
+		So(func() { OptDrain(time.Second, 0, 0) }, ShouldPanicWith, "timeout must be positive")
+	})
+
+	Convey("Calling OptDrain with a negative reconnect delay should panic", t, func() {
+		So(func() { OptDrain(time.Second, time.Second, -1) }, ShouldPanicWith, "reconnectDelay must not be negative")
+	})
+
+	Convey("Calling OptAccessLog should work", t, func() {
+		OptAccessLog(0.5, time.Second)(&c)
+		So(c.accessLog.enabled, ShouldBeTrue)
+		So(c.accessLog.sampleRate, ShouldEqual, 0.5)
+		So(c.accessLog.slowThreshold, ShouldEqual, time.Second)
+	})
+
+	Convey("Calling OptAccessLog with invalid values should panic", t, func() {
+		So(func() { OptAccessLog(-0.1, 0) }, ShouldPanicWith, "sampleRate must be between 0 and 1")
+		So(func() { OptAccessLog(1.1, 0) }, ShouldPanicWith, "sampleRate must be between 0 and 1")
+		So(func() { OptAccessLog(1, -1) }, ShouldPanicWith, "slowThreshold must not be negative")
+	})
+
+	Convey("Calling OptAccessLogSlowThresholds should work", t, func() {
+		ident := elemental.MakeIdentity("thing", "things")
+		OptAccessLogSlowThresholds(map[elemental.Identity]time.Duration{ident: time.Minute})(&c)
+		So(c.accessLog.slowThresholds, ShouldResemble, map[elemental.Identity]time.Duration{ident: time.Minute})
+	})
+
+	Convey("Calling OptAccessLogSlowThresholds with a negative threshold should panic", t, func() {
+		ident := elemental.MakeIdentity("thing", "things")
+		So(
+			func() { OptAccessLogSlowThresholds(map[elemental.Identity]time.Duration{ident: -1}) },
+			ShouldPanicWith,
+			"threshold for identity 'thing' must not be negative",
+		)
+	})
+
+	Convey("Calling OptAccessLogRedactions should work", t, func() {
+		OptAccessLogRedactions([]string{"X-Secret"}, []string{"password"})(&c)
+		So(c.accessLog.redactedHeaders, ShouldResemble, []string{"X-Secret"})
+		So(c.accessLog.redactedAttributes, ShouldResemble, []string{"password"})
+	})
+
+	Convey("Calling OptAccessLogAttributes should work", t, func() {
+		OptAccessLogAttributes()(&c)
+		So(c.accessLog.attributes, ShouldBeTrue)
+	})
+
 	Convey("Calling OptTraceCleaner should work", t, func() {
 		f := func(elemental.Identity, []byte) []byte {
 			return nil
@@ -369,4 +510,20 @@ func TestBahamut_Options(t *testing.T) {
 		OptErrorTransformer(f)(&c)
 		So(c.hooks.errorTransformer, ShouldEqual, f)
 	})
+
+	Convey("Calling OptErrorReporter should work", t, func() {
+		r := &mockErrorReporter{}
+		OptErrorReporter(r, 10, 20, time.Minute)(&c)
+		So(c.hooks.errorReporter, ShouldNotBeNil)
+		So(c.hooks.errorReporter.reporter, ShouldEqual, r)
+		So(c.hooks.errorReporter.limiter.Limit(), ShouldEqual, rate.Limit(10))
+		So(c.hooks.errorReporter.limiter.Burst(), ShouldEqual, 20)
+		So(c.hooks.errorReporter.dedupWindow, ShouldEqual, time.Minute)
+	})
+
+	Convey("Calling OptErrorReporter with invalid values should panic", t, func() {
+		So(func() { OptErrorReporter(nil, 10, 20, time.Minute) }, ShouldPanicWith, "reporter must not be nil")
+		So(func() { OptErrorReporter(&mockErrorReporter{}, -1, 20, time.Minute) }, ShouldPanicWith, "limit must not be negative")
+		So(func() { OptErrorReporter(&mockErrorReporter{}, 10, 20, -1) }, ShouldPanicWith, "dedupWindow must not be negative")
+	})
 }
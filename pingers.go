@@ -0,0 +1,199 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// PingStatusDegraded represents the status of a health report
+// where only non critical Pingers are failing.
+const PingStatusDegraded = "degraded"
+
+// A PingerFunc is a function that implements the Pinger interface.
+type PingerFunc func(timeout time.Duration) error
+
+// Ping calls the function.
+func (f PingerFunc) Ping(timeout time.Duration) error {
+	return f(timeout)
+}
+
+// NewPubSubPinger returns a Pinger that reports the connection
+// status of the given PubSubClient.
+//
+// If the client does not implement the Pinger interface, like the
+// local PubSubClient, the returned Pinger always succeeds.
+func NewPubSubPinger(client PubSubClient) Pinger {
+
+	if client == nil {
+		panic("client must not be nil")
+	}
+
+	return PingerFunc(func(timeout time.Duration) error {
+
+		if p, ok := client.(Pinger); ok {
+			return p.Ping(timeout)
+		}
+
+		return nil
+	})
+}
+
+// NewTCPPinger returns a Pinger that succeeds
+// if it can open a TCP connection to the given address.
+func NewTCPPinger(address string) Pinger {
+
+	if address == "" {
+		panic("address must not be empty")
+	}
+
+	return PingerFunc(func(timeout time.Duration) error {
+
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	})
+}
+
+// NewHTTPPinger returns a Pinger that sends a GET request
+// to the given url using the given client and succeeds if the
+// response has the given expected status code.
+// If client is nil, http.DefaultClient will be used.
+func NewHTTPPinger(client *http.Client, url string, expectedStatus int) Pinger {
+
+	if url == "" {
+		panic("url must not be empty")
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return PingerFunc(func(timeout time.Duration) error {
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close() // nolint: errcheck
+
+		if resp.StatusCode != expectedStatus {
+			return fmt.Errorf("unexpected status code %d, expected %d", resp.StatusCode, expectedStatus)
+		}
+
+		return nil
+	})
+}
+
+// NewDiskPinger returns a Pinger that fails if the filesystem
+// containing the given path has less than minFree bytes available.
+func NewDiskPinger(path string, minFree uint64) Pinger {
+
+	if path == "" {
+		panic("path must not be empty")
+	}
+
+	return PingerFunc(func(time.Duration) error {
+
+		free, err := diskFree(path)
+		if err != nil {
+			return err
+		}
+
+		if free < minFree {
+			return fmt.Errorf("only %d bytes available on %s, expected at least %d", free, path, minFree)
+		}
+
+		return nil
+	})
+}
+
+// NewGoroutinesPinger returns a Pinger that fails if
+// the number of goroutines is greater than max.
+func NewGoroutinesPinger(max int) Pinger {
+
+	if max <= 0 {
+		panic("max must be positive")
+	}
+
+	return PingerFunc(func(time.Duration) error {
+
+		if n := runtime.NumGoroutine(); n > max {
+			return fmt.Errorf("%d goroutines running, expected at most %d", n, max)
+		}
+
+		return nil
+	})
+}
+
+// NewMemoryPinger returns a Pinger that fails if the
+// number of bytes of allocated heap objects is greater than max.
+func NewMemoryPinger(max uint64) Pinger {
+
+	if max == 0 {
+		panic("max must be positive")
+	}
+
+	return PingerFunc(func(time.Duration) error {
+
+		ms := runtime.MemStats{}
+		runtime.ReadMemStats(&ms)
+
+		if ms.HeapAlloc > max {
+			return fmt.Errorf("%d bytes of heap allocated, expected at most %d", ms.HeapAlloc, max)
+		}
+
+		return nil
+	})
+}
+
+// a nonCriticalPinger is a Pinger which failures
+// degrade the health report without failing readiness.
+type nonCriticalPinger struct {
+	Pinger
+}
+
+// NewNonCriticalPinger wraps the given Pinger so its failures
+// only degrade the health report served by the health server,
+// without making the server unhealthy or not ready.
+func NewNonCriticalPinger(pinger Pinger) Pinger {
+
+	if pinger == nil {
+		panic("pinger must not be nil")
+	}
+
+	return nonCriticalPinger{Pinger: pinger}
+}
+
+// isCritical returns true if the given pinger
+// has not been wrapped by NewNonCriticalPinger.
+func isCritical(pinger Pinger) bool {
+
+	_, ok := pinger.(nonCriticalPinger)
+	return !ok
+}
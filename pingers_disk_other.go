@@ -0,0 +1,25 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package bahamut
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// diskFree is not supported on this platform.
+func diskFree(path string) (uint64, error) {
+	return 0, fmt.Errorf("disk pinger is not supported on %s", runtime.GOOS)
+}
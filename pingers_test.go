@@ -0,0 +1,258 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type pingablePubSub struct {
+	PubSubClient
+	err error
+}
+
+func (p pingablePubSub) Ping(timeout time.Duration) error {
+	return p.err
+}
+
+func TestPingerFunc(t *testing.T) {
+
+	Convey("Given I have a PingerFunc", t, func() {
+
+		var called time.Duration
+		p := PingerFunc(func(timeout time.Duration) error {
+			called = timeout
+			return fmt.Errorf("boom")
+		})
+
+		Convey("When I call Ping", func() {
+
+			err := p.Ping(3 * time.Second)
+
+			Convey("Then the function should have been called", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "boom")
+				So(called, ShouldEqual, 3*time.Second)
+			})
+		})
+	})
+}
+
+func TestNewPubSubPinger(t *testing.T) {
+
+	Convey("Calling NewPubSubPinger with a nil client should panic", t, func() {
+		So(func() { NewPubSubPinger(nil) }, ShouldPanicWith, "client must not be nil")
+	})
+
+	Convey("Given I have a pubsub client that is not a pinger", t, func() {
+
+		p := NewPubSubPinger(NewLocalPubSubClient())
+
+		Convey("Then Ping should succeed", func() {
+			So(p.Ping(time.Second), ShouldBeNil)
+		})
+	})
+
+	Convey("Given I have a pubsub client that is a pinger", t, func() {
+
+		p := NewPubSubPinger(pingablePubSub{err: fmt.Errorf("reconnecting")})
+
+		Convey("Then Ping should return the client status", func() {
+			err := p.Ping(time.Second)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "reconnecting")
+		})
+	})
+}
+
+func TestNewTCPPinger(t *testing.T) {
+
+	Convey("Calling NewTCPPinger with an empty address should panic", t, func() {
+		So(func() { NewTCPPinger("") }, ShouldPanicWith, "address must not be empty")
+	})
+
+	Convey("Given I have a listening TCP server", t, func() {
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer ln.Close() // nolint: errcheck
+
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close() // nolint: errcheck
+			}
+		}()
+
+		Convey("Then Ping should succeed", func() {
+			So(NewTCPPinger(ln.Addr().String()).Ping(time.Second), ShouldBeNil)
+		})
+	})
+
+	Convey("Given I have no listening TCP server", t, func() {
+
+		port := freePort()
+
+		Convey("Then Ping should fail", func() {
+			So(NewTCPPinger(fmt.Sprintf("127.0.0.1:%d", port)).Ping(time.Second), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestNewHTTPPinger(t *testing.T) {
+
+	Convey("Calling NewHTTPPinger with an empty url should panic", t, func() {
+		So(func() { NewHTTPPinger(nil, "", http.StatusOK) }, ShouldPanicWith, "url must not be empty")
+	})
+
+	Convey("Given I have an HTTP server", t, func() {
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(300 * time.Millisecond)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer ts.Close()
+
+		Convey("When I ping it with the expected status", func() {
+
+			err := NewHTTPPinger(nil, ts.URL, http.StatusNoContent).Ping(time.Second)
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When I ping it with another expected status", func() {
+
+			err := NewHTTPPinger(ts.Client(), ts.URL, http.StatusOK).Ping(time.Second)
+
+			Convey("Then err should be correct", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unexpected status code 204, expected 200")
+			})
+		})
+
+		Convey("When I ping it with a short timeout", func() {
+
+			err := NewHTTPPinger(nil, ts.URL+"/slow", http.StatusNoContent).Ping(10 * time.Millisecond)
+
+			Convey("Then err should be a timeout", func() {
+				So(err, ShouldNotBeNil)
+				So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestNewDiskPinger(t *testing.T) {
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("disk pinger is not supported on " + runtime.GOOS)
+	}
+
+	Convey("Calling NewDiskPinger with an empty path should panic", t, func() {
+		So(func() { NewDiskPinger("", 1) }, ShouldPanicWith, "path must not be empty")
+	})
+
+	Convey("Given I have a disk pinger with a low threshold", t, func() {
+
+		p := NewDiskPinger(".", 1)
+
+		Convey("Then Ping should succeed", func() {
+			So(p.Ping(time.Second), ShouldBeNil)
+		})
+	})
+
+	Convey("Given I have a disk pinger with a huge threshold", t, func() {
+
+		p := NewDiskPinger(".", ^uint64(0))
+
+		Convey("Then Ping should fail", func() {
+			So(p.Ping(time.Second), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given I have a disk pinger on a path that does not exist", t, func() {
+
+		p := NewDiskPinger("/does/not/exist", 1)
+
+		Convey("Then Ping should fail", func() {
+			So(p.Ping(time.Second), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestNewGoroutinesPinger(t *testing.T) {
+
+	Convey("Calling NewGoroutinesPinger with an invalid max should panic", t, func() {
+		So(func() { NewGoroutinesPinger(0) }, ShouldPanicWith, "max must be positive")
+	})
+
+	Convey("Given I have goroutines pingers", t, func() {
+
+		Convey("Then Ping should be correct", func() {
+			So(NewGoroutinesPinger(1000000).Ping(time.Second), ShouldBeNil)
+			So(NewGoroutinesPinger(1).Ping(time.Second), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestNewMemoryPinger(t *testing.T) {
+
+	Convey("Calling NewMemoryPinger with an invalid max should panic", t, func() {
+		So(func() { NewMemoryPinger(0) }, ShouldPanicWith, "max must be positive")
+	})
+
+	Convey("Given I have memory pingers", t, func() {
+
+		Convey("Then Ping should be correct", func() {
+			So(NewMemoryPinger(^uint64(0)).Ping(time.Second), ShouldBeNil)
+			So(NewMemoryPinger(1).Ping(time.Second), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestNewNonCriticalPinger(t *testing.T) {
+
+	Convey("Calling NewNonCriticalPinger with a nil pinger should panic", t, func() {
+		So(func() { NewNonCriticalPinger(nil) }, ShouldPanicWith, "pinger must not be nil")
+	})
+
+	Convey("Given I have a non critical pinger", t, func() {
+
+		p := NewNonCriticalPinger(MockPinger{PingStatus: fmt.Errorf("boom")})
+
+		Convey("Then it should not be critical", func() {
+			So(isCritical(p), ShouldBeFalse)
+			So(isCritical(MockPinger{}), ShouldBeTrue)
+		})
+
+		Convey("Then Ping should call the wrapped pinger", func() {
+			So(p.Ping(time.Second), ShouldNotBeNil)
+		})
+	})
+}
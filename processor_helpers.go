@@ -13,6 +13,7 @@ package bahamut
 
 import (
 	"net/http"
+	"time"
 
 	"go.aporeto.io/elemental"
 )
@@ -22,6 +23,8 @@ import (
 // If it is not authenticated it stops the normal processing execution flow, and will write the Unauthorized response to the given writer.
 // If not Authenticator is set, then it will always return true.
 //
+// The decision of each consulted authenticator is recorded and can be retrieved using AuthDecisions.
+//
 // This is mostly used by autogenerated code, and you should not need to use it manually.
 func CheckAuthentication(authenticators []RequestAuthenticator, ctx Context) (err error) {
 
@@ -29,10 +32,15 @@ func CheckAuthentication(authenticators []RequestAuthenticator, ctx Context) (er
 		return nil
 	}
 
+	if bctx, ok := ctx.(*bcontext); ok {
+		defer bctx.addTiming(&bctx.timings.authentication, time.Now())
+	}
+
 	var action AuthAction
 	for _, authenticator := range authenticators {
 
 		action, err = authenticator.AuthenticateRequest(ctx)
+		recordAuthDecision(ctx, AuthStageAuthentication, authenticator, action, err)
 		if err != nil {
 			return err
 		}
@@ -55,6 +63,8 @@ func CheckAuthentication(authenticators []RequestAuthenticator, ctx Context) (er
 // If it is not authorized it stops the normal processing execution flow, and will write the Unauthorized response to the given writer.
 // If not Authorizer is set, then it will always return true.
 //
+// The decision of each consulted authorizer is recorded and can be retrieved using AuthDecisions.
+//
 // This is mostly used by autogenerated code, and you should not need to use it manually.
 func CheckAuthorization(authorizers []Authorizer, ctx Context) (err error) {
 
@@ -62,10 +72,15 @@ func CheckAuthorization(authorizers []Authorizer, ctx Context) (err error) {
 		return nil
 	}
 
+	if bctx, ok := ctx.(*bcontext); ok {
+		defer bctx.addTiming(&bctx.timings.authorization, time.Now())
+	}
+
 	var action AuthAction
 	for _, authorizer := range authorizers {
 
 		action, err = authorizer.IsAuthorized(ctx)
+		recordAuthDecision(ctx, AuthStageAuthorization, authorizer, action, err)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,462 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// The names of the profiles written in each profiling capture.
+var captureProfiles = []string{"heap", "mutex", "goroutine"}
+
+var captureReasonSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// The errors returned when a capture is requested too often.
+var (
+	errProfilingCaptureRunning  = errors.New("a profiling capture is already running")
+	errProfilingCaptureCooldown = errors.New("a profiling capture has been made during the cooldown")
+)
+
+// A ProfilingCapture describes a profiling capture
+// stored by the profiling server.
+type ProfilingCapture struct {
+	Name  string    `json:"name"`
+	Time  time.Time `json:"time"`
+	Files []string  `json:"files"`
+}
+
+// A profiler captures profiles periodically or when one of
+// its triggers fires, and stores them in a directory.
+type profiler struct {
+	directory       string
+	interval        time.Duration
+	cpuDuration     time.Duration
+	maxCount        int
+	maxAge          time.Duration
+	triggers        []ProfilingTrigger
+	triggerInterval time.Duration
+	cooldown        time.Duration
+
+	lastCapture time.Time
+	lock        sync.Mutex
+	captureLock sync.Mutex
+}
+
+// newProfiler returns a new profiler configured from the given config.
+func newProfiler(cfg config) *profiler {
+
+	c := cfg.profilingServer.capture
+
+	return &profiler{
+		directory:       c.directory,
+		interval:        c.interval,
+		cpuDuration:     c.cpuDuration,
+		maxCount:        c.maxCount,
+		maxAge:          c.maxAge,
+		triggers:        c.triggers,
+		triggerInterval: c.triggerInterval,
+		cooldown:        c.cooldown,
+	}
+}
+
+// run captures the profiles periodically and checks the
+// triggers until the given context is canceled.
+func (p *profiler) run(ctx context.Context) {
+
+	if err := os.MkdirAll(p.directory, 0700); err != nil {
+		zap.L().Error("Unable to create profiling capture directory", zap.String("directory", p.directory), zap.Error(err))
+		return
+	}
+
+	var intervalCh, triggerCh <-chan time.Time
+
+	if p.interval > 0 {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		intervalCh = ticker.C
+	}
+
+	if len(p.triggers) > 0 {
+		ticker := time.NewTicker(p.triggerInterval)
+		defer ticker.Stop()
+		triggerCh = ticker.C
+	}
+
+	for {
+		select {
+
+		case <-intervalCh:
+			p.captureAndLog(ctx, "periodic")
+
+		case <-triggerCh:
+			if reason, ok := p.check(); ok {
+				p.captureAndLog(ctx, reason)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check checks the triggers and returns the reason of the first
+// one that fired, unless a capture has been made during the cooldown.
+func (p *profiler) check() (string, bool) {
+
+	for _, t := range p.triggers {
+
+		reason, ok := t.ShouldCapture()
+		if !ok {
+			continue
+		}
+
+		p.lock.Lock()
+		last := p.lastCapture
+		p.lock.Unlock()
+
+		if time.Since(last) < p.cooldown {
+			return "", false
+		}
+
+		return reason, true
+	}
+
+	return "", false
+}
+
+// captureAndLog captures the profiles and logs the result.
+func (p *profiler) captureAndLog(ctx context.Context, reason string) {
+
+	capture, err := p.capture(ctx, reason)
+	logCapture(reason, capture, err)
+}
+
+// tryCaptureAndLog starts capturing the profiles in the background
+// and logs the result. It returns errProfilingCaptureRunning if a
+// capture is already running, or errProfilingCaptureCooldown if a
+// capture has been made during the cooldown.
+func (p *profiler) tryCaptureAndLog(ctx context.Context, reason string) error {
+
+	if !p.captureLock.TryLock() {
+		return errProfilingCaptureRunning
+	}
+
+	p.lock.Lock()
+	last := p.lastCapture
+	p.lock.Unlock()
+
+	if time.Since(last) < p.cooldown {
+		p.captureLock.Unlock()
+		return errProfilingCaptureCooldown
+	}
+
+	go func() {
+		defer p.captureLock.Unlock()
+
+		capture, err := p.captureLocked(ctx, reason)
+		logCapture(reason, capture, err)
+	}()
+
+	return nil
+}
+
+// capture captures a CPU profile for the configured duration, then the heap,
+// mutex and goroutine profiles, and applies the retention policy. Only one
+// capture can run at a time.
+func (p *profiler) capture(ctx context.Context, reason string) (ProfilingCapture, error) {
+
+	p.captureLock.Lock()
+	defer p.captureLock.Unlock()
+
+	return p.captureLocked(ctx, reason)
+}
+
+// captureLocked is capture without the locking.
+// The caller must hold the captureLock.
+func (p *profiler) captureLocked(ctx context.Context, reason string) (ProfilingCapture, error) {
+
+	now := time.Now()
+
+	p.lock.Lock()
+	p.lastCapture = now
+	p.lock.Unlock()
+
+	capture := ProfilingCapture{
+		Name: now.UTC().Format("20060102T150405.000") + "-" + captureReasonSanitizer.ReplaceAllString(reason, "_"),
+		Time: now,
+	}
+
+	dir := filepath.Join(p.directory, capture.Name)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return capture, err
+	}
+
+	// The CPU profile may fail to start if another one is
+	// running, for instance from the pprof endpoint.
+	// In that case, we still capture the other profiles.
+	if err := writeProfileFile(filepath.Join(dir, "cpu.pprof"), func(f *os.File) error {
+
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(p.cpuDuration):
+		case <-ctx.Done():
+		}
+
+		pprof.StopCPUProfile()
+
+		return nil
+	}); err != nil {
+		zap.L().Warn("Unable to capture CPU profile", zap.Error(err))
+	} else {
+		capture.Files = append(capture.Files, "cpu.pprof")
+	}
+
+	for _, name := range captureProfiles {
+
+		file := name + ".pprof"
+
+		if err := writeProfileFile(filepath.Join(dir, file), func(f *os.File) error {
+			return pprof.Lookup(name).WriteTo(f, 0)
+		}); err != nil {
+			return capture, err
+		}
+
+		capture.Files = append(capture.Files, file)
+	}
+
+	if err := p.cleanup(); err != nil {
+		zap.L().Warn("Unable to apply profiling capture retention", zap.Error(err))
+	}
+
+	return capture, nil
+}
+
+// logCapture logs the result of a capture.
+func logCapture(reason string, capture ProfilingCapture, err error) {
+
+	if err != nil {
+		zap.L().Error("Unable to capture profiles", zap.String("reason", reason), zap.Error(err))
+		return
+	}
+
+	zap.L().Info("Profiles captured", zap.String("reason", reason), zap.String("capture", capture.Name))
+}
+
+// captures returns the stored captures, from the oldest to the newest.
+func (p *profiler) captures() ([]ProfilingCapture, error) {
+
+	entries, err := ioutil.ReadDir(p.directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ProfilingCapture{}, nil
+		}
+		return nil, err
+	}
+
+	captures := make([]ProfilingCapture, 0, len(entries))
+
+	for _, entry := range entries {
+
+		if !entry.IsDir() {
+			continue
+		}
+
+		files, err := ioutil.ReadDir(filepath.Join(p.directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		c := ProfilingCapture{
+			Name:  entry.Name(),
+			Time:  entry.ModTime(),
+			Files: make([]string, 0, len(files)),
+		}
+
+		for _, f := range files {
+			c.Files = append(c.Files, f.Name())
+		}
+
+		captures = append(captures, c)
+	}
+
+	sort.Slice(captures, func(i, j int) bool { return captures[i].Name < captures[j].Name })
+
+	return captures, nil
+}
+
+// cleanup removes the captures older than maxAge
+// and the oldest ones beyond maxCount.
+func (p *profiler) cleanup() error {
+
+	captures, err := p.captures()
+	if err != nil {
+		return err
+	}
+
+	for i, c := range captures {
+
+		tooMany := p.maxCount > 0 && len(captures)-i > p.maxCount
+		tooOld := p.maxAge > 0 && time.Since(c.Time) > p.maxAge
+
+		if !tooMany && !tooOld {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(p.directory, c.Name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeProfileFile creates the file at the given
+// path and calls the given function to fill it.
+func writeProfileFile(path string, write func(*os.File) error) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()       // nolint: errcheck
+		os.Remove(path) // nolint: errcheck
+		return err
+	}
+
+	return f.Close()
+}
+
+// A ProfilingTrigger decides when the profiling
+// server must capture the profiles.
+type ProfilingTrigger interface {
+
+	// ShouldCapture returns true and the reason of the
+	// capture if the profiles must be captured.
+	ShouldCapture() (string, bool)
+}
+
+// A latencyObserver is a ProfilingTrigger that
+// must receive the latency of the API requests.
+type latencyObserver interface {
+	observeLatency(time.Duration)
+}
+
+// goroutinesProfilingTrigger fires when there
+// are too many goroutines running.
+type goroutinesProfilingTrigger struct {
+	max int
+}
+
+// NewGoroutinesProfilingTrigger returns a ProfilingTrigger that fires
+// when the number of goroutines is greater than max.
+func NewGoroutinesProfilingTrigger(max int) ProfilingTrigger {
+
+	if max <= 0 {
+		panic("max must be positive")
+	}
+
+	return &goroutinesProfilingTrigger{max: max}
+}
+
+func (t *goroutinesProfilingTrigger) ShouldCapture() (string, bool) {
+
+	if n := runtime.NumGoroutine(); n > t.max {
+		return fmt.Sprintf("goroutines-%d", n), true
+	}
+
+	return "", false
+}
+
+// latencyProfilingTrigger fires when the p99 latency of the
+// API requests received since the last check is too high.
+type latencyProfilingTrigger struct {
+	threshold  time.Duration
+	minSamples int
+	samples    []time.Duration
+	next       int
+	lock       sync.Mutex
+}
+
+// NewLatencyProfilingTrigger returns a ProfilingTrigger that fires when the
+// p99 latency of the API requests received since its last check is greater
+// than the given threshold. It needs at least minSamples requests to fire,
+// and only keeps the latencies of the last 1024 requests.
+func NewLatencyProfilingTrigger(threshold time.Duration, minSamples int) ProfilingTrigger {
+
+	if threshold <= 0 {
+		panic("threshold must be positive")
+	}
+
+	if minSamples <= 0 || minSamples > 1024 {
+		panic("minSamples must be between 1 and 1024")
+	}
+
+	return &latencyProfilingTrigger{
+		threshold:  threshold,
+		minSamples: minSamples,
+		samples:    make([]time.Duration, 0, 1024),
+	}
+}
+
+func (t *latencyProfilingTrigger) observeLatency(d time.Duration) {
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.samples) < cap(t.samples) {
+		t.samples = append(t.samples, d)
+		return
+	}
+
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % len(t.samples)
+}
+
+func (t *latencyProfilingTrigger) ShouldCapture() (string, bool) {
+
+	t.lock.Lock()
+	samples := append([]time.Duration{}, t.samples...)
+	t.samples = t.samples[:0]
+	t.next = 0
+	t.lock.Unlock()
+
+	if len(samples) < t.minSamples {
+		return "", false
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	p99 := samples[(len(samples)*99-1)/100]
+	if p99 > t.threshold {
+		return fmt.Sprintf("latency-p99-%s", p99), true
+	}
+
+	return "", false
+}
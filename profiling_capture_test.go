@@ -0,0 +1,323 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockProfilingTrigger struct {
+	reason string
+	fire   bool
+}
+
+func (t *mockProfilingTrigger) ShouldCapture() (string, bool) {
+	return t.reason, t.fire
+}
+
+func newTestProfiler(dir string) *profiler {
+
+	cfg := config{}
+	cfg.profilingServer.capture.directory = dir
+	cfg.profilingServer.capture.cpuDuration = 10 * time.Millisecond
+
+	return newProfiler(cfg)
+}
+
+func TestProfiler_capture(t *testing.T) {
+
+	Convey("Given I have a profiler", t, func() {
+
+		dir, err := ioutil.TempDir("", "bahamut-profiling")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		p := newTestProfiler(dir)
+
+		Convey("When I capture the profiles", func() {
+
+			c, err := p.capture(context.Background(), "latency p99/1s")
+
+			Convey("Then err should be nil", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("Then the capture should be correct", func() {
+				So(strings.HasSuffix(c.Name, "-latency_p99_1s"), ShouldBeTrue)
+				So(c.Files, ShouldResemble, []string{"cpu.pprof", "heap.pprof", "mutex.pprof", "goroutine.pprof"})
+
+				for _, f := range c.Files {
+					info, err := os.Stat(filepath.Join(dir, c.Name, f))
+					So(err, ShouldBeNil)
+					So(info.Size(), ShouldBeGreaterThan, 0)
+				}
+			})
+
+			Convey("Then the capture should be listed", func() {
+				captures, err := p.captures()
+				So(err, ShouldBeNil)
+				So(len(captures), ShouldEqual, 1)
+				So(captures[0].Name, ShouldEqual, c.Name)
+				So(len(captures[0].Files), ShouldEqual, 4)
+			})
+		})
+	})
+
+	Convey("Given I have a profiler with a directory that does not exist", t, func() {
+
+		p := newTestProfiler("/does/not/exist")
+
+		Convey("When I list the captures", func() {
+
+			captures, err := p.captures()
+
+			Convey("Then the list should be empty", func() {
+				So(err, ShouldBeNil)
+				So(len(captures), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestProfiler_cleanup(t *testing.T) {
+
+	Convey("Given I have a profiler with a directory containing captures", t, func() {
+
+		dir, err := ioutil.TempDir("", "bahamut-profiling")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		for _, name := range []string{"20200101T000000.000-a", "20200101T000001.000-b", "20200101T000002.000-c"} {
+			So(os.Mkdir(filepath.Join(dir, name), 0700), ShouldBeNil)
+		}
+
+		p := newTestProfiler(dir)
+
+		Convey("When I cleanup with a max count", func() {
+
+			p.maxCount = 2
+			So(p.cleanup(), ShouldBeNil)
+
+			Convey("Then the oldest capture should be removed", func() {
+				captures, err := p.captures()
+				So(err, ShouldBeNil)
+				So(len(captures), ShouldEqual, 2)
+				So(captures[0].Name, ShouldEqual, "20200101T000001.000-b")
+			})
+		})
+
+		Convey("When I cleanup with a max age", func() {
+
+			old := time.Now().Add(-time.Hour)
+			So(os.Chtimes(filepath.Join(dir, "20200101T000000.000-a"), old, old), ShouldBeNil)
+
+			p.maxAge = time.Minute
+			So(p.cleanup(), ShouldBeNil)
+
+			Convey("Then the old capture should be removed", func() {
+				captures, err := p.captures()
+				So(err, ShouldBeNil)
+				So(len(captures), ShouldEqual, 2)
+				So(captures[0].Name, ShouldEqual, "20200101T000001.000-b")
+			})
+		})
+
+		Convey("When I cleanup with no retention", func() {
+
+			So(p.cleanup(), ShouldBeNil)
+
+			Convey("Then no capture should be removed", func() {
+				captures, err := p.captures()
+				So(err, ShouldBeNil)
+				So(len(captures), ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func TestProfiler_check(t *testing.T) {
+
+	Convey("Given I have a profiler with triggers", t, func() {
+
+		t1 := &mockProfilingTrigger{}
+		t2 := &mockProfilingTrigger{reason: "t2"}
+
+		p := newTestProfiler("")
+		p.triggers = []ProfilingTrigger{t1, t2}
+		p.cooldown = time.Hour
+
+		Convey("When no trigger fires", func() {
+
+			_, ok := p.check()
+
+			Convey("Then no capture should be triggered", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When a trigger fires", func() {
+
+			t2.fire = true
+			reason, ok := p.check()
+
+			Convey("Then a capture should be triggered", func() {
+				So(ok, ShouldBeTrue)
+				So(reason, ShouldEqual, "t2")
+			})
+		})
+
+		Convey("When a trigger fires during the cooldown", func() {
+
+			t2.fire = true
+			p.lastCapture = time.Now()
+			_, ok := p.check()
+
+			Convey("Then no capture should be triggered", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestProfiler_run(t *testing.T) {
+
+	Convey("Given I have a running profiler with a firing trigger", t, func() {
+
+		dir, err := ioutil.TempDir("", "bahamut-profiling")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		p := newTestProfiler(filepath.Join(dir, "captures"))
+		p.triggers = []ProfilingTrigger{&mockProfilingTrigger{reason: "test", fire: true}}
+		p.triggerInterval = 10 * time.Millisecond
+		p.cooldown = time.Hour
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go p.run(ctx)
+
+		Convey("Then exactly one capture should be made", func() {
+			time.Sleep(300 * time.Millisecond)
+			captures, err := p.captures()
+			So(err, ShouldBeNil)
+			So(len(captures), ShouldEqual, 1)
+			So(strings.HasSuffix(captures[0].Name, "-test"), ShouldBeTrue)
+		})
+	})
+}
+
+func TestGoroutinesProfilingTrigger(t *testing.T) {
+
+	Convey("Calling NewGoroutinesProfilingTrigger with an invalid max should panic", t, func() {
+		So(func() { NewGoroutinesProfilingTrigger(0) }, ShouldPanicWith, "max must be positive")
+	})
+
+	Convey("Given I have goroutines triggers", t, func() {
+
+		Convey("Then they should fire correctly", func() {
+			_, ok := NewGoroutinesProfilingTrigger(1000000).ShouldCapture()
+			So(ok, ShouldBeFalse)
+
+			reason, ok := NewGoroutinesProfilingTrigger(1).ShouldCapture()
+			So(ok, ShouldBeTrue)
+			So(reason, ShouldStartWith, "goroutines-")
+		})
+	})
+}
+
+func TestLatencyProfilingTrigger(t *testing.T) {
+
+	Convey("Calling NewLatencyProfilingTrigger with an invalid threshold should panic", t, func() {
+		So(func() { NewLatencyProfilingTrigger(0, 1) }, ShouldPanicWith, "threshold must be positive")
+	})
+
+	Convey("Calling NewLatencyProfilingTrigger with an invalid minSamples should panic", t, func() {
+		So(func() { NewLatencyProfilingTrigger(time.Second, 0) }, ShouldPanicWith, "minSamples must be between 1 and 1024")
+		So(func() { NewLatencyProfilingTrigger(time.Second, 1025) }, ShouldPanicWith, "minSamples must be between 1 and 1024")
+	})
+
+	Convey("Given I have a latency trigger", t, func() {
+
+		tr := NewLatencyProfilingTrigger(100*time.Millisecond, 10)
+		o := tr.(latencyObserver)
+
+		Convey("When there are not enough samples", func() {
+
+			o.observeLatency(time.Second)
+			_, ok := tr.ShouldCapture()
+
+			Convey("Then it should not fire", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When the p99 latency is below the threshold", func() {
+
+			for i := 0; i < 100; i++ {
+				o.observeLatency(time.Millisecond)
+			}
+			o.observeLatency(time.Second)
+
+			_, ok := tr.ShouldCapture()
+
+			Convey("Then it should not fire", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When the p99 latency is above the threshold", func() {
+
+			for i := 0; i < 98; i++ {
+				o.observeLatency(time.Millisecond)
+			}
+			o.observeLatency(time.Second)
+			o.observeLatency(time.Second)
+
+			reason, ok := tr.ShouldCapture()
+
+			Convey("Then it should fire", func() {
+				So(ok, ShouldBeTrue)
+				So(reason, ShouldEqual, "latency-p99-1s")
+			})
+
+			Convey("Then the samples should be reset", func() {
+				_, ok := tr.ShouldCapture()
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("When more samples than the window are observed", func() {
+
+			for i := 0; i < 2000; i++ {
+				o.observeLatency(time.Second)
+			}
+			for i := 0; i < 1024; i++ {
+				o.observeLatency(time.Millisecond)
+			}
+
+			_, ok := tr.ShouldCapture()
+
+			Convey("Then only the last samples should be considered", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
@@ -15,23 +15,46 @@ import (
 	"context"
 	"net/http"
 	"net/http/pprof"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+// profilingRates holds the profile rates of the runtime.
+type profilingRates struct {
+	MutexProfileFraction int `json:"mutexProfileFraction"`
+	BlockProfileRate     int `json:"blockProfileRate"`
+}
+
 // an profilingServer is the structure serving the profiling.
 type profilingServer struct {
-	cfg    config
-	server *http.Server
+	cfg      config
+	server   *http.Server
+	profiler *profiler
+	ctx      context.Context
+
+	// The runtime has no getter for the block profile rate.
+	blockProfileRate int
+	ratesLock        sync.Mutex
 }
 
 // newProfilingServer returns a new profilingServer.
 func newProfilingServer(cfg config) *profilingServer {
 
-	return &profilingServer{
+	s := &profilingServer{
 		cfg: cfg,
 	}
+
+	if cfg.profilingServer.capture.enabled {
+		s.profiler = newProfiler(cfg)
+	}
+
+	return s
 }
 
 // start starts the profilingServer.
@@ -42,6 +65,21 @@ func (s *profilingServer) start(ctx context.Context) {
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/profiling/rates", s.handleRates)
+
+	if s.cfg.profilingServer.ratesConfigured {
+		s.setRates(profilingRates{
+			MutexProfileFraction: s.cfg.profilingServer.mutexProfileFraction,
+			BlockProfileRate:     s.cfg.profilingServer.blockProfileRate,
+		})
+	}
+
+	if s.profiler != nil {
+		s.ctx = ctx
+		mux.HandleFunc("/debug/profiling/captures", s.handleCaptures)
+		mux.HandleFunc("/debug/profiling/captures/", s.handleCaptureFile)
+		go s.profiler.run(ctx)
+	}
 
 	s.server = &http.Server{
 		Addr:    s.cfg.profilingServer.listenAddress,
@@ -82,3 +120,126 @@ func (s *profilingServer) stop() {
 
 	zap.L().Debug("Profile server stopped")
 }
+
+// rates returns the current profile rates.
+func (s *profilingServer) rates() profilingRates {
+
+	s.ratesLock.Lock()
+	defer s.ratesLock.Unlock()
+
+	return profilingRates{
+		MutexProfileFraction: runtime.SetMutexProfileFraction(-1),
+		BlockProfileRate:     s.blockProfileRate,
+	}
+}
+
+// setRates sets the profile rates of the runtime.
+func (s *profilingServer) setRates(rates profilingRates) {
+
+	s.ratesLock.Lock()
+	defer s.ratesLock.Unlock()
+
+	runtime.SetMutexProfileFraction(rates.MutexProfileFraction)
+	runtime.SetBlockProfileRate(rates.BlockProfileRate)
+	s.blockProfileRate = rates.BlockProfileRate
+}
+
+// handleRates returns the profile rates on GET, and sets
+// them from the `mutex` and `block` query parameters on PUT.
+func (s *profilingServer) handleRates(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+
+	case http.MethodGet:
+
+	case http.MethodPut:
+
+		rates := s.rates()
+
+		for key, dest := range map[string]*int{
+			"mutex": &rates.MutexProfileFraction,
+			"block": &rates.BlockProfileRate,
+		} {
+			v := r.URL.Query().Get(key)
+			if v == "" {
+				continue
+			}
+
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "Invalid "+key+" rate", http.StatusBadRequest)
+				return
+			}
+
+			*dest = n
+		}
+
+		s.setRates(rates)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.rates())
+}
+
+// handleCaptures lists the captures on GET, and triggers a new
+// capture on POST unless one is running or has been made during
+// the cooldown.
+func (s *profilingServer) handleCaptures(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+
+	case http.MethodGet:
+
+		captures, err := s.profiler.captures()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, captures)
+
+	case http.MethodPost:
+
+		switch err := s.profiler.tryCaptureAndLog(s.ctx, "manual"); err {
+		case nil:
+			w.WriteHeader(http.StatusAccepted)
+		case errProfilingCaptureRunning:
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		}
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCaptureFile downloads a file of a capture,
+// from the path `/debug/profiling/captures/<capture>/<file>`.
+func (s *profilingServer) handleCaptureFile(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/debug/profiling/captures/"), "/")
+	if len(parts) != 2 || !isCapturePathElement(parts[0]) || !isCapturePathElement(parts[1]) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+parts[0]+"-"+parts[1]+"\"")
+
+	http.ServeFile(w, r, filepath.Join(s.profiler.directory, parts[0], parts[1]))
+}
+
+// isCapturePathElement returns true if the given
+// path element is safe to use inside the capture directory.
+func isCapturePathElement(e string) bool {
+	return e != "" && e != "." && e != ".." && !strings.ContainsAny(e, `/\`)
+}
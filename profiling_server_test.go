@@ -0,0 +1,206 @@
+// Copyright 2019 Aporeto Inc.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bahamut
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProfilingServer_rates(t *testing.T) {
+
+	Convey("Given I have a profiling server with configured rates", t, func() {
+
+		cfg := config{}
+		cfg.profilingServer.listenAddress = "127.0.0.1:0"
+		cfg.profilingServer.ratesConfigured = true
+		cfg.profilingServer.mutexProfileFraction = 5
+		cfg.profilingServer.blockProfileRate = 10
+
+		s := newProfilingServer(cfg)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// start returns immediately as the context is canceled.
+		s.start(ctx)
+		defer s.stop()
+		defer s.setRates(profilingRates{})
+
+		Convey("When I get the rates", func() {
+
+			w := httptest.NewRecorder()
+			s.handleRates(w, httptest.NewRequest(http.MethodGet, "/debug/profiling/rates", nil))
+
+			Convey("Then the rates should be correct", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				rates := profilingRates{}
+				So(json.NewDecoder(w.Body).Decode(&rates), ShouldBeNil)
+				So(rates.MutexProfileFraction, ShouldEqual, 5)
+				So(rates.BlockProfileRate, ShouldEqual, 10)
+			})
+		})
+
+		Convey("When I set the mutex rate", func() {
+
+			w := httptest.NewRecorder()
+			s.handleRates(w, httptest.NewRequest(http.MethodPut, "/debug/profiling/rates?mutex=2", nil))
+
+			Convey("Then the rates should be correct", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				rates := profilingRates{}
+				So(json.NewDecoder(w.Body).Decode(&rates), ShouldBeNil)
+				So(rates.MutexProfileFraction, ShouldEqual, 2)
+				So(rates.BlockProfileRate, ShouldEqual, 10)
+				So(runtime.SetMutexProfileFraction(-1), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When I set an invalid rate", func() {
+
+			w := httptest.NewRecorder()
+			s.handleRates(w, httptest.NewRequest(http.MethodPut, "/debug/profiling/rates?block=-1", nil))
+
+			Convey("Then the request should be rejected", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(s.rates().BlockProfileRate, ShouldEqual, 10)
+			})
+		})
+
+		Convey("When I delete the rates", func() {
+
+			w := httptest.NewRecorder()
+			s.handleRates(w, httptest.NewRequest(http.MethodDelete, "/debug/profiling/rates", nil))
+
+			Convey("Then the request should be rejected", func() {
+				So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			})
+		})
+	})
+}
+
+func TestProfilingServer_captures(t *testing.T) {
+
+	Convey("Given I have a profiling server with capture enabled", t, func() {
+
+		dir, err := ioutil.TempDir("", "bahamut-profiling")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir) // nolint: errcheck
+
+		cfg := config{}
+		cfg.profilingServer.listenAddress = "127.0.0.1:0"
+		cfg.profilingServer.capture.enabled = true
+		cfg.profilingServer.capture.directory = dir
+		cfg.profilingServer.capture.cpuDuration = 10 * time.Millisecond
+
+		s := newProfilingServer(cfg)
+		s.ctx = context.Background()
+
+		Convey("When I trigger a capture and list the captures", func() {
+
+			w := httptest.NewRecorder()
+			s.handleCaptures(w, httptest.NewRequest(http.MethodPost, "/debug/profiling/captures", nil))
+			So(w.Code, ShouldEqual, http.StatusAccepted)
+
+			time.Sleep(300 * time.Millisecond)
+
+			w = httptest.NewRecorder()
+			s.handleCaptures(w, httptest.NewRequest(http.MethodGet, "/debug/profiling/captures", nil))
+
+			captures := []ProfilingCapture{}
+			So(json.NewDecoder(w.Body).Decode(&captures), ShouldBeNil)
+
+			Convey("Then the capture should be listed", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(len(captures), ShouldEqual, 1)
+				So(len(captures[0].Files), ShouldEqual, 4)
+			})
+
+			Convey("When I download a file of the capture", func() {
+
+				w := httptest.NewRecorder()
+				s.handleCaptureFile(w, httptest.NewRequest(http.MethodGet, "/debug/profiling/captures/"+captures[0].Name+"/heap.pprof", nil))
+
+				Convey("Then the file should be downloaded", func() {
+					data, err := ioutil.ReadFile(filepath.Join(dir, captures[0].Name, "heap.pprof"))
+					So(err, ShouldBeNil)
+					So(w.Code, ShouldEqual, http.StatusOK)
+					So(w.Body.Bytes(), ShouldResemble, data)
+				})
+			})
+		})
+
+		Convey("When I trigger a capture while one is running", func() {
+
+			w1 := httptest.NewRecorder()
+			s.handleCaptures(w1, httptest.NewRequest(http.MethodPost, "/debug/profiling/captures", nil))
+
+			w2 := httptest.NewRecorder()
+			s.handleCaptures(w2, httptest.NewRequest(http.MethodPost, "/debug/profiling/captures", nil))
+
+			time.Sleep(300 * time.Millisecond)
+
+			Convey("Then the second capture should be rejected", func() {
+				So(w1.Code, ShouldEqual, http.StatusAccepted)
+				So(w2.Code, ShouldEqual, http.StatusConflict)
+			})
+		})
+
+		Convey("When I trigger a capture during the cooldown", func() {
+
+			s.profiler.cooldown = time.Hour
+
+			w1 := httptest.NewRecorder()
+			s.handleCaptures(w1, httptest.NewRequest(http.MethodPost, "/debug/profiling/captures", nil))
+
+			time.Sleep(300 * time.Millisecond)
+
+			w2 := httptest.NewRecorder()
+			s.handleCaptures(w2, httptest.NewRequest(http.MethodPost, "/debug/profiling/captures", nil))
+
+			Convey("Then the second capture should be rejected", func() {
+				So(w1.Code, ShouldEqual, http.StatusAccepted)
+				So(w2.Code, ShouldEqual, http.StatusTooManyRequests)
+			})
+		})
+
+		Convey("When I download a file outside of the captures", func() {
+
+			w := httptest.NewRecorder()
+			s.handleCaptureFile(w, httptest.NewRequest(http.MethodGet, "/debug/profiling/captures/../heap.pprof", nil))
+
+			Convey("Then the request should be rejected", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+
+		Convey("When I download a capture with an invalid path", func() {
+
+			w := httptest.NewRecorder()
+			s.handleCaptureFile(w, httptest.NewRequest(http.MethodGet, "/debug/profiling/captures/a", nil))
+
+			Convey("Then the request should be rejected", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+}
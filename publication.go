@@ -12,6 +12,7 @@
 package bahamut
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -19,6 +20,10 @@ import (
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/log"
 	"go.aporeto.io/elemental"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ResponseMode represents the response that is expected to be produced by the subscriber
@@ -66,6 +71,7 @@ type Publication struct {
 	timedOut bool
 	mux      sync.Mutex
 	span     opentracing.Span
+	otelSpan trace.Span
 }
 
 // NewPublication returns a new Publication.
@@ -97,6 +103,10 @@ func (p *Publication) EncodeWithEncoding(o interface{}, encoding elemental.Encod
 		p.span.LogFields(log.Object("payload", string(p.Data)))
 	}
 
+	if p.otelSpan != nil {
+		p.otelSpan.AddEvent("payload", trace.WithAttributes(attribute.String("payload", string(p.Data))))
+	}
+
 	return nil
 }
 
@@ -107,6 +117,10 @@ func (p *Publication) Decode(dest interface{}) error {
 		p.span.LogFields(log.Object("payload", string(p.Data)))
 	}
 
+	if p.otelSpan != nil {
+		p.otelSpan.AddEvent("payload", trace.WithAttributes(attribute.String("payload", string(p.Data))))
+	}
+
 	return elemental.Decode(p.Encoding, p.Data, dest)
 }
 
@@ -146,6 +160,80 @@ func (p *Publication) Span() opentracing.Span {
 	return p.span
 }
 
+// StartOTelTracingFromContext starts a new child OpenTelemetry span of the
+// span held by the given context and injects its W3C traceparent into
+// the TrackingData. It does nothing if the context holds no valid span.
+func (p *Publication) StartOTelTracingFromContext(ctx context.Context, name string) context.Context {
+
+	parent := trace.SpanFromContext(ctx)
+	if !parent.SpanContext().IsValid() {
+		return ctx
+	}
+
+	ctx, p.otelSpan = parent.TracerProvider().Tracer(otelInstrumentationName).Start(
+		ctx,
+		name,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("topic", p.Topic),
+			attribute.Int("partition", int(p.Partition)),
+		),
+	)
+
+	if p.TrackingData == nil {
+		p.TrackingData = opentracing.TextMapCarrier{}
+	}
+
+	otelPropagator.Inject(ctx, propagation.MapCarrier(p.TrackingData))
+
+	return ctx
+}
+
+// StartOTelTracing starts a new OpenTelemetry span using the W3C traceparent
+// carried by the TrackingData, if any, and returns a context holding it.
+func (p *Publication) StartOTelTracing(ctx context.Context, tp trace.TracerProvider, name string) context.Context {
+
+	if tp == nil {
+		return ctx
+	}
+
+	ctx = otelPropagator.Extract(ctx, propagation.MapCarrier(p.TrackingData))
+
+	ctx, p.otelSpan = tp.Tracer(otelInstrumentationName).Start(
+		ctx,
+		name,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("topic", p.Topic),
+			attribute.Int("partition", int(p.Partition)),
+		),
+	)
+
+	return ctx
+}
+
+// OTelSpan returns the current OpenTelemetry span.
+func (p *Publication) OTelSpan() trace.Span {
+
+	return p.otelSpan
+}
+
+// finishOTelTracing ends the current OpenTelemetry
+// span, recording the given error if any.
+func (p *Publication) finishOTelTracing(err error) {
+
+	if p.otelSpan == nil {
+		return
+	}
+
+	if err != nil {
+		p.otelSpan.RecordError(err)
+		p.otelSpan.SetStatus(codes.Error, err.Error())
+	}
+
+	p.otelSpan.End()
+}
+
 // Duplicate returns a copy of the publication
 func (p *Publication) Duplicate() *Publication {
 
@@ -157,6 +245,7 @@ func (p *Publication) Duplicate() *Publication {
 	pub.Encoding = p.Encoding
 	pub.ResponseMode = p.ResponseMode
 	pub.span = p.span
+	pub.otelSpan = p.otelSpan
 
 	return pub
 }
@@ -12,12 +12,14 @@
 package bahamut
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"go.aporeto.io/elemental"
 	testmodel "go.aporeto.io/elemental/test/model"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestPublication_NewPublication(t *testing.T) {
@@ -193,6 +195,70 @@ func TestPublicationTracing(t *testing.T) {
 	})
 }
 
+func TestPublicationOTelTracing(t *testing.T) {
+
+	Convey("Given I have a context with no span", t, func() {
+
+		publication := NewPublication("topic")
+
+		Convey("When I call StartOTelTracingFromContext", func() {
+
+			publication.StartOTelTracingFromContext(context.Background(), "test")
+
+			Convey("Then no span should be started", func() {
+				So(publication.OTelSpan(), ShouldBeNil)
+				So(publication.TrackingData, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When I call StartOTelTracing with no tracer provider", func() {
+
+			publication.StartOTelTracing(context.Background(), nil, "test")
+
+			Convey("Then no span should be started", func() {
+				So(publication.OTelSpan(), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given I have a context holding a span", t, func() {
+
+		tp, exporter := newTestTracerProvider()
+		ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+		Convey("When I send a publication across a hop", func() {
+
+			publication := NewPublication("topic")
+			publication.StartOTelTracingFromContext(ctx, "publish")
+			So(publication.Encode("hello"), ShouldBeNil)
+			publication.finishOTelTracing(nil)
+			parent.End()
+
+			wired := &Publication{Topic: publication.Topic, TrackingData: publication.TrackingData, Data: publication.Data}
+			wired.StartOTelTracing(context.Background(), tp, "receive")
+			wired.finishOTelTracing(nil)
+
+			spans := exporter.GetSpans()
+
+			Convey("Then the traceparent should be in the tracking data", func() {
+				So(publication.TrackingData["traceparent"], ShouldStartWith, "00-"+parent.SpanContext().TraceID().String())
+			})
+
+			Convey("Then the spans should belong to the same trace", func() {
+				So(len(spans), ShouldEqual, 3)
+				So(spans[0].Name, ShouldEqual, "publish")
+				So(spans[0].SpanKind, ShouldEqual, trace.SpanKindProducer)
+				So(spans[0].Parent.SpanID(), ShouldEqual, parent.SpanContext().SpanID())
+				So(len(spans[0].Events), ShouldEqual, 1)
+				So(spans[2].Name, ShouldEqual, "receive")
+				So(spans[2].SpanKind, ShouldEqual, trace.SpanKindConsumer)
+				So(spans[2].Parent.SpanID(), ShouldEqual, spans[0].SpanContext.SpanID())
+				So(spans[2].SpanContext.TraceID(), ShouldEqual, parent.SpanContext().TraceID())
+			})
+		})
+	})
+}
+
 func TestPublication_Duplicate(t *testing.T) {
 
 	Convey("Given I have a publication", t, func() {
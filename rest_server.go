@@ -42,24 +42,46 @@ var (
 
 // an restServer is the structure serving the api routes.
 type restServer struct {
-	cfg             config
-	multiplexer     *bone.Mux
-	server          *http.Server
-	processorFinder processorFinderFunc
-	pusher          eventPusherFunc
-	customHandlers  retrieveHandlersFunc
+	cfg              config
+	multiplexer      *bone.Mux
+	server           *http.Server
+	processorFinder  processorFinderFunc
+	pusher           eventPusherFunc
+	customHandlers   retrieveHandlersFunc
+	latencyObservers []latencyObserver
+	accessLogger     *accessLogger
+	measureAPI       func(*elemental.Request) FinishAPIMeasurementFunc
 }
 
 // newRestServer returns a new apiServer.
 func newRestServer(cfg config, multiplexer *bone.Mux, processorFinder processorFinderFunc, customHandlers retrieveHandlersFunc, pusher eventPusherFunc) *restServer {
 
-	return &restServer{
+	srv := &restServer{
 		cfg:             cfg,
 		multiplexer:     multiplexer,
 		processorFinder: processorFinder,
 		pusher:          pusher,
 		customHandlers:  customHandlers,
 	}
+
+	if cfg.accessLog.enabled {
+		srv.accessLogger = newAccessLogger(cfg)
+	}
+
+	if m, ok := cfg.healthServer.metricsManager.(APIMetricsManager); ok {
+		srv.measureAPI = m.MeasureAPIRequest
+	}
+
+	// The profiling triggers may need the latency of the requests.
+	if cfg.profilingServer.enabled && cfg.profilingServer.capture.enabled {
+		for _, t := range cfg.profilingServer.capture.triggers {
+			if o, ok := t.(latencyObserver); ok {
+				srv.latencyObservers = append(srv.latencyObservers, o)
+			}
+		}
+	}
+
+	return srv
 }
 
 // createSecureHTTPServer returns the main HTTP Server.
@@ -260,7 +282,12 @@ func (a *restServer) start(ctx context.Context, routesInfo map[int][]RouteInfo)
 
 func (a *restServer) stop() context.Context {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	timeout := 60 * time.Second
+	if a.cfg.drain.enabled {
+		timeout = a.cfg.drain.timeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 
 	go func() {
 		defer cancel()
@@ -278,6 +305,8 @@ func (a *restServer) makeHandler(handler handlerFunc) http.HandlerFunc {
 
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 
+		start := time.Now()
+
 		var measure FinishMeasurementFunc
 		if a.cfg.healthServer.metricsManager != nil {
 			measure = a.cfg.healthServer.metricsManager.MeasureRequest(req.Method, req.URL.Path)
@@ -320,15 +349,25 @@ func (a *restServer) makeHandler(handler handlerFunc) http.HandlerFunc {
 		}
 
 		ctx := traceRequest(req.Context(), request, a.cfg.opentracing.tracer, a.cfg.opentracing.excludedIdentities, a.cfg.opentracing.traceCleaner)
+		ctx = traceRequestOTel(ctx, request, a.cfg.opentelemetry.tracerProvider, a.cfg.opentracing.excludedIdentities, a.cfg.opentracing.traceCleaner)
 		defer finishTracing(ctx)
 
+		var measureAPI FinishAPIMeasurementFunc
+		if a.measureAPI != nil {
+			measureAPI = a.measureAPI(request)
+		}
+
 		// Global rate limiting
 		if a.cfg.rateLimiting.rateLimiter != nil {
 			if !a.cfg.rateLimiting.rateLimiter.Allow() {
-				code := writeHTTPResponse(w, makeErrorResponse(ctx, elemental.NewResponse(request), ErrRateLimit, nil, nil))
+				resp := makeErrorResponse(ctx, elemental.NewResponse(request), ErrRateLimit, nil, nil)
+				code := writeHTTPResponse(w, resp)
 				if measure != nil {
 					measure(code, opentracing.SpanFromContext(ctx))
 				}
+				if measureAPI != nil {
+					measureAPI(code, FailureStageRateLimit, responseSize(resp))
+				}
 				return
 			}
 		}
@@ -338,10 +377,14 @@ func (a *restServer) makeHandler(handler handlerFunc) http.HandlerFunc {
 			if rlm, ok := a.cfg.rateLimiting.apiRateLimiters[request.Identity]; ok {
 				if rlm.condition == nil || rlm.condition(request) {
 					if !rlm.limiter.Allow() {
-						code := writeHTTPResponse(w, makeErrorResponse(ctx, elemental.NewResponse(request), ErrRateLimit, nil, nil))
+						resp := makeErrorResponse(ctx, elemental.NewResponse(request), ErrRateLimit, nil, nil)
+						code := writeHTTPResponse(w, resp)
 						if measure != nil {
 							measure(code, opentracing.SpanFromContext(ctx))
 						}
+						if measureAPI != nil {
+							measureAPI(code, FailureStageRateLimit, responseSize(resp))
+						}
 						return
 					}
 				}
@@ -350,18 +393,37 @@ func (a *restServer) makeHandler(handler handlerFunc) http.HandlerFunc {
 
 		bctx := newContext(ctx, request)
 		resp := handler(bctx, a.cfg, a.processorFinder, a.pusher)
-		var code int
+		var code, size int
+
+		if a.cfg.security.authDebugClaim != "" {
+			setAuthDebugHeader(w, bctx, a.cfg.security.authDebugClaim)
+		}
 
 		switch {
 		case bctx.responseWriter != nil:
 			code = bctx.responseWriter(w)
 		default:
 			code = writeHTTPResponse(w, resp)
+			size = responseSize(resp)
 		}
 
 		if measure != nil {
 			measure(code, opentracing.SpanFromContext(ctx))
 		}
+
+		if measureAPI != nil {
+			measureAPI(code, bctx.failureStage, size)
+		}
+
+		latency := time.Since(start)
+
+		for _, o := range a.latencyObservers {
+			o.observeLatency(latency)
+		}
+
+		if a.accessLogger != nil {
+			a.accessLogger.log(req.Method, req.URL.Path, bctx, code, size, latency)
+		}
 	})
 
 	if a.cfg.restServer.disableCompression {
@@ -12,6 +12,7 @@
 package bahamut
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -87,6 +88,17 @@ func writeHTTPResponse(w http.ResponseWriter, r *elemental.Response) int {
 	return r.StatusCode
 }
 
+// responseSize returns the size of the body of the given response,
+// or 0 if the response is nil.
+func responseSize(r *elemental.Response) int {
+
+	if r == nil {
+		return 0
+	}
+
+	return len(r.Data)
+}
+
 // If the first one is "v" it means the next one has to be a int for the version number.
 func extractAPIVersion(path string) (version int, err error) {
 
@@ -100,3 +112,15 @@ func extractAPIVersion(path string) (version int, err error) {
 
 	return version, nil
 }
+
+// writeJSON writes the given object as JSON,
+// using the given status code.
+func writeJSON(w http.ResponseWriter, code int, o interface{}) {
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(o); err != nil {
+		zap.L().Debug("Unable to send json response to client", zap.Error(err))
+	}
+}
@@ -221,6 +221,31 @@ func TestRestServerHelper_writeHTTPResponse(t *testing.T) {
 	})
 }
 
+func TestRestServerHelper_writeJSON(t *testing.T) {
+
+	Convey("Given I have a health report", t, func() {
+
+		r := HealthReport{
+			Status: PingStatusError,
+			Checks: map[string]HealthCheckResult{
+				"p1": {Status: PingStatusError, LastError: "boom"},
+			},
+		}
+
+		Convey("When I write it", func() {
+
+			w := httptest.NewRecorder()
+			writeJSON(w, 503, r)
+
+			Convey("Then the response should be correct", func() {
+				So(w.Code, ShouldEqual, 503)
+				So(w.Header().Get("Content-Type"), ShouldEqual, "application/json; charset=UTF-8")
+				So(w.Body.String(), ShouldEqual, `{"status":"error","ready":false,"checks":{"p1":{"status":"error","lastError":"boom"}}}`+"\n")
+			})
+		})
+	})
+}
+
 func Test_extractAPIVersion(t *testing.T) {
 	type args struct {
 		path string
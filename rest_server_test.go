@@ -316,6 +316,15 @@ func (m *mockMetricsManager) RegisterTCPConnection()                       {}
 func (m *mockMetricsManager) UnregisterTCPConnection()                     {}
 func (m *mockMetricsManager) Write(w http.ResponseWriter, r *http.Request) {}
 
+type mockAPIMetricsManager struct {
+	mockMetricsManager
+	measureAPIFunc FinishAPIMeasurementFunc
+}
+
+func (m *mockAPIMetricsManager) MeasureAPIRequest(request *elemental.Request) FinishAPIMeasurementFunc {
+	return m.measureAPIFunc
+}
+
 func TestServer_MakeHandlers(t *testing.T) {
 
 	Convey("Given I have some config", t, func() {
@@ -326,10 +335,19 @@ func TestServer_MakeHandlers(t *testing.T) {
 		}
 
 		var measuredCode int
+		var measuredAPICode int
+		var measuredStage FailureStage
 		cfg := config{}
 		cfg.model.modelManagers = mm
-		cfg.healthServer.metricsManager = &mockMetricsManager{
-			measureFunc: func(code int, span opentracing.Span) time.Duration { measuredCode = code; return 0 },
+		cfg.healthServer.metricsManager = &mockAPIMetricsManager{
+			mockMetricsManager: mockMetricsManager{
+				measureFunc: func(code int, span opentracing.Span) time.Duration { measuredCode = code; return 0 },
+			},
+			measureAPIFunc: func(code int, stage FailureStage, responseSize int) time.Duration {
+				measuredAPICode = code
+				measuredStage = stage
+				return 0
+			},
 		}
 
 		Convey("When I create a handler with a bad url", func() {
@@ -383,6 +401,8 @@ func TestServer_MakeHandlers(t *testing.T) {
 
 			So(w.Result().StatusCode, ShouldEqual, http.StatusMethodNotAllowed)
 			So(measuredCode, ShouldEqual, http.StatusMethodNotAllowed)
+			So(measuredAPICode, ShouldEqual, http.StatusMethodNotAllowed)
+			So(measuredStage, ShouldEqual, FailureStageNone)
 		})
 
 		Convey("When I create a handler with global rate limiters", func() {
@@ -402,6 +422,8 @@ func TestServer_MakeHandlers(t *testing.T) {
 
 			So(w.Result().StatusCode, ShouldEqual, http.StatusTooManyRequests)
 			So(measuredCode, ShouldEqual, http.StatusTooManyRequests)
+			So(measuredAPICode, ShouldEqual, http.StatusTooManyRequests)
+			So(measuredStage, ShouldEqual, FailureStageRateLimit)
 		})
 
 		Convey("When I create a handler with per api rate limiters", func() {
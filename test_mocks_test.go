@@ -12,6 +12,7 @@
 package bahamut
 
 import (
+	"context"
 	"net/http"
 	"sync"
 	"time"
@@ -171,7 +172,7 @@ type mockPusher struct {
 	sync.Mutex
 }
 
-func (f *mockPusher) Push(evt ...*elemental.Event) {
+func (f *mockPusher) Push(ctx context.Context, evt ...*elemental.Event) {
 
 	f.Lock()
 	defer f.Unlock()
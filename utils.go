@@ -22,6 +22,9 @@ import (
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/log"
 	"go.aporeto.io/elemental"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func handleRecoveredPanic(ctx context.Context, r interface{}, disablePanicRecovery bool) error {
@@ -47,10 +50,23 @@ func handleRecoveredPanic(ctx context.Context, r interface{}, disablePanicRecove
 		)
 	}
 
+	otelSp := otelSpanFromContext(ctx)
+	if otelSp != nil {
+		otelSp.SetAttributes(attribute.Bool("panic", true))
+		otelSp.AddEvent("panic", trace.WithAttributes(
+			attribute.String("panic", fmt.Sprintf("%v", r)),
+			attribute.String("stack", st),
+		))
+		otelSp.SetStatus(codes.Error, fmt.Sprintf("panic: %v", r))
+	}
+
 	if disablePanicRecovery {
 		if sp != nil {
 			sp.Finish()
 		}
+		if otelSp != nil {
+			otelSp.End()
+		}
 		panic(err)
 	}
 
@@ -67,11 +83,28 @@ func extractSpanID(span opentracing.Span) string {
 	return spanID
 }
 
+// extractTraceID returns the ID of the opentracing span held by
+// the given context or, if there is none, the trace ID of its
+// OpenTelemetry span.
+func extractTraceID(ctx context.Context) string {
+
+	span := opentracing.SpanFromContext(ctx)
+
+	if otelSpan := otelSpanFromContext(ctx); span == nil && otelSpan != nil {
+		return otelSpan.SpanContext().TraceID().String()
+	}
+
+	return extractSpanID(span)
+}
+
 func processError(ctx context.Context, err error) (outError elemental.Errors) {
 
 	span := opentracing.SpanFromContext(ctx)
+	otelSpan := otelSpanFromContext(ctx)
+
+	outError = elemental.NewErrors(err).Trace(extractTraceID(ctx))
 
-	outError = elemental.NewErrors(err).Trace(extractSpanID(span))
+	recordOTelError(otelSpan, outError)
 
 	if span != nil {
 		span.SetTag("error", true)
@@ -35,8 +35,16 @@ const (
 
 type unregisterFunc func(*wsPushSession)
 
+// A wsPushMessage is a message waiting
+// to be written to the websocket.
+type wsPushMessage struct {
+	data      []byte
+	identity  string
+	timestamp time.Time
+}
+
 type wsPushSession struct {
-	dataCh                chan []byte
+	dataCh                chan wsPushMessage
 	pushConfig            *elemental.PushConfig
 	currentPushConfigLock sync.RWMutex
 	parametersLock        sync.RWMutex
@@ -51,6 +59,7 @@ type wsPushSession struct {
 	parameters            url.Values
 	remoteAddr            string
 	conn                  wsc.Websocket
+	rawConn               *websocket.Conn
 	startTime             time.Time
 	unregister            unregisterFunc
 	tlsConnectionState    *tls.ConnectionState
@@ -60,6 +69,7 @@ type wsPushSession struct {
 	encodingRead          elemental.EncodingType
 	encodingWrite         elemental.EncodingType
 	cookies               []*http.Cookie
+	metrics               PushMetricsManager
 }
 
 func newWSPushSession(
@@ -72,9 +82,10 @@ func newWSPushSession(
 
 	id := uuid.Must(uuid.NewV4()).String()
 	ctx, cancel := context.WithCancel(request.Context())
+	metrics, _ := cfg.healthServer.metricsManager.(PushMetricsManager)
 
 	return &wsPushSession{
-		dataCh:             make(chan []byte, 64),
+		dataCh:             make(chan wsPushMessage, 64),
 		id:                 id,
 		claims:             []string{},
 		claimsMap:          map[string]string{},
@@ -90,6 +101,7 @@ func newWSPushSession(
 		remoteAddr:         request.RemoteAddr,
 		encodingRead:       encodingRead,
 		encodingWrite:      encodingWrite,
+		metrics:            metrics,
 	}
 }
 
@@ -103,6 +115,9 @@ func (s *wsPushSession) DirectPush(events ...*elemental.Event) {
 
 		f := s.currentPushConfig()
 		if f != nil && f.IsFilteredOut(event.Identity, event.Type) {
+			if s.metrics != nil {
+				s.metrics.RegisterEventFiltered(event.Identity, PushFilterReasonPushConfig)
+			}
 			continue
 		}
 
@@ -125,7 +140,7 @@ func (s *wsPushSession) DirectPush(events ...*elemental.Event) {
 			continue
 		}
 
-		s.send(data)
+		s.sendMessage(wsPushMessage{data: data, identity: event.Identity, timestamp: event.Timestamp})
 	}
 }
 
@@ -162,6 +177,7 @@ func (s *wsPushSession) SetMetadata(m interface{})                     { s.metad
 func (s *wsPushSession) ClientIP() string                              { return s.remoteAddr }
 func (s *wsPushSession) setRemoteAddress(addr string)                  { s.remoteAddr = addr }
 func (s *wsPushSession) setConn(conn wsc.Websocket)                    { s.conn = conn }
+func (s *wsPushSession) setRawConn(conn *websocket.Conn)               { s.rawConn = conn }
 func (s *wsPushSession) close(code int)                                { s.conn.Close(code) }
 func (s *wsPushSession) setTLSConnectionState(st *tls.ConnectionState) { s.tlsConnectionState = st }
 func (s *wsPushSession) Header(key string) string                      { return s.headers.Get(key) }
@@ -172,6 +188,27 @@ func (s *wsPushSession) Parameter(key string) string {
 	return s.parameters.Get(key)
 }
 
+// closeWithReason closes the websocket with the given code and reason.
+// As wsc.Websocket cannot send a close reason, the close message is
+// written to the underlying connection when it is known, which is then
+// closed so wsc.Websocket does not send a close message of its own.
+func (s *wsPushSession) closeWithReason(code int, reason string) {
+
+	if s.rawConn != nil {
+		msg := websocket.FormatCloseMessage(code, reason)
+		err := s.rawConn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		if err == nil {
+			if err = s.rawConn.Close(); err != nil {
+				zap.L().Debug("Unable to close connection", zap.String("session", s.id), zap.Error(err))
+			}
+			return
+		}
+		zap.L().Debug("Unable to send close message", zap.String("session", s.id), zap.Error(err))
+	}
+
+	s.close(code)
+}
+
 func (s *wsPushSession) inErrorState() bool {
 	s.errorStateLock.RLock()
 	defer s.errorStateLock.RUnlock()
@@ -252,13 +289,35 @@ func (s *wsPushSession) Cookie(name string) (*http.Cookie, error) {
 // additional checks.
 func (s *wsPushSession) send(data []byte) {
 
+	s.sendMessage(wsPushMessage{data: data})
+}
+
+// sendMessage queues the given message to be written
+// to the websocket, or drops it if the queue is full.
+func (s *wsPushSession) sendMessage(msg wsPushMessage) {
+
 	select {
-	case s.dataCh <- data:
+	case s.dataCh <- msg:
+		if s.metrics != nil {
+			s.metrics.ObservePushSessionQueueDepth(len(s.dataCh))
+		}
 	default:
 		zap.L().Warn("Slow consumer. event dropped",
 			zap.String("sessionID", s.id),
 			zap.Strings("claims", s.claims),
 		)
+		if s.metrics != nil {
+			s.metrics.RegisterEventDropped(msg.identity)
+		}
+	}
+}
+
+// registerClosed reports the closing of the
+// session with the given reason.
+func (s *wsPushSession) registerClosed(reason PushSessionCloseReason) {
+
+	if s.metrics != nil {
+		s.metrics.RegisterPushSessionClosed(reason)
 	}
 }
 
@@ -268,9 +327,13 @@ func (s *wsPushSession) listen() {
 
 	for {
 		select {
-		case data := <-s.dataCh:
+		case msg := <-s.dataCh:
+
+			s.conn.Write(msg.data)
 
-			s.conn.Write(data)
+			if s.metrics != nil && !msg.timestamp.IsZero() {
+				s.metrics.MeasureEventDispatchLatency(msg.identity, time.Since(msg.timestamp))
+			}
 
 		case data := <-s.conn.Read():
 
@@ -278,6 +341,7 @@ func (s *wsPushSession) listen() {
 			if err := elemental.Decode(s.encodingRead, data, pushConfig); err != nil {
 				if !s.handlesErrorEvents() {
 					s.close(websocket.CloseUnsupportedData)
+					s.registerClosed(PushSessionCloseReasonInvalidPushConfig)
 					return
 				}
 
@@ -299,6 +363,7 @@ func (s *wsPushSession) listen() {
 
 				if !s.handlesErrorEvents() {
 					s.close(websocket.CloseUnsupportedData)
+					s.registerClosed(PushSessionCloseReasonInvalidPushConfig)
 					return
 				}
 
@@ -321,10 +386,21 @@ func (s *wsPushSession) listen() {
 			zap.L().Error("Error received from websocket", zap.String("session", s.id), zap.Error(err))
 
 		case <-s.conn.Done():
+			s.registerClosed(PushSessionCloseReasonConnectionClosed)
 			return
 
 		case <-s.ctx.Done():
-			s.close(websocket.CloseGoingAway)
+			// When draining, we tell the client the server is
+			// restarting, so it can reconnect to another instance.
+			if s.cfg.drain.enabled {
+				s.closeWithReason(
+					websocket.CloseServiceRestart,
+					fmt.Sprintf("server draining, reconnect after %s", s.cfg.drain.reconnectDelay),
+				)
+			} else {
+				s.close(websocket.CloseGoingAway)
+			}
+			s.registerClosed(PushSessionCloseReasonServerShutdown)
 			return
 		}
 	}
@@ -16,8 +16,11 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -45,7 +48,7 @@ func TestWSPushSession_newPushSession(t *testing.T) {
 		s := newWSPushSession(req, conf, unregister, elemental.EncodingTypeMSGPACK, elemental.EncodingTypeMSGPACK)
 
 		Convey("Then it should be correctly initialized", func() {
-			So(s.dataCh, ShouldHaveSameTypeAs, make(chan []byte))
+			So(s.dataCh, ShouldHaveSameTypeAs, make(chan wsPushMessage))
 			So(s.Claims(), ShouldResemble, []string{})
 			So(s.claimsMap, ShouldResemble, map[string]string{})
 			So(s.cfg, ShouldResemble, conf)
@@ -82,14 +85,15 @@ func TestWSPushSession_DirectPush(t *testing.T) {
 		Convey("When I call directPush", func() {
 
 			go s.DirectPush(evt, evt)
-			data1 := <-s.dataCh
-			data2 := <-s.dataCh
+			msg1 := <-s.dataCh
+			msg2 := <-s.dataCh
 
 			Convey("Then data1 should be correct", func() {
-				So(string(data1), ShouldEqual, string(msgpack))
+				So(string(msg1.data), ShouldEqual, string(msgpack))
+				So(msg1.timestamp.Equal(evt.Timestamp), ShouldBeTrue)
 			})
 			Convey("Then data2 should be correct", func() {
-				So(string(data2), ShouldEqual, string(msgpack))
+				So(string(msg2.data), ShouldEqual, string(msgpack))
 			})
 		})
 
@@ -101,14 +105,14 @@ func TestWSPushSession_DirectPush(t *testing.T) {
 			s.setCurrentPushConfig(f)
 			go s.DirectPush(evt)
 
-			var data []byte
+			var msg wsPushMessage
 			select {
-			case data = <-s.dataCh:
+			case msg = <-s.dataCh:
 			case <-time.After(1 * time.Second):
 			}
 
 			Convey("Then data should be correct", func() {
-				So(data, ShouldBeNil)
+				So(msg.data, ShouldBeNil)
 			})
 		})
 
@@ -117,14 +121,14 @@ func TestWSPushSession_DirectPush(t *testing.T) {
 			s.startTime = time.Now().Add(1 * time.Second)
 			go s.DirectPush(evt)
 
-			var data []byte
+			var msg wsPushMessage
 			select {
-			case data = <-s.dataCh:
+			case msg = <-s.dataCh:
 			case <-time.After(1 * time.Second):
 			}
 
 			Convey("Then data should be correct", func() {
-				So(data, ShouldBeNil)
+				So(msg.data, ShouldBeNil)
 			})
 		})
 
@@ -135,14 +139,166 @@ func TestWSPushSession_DirectPush(t *testing.T) {
 
 			go s.DirectPush(evt)
 
-			var data []byte
+			var msg wsPushMessage
 			select {
-			case data = <-s.dataCh:
+			case msg = <-s.dataCh:
 			case <-time.After(1 * time.Second):
 			}
 
 			Convey("Then data should be correct", func() {
-				So(data, ShouldBeNil)
+				So(msg.data, ShouldBeNil)
+			})
+		})
+	})
+}
+
+type mockPushMetricsManager struct {
+	testMetricsManager
+	filtered    map[PushFilterReason]int
+	dropped     int
+	queueDepths []int
+	closed      []PushSessionCloseReason
+}
+
+func newMockPushMetricsManager() *mockPushMetricsManager {
+	return &mockPushMetricsManager{filtered: map[PushFilterReason]int{}}
+}
+
+func (m *mockPushMetricsManager) RegisterEventPublished(string, elemental.EventType)      {}
+func (m *mockPushMetricsManager) RegisterEventPublishFailure(string, elemental.EventType) {}
+func (m *mockPushMetricsManager) RegisterEventPublishRetry(string, elemental.EventType)   {}
+func (m *mockPushMetricsManager) RegisterPublicationReceived()                            {}
+func (m *mockPushMetricsManager) RegisterPublicationDecodeFailure()                       {}
+func (m *mockPushMetricsManager) MeasureEventDispatchLatency(string, time.Duration)       {}
+func (m *mockPushMetricsManager) RegisterEventFiltered(identity string, reason PushFilterReason) {
+	m.filtered[reason]++
+}
+func (m *mockPushMetricsManager) RegisterEventDropped(string) { m.dropped++ }
+func (m *mockPushMetricsManager) ObservePushSessionQueueDepth(depth int) {
+	m.queueDepths = append(m.queueDepths, depth)
+}
+func (m *mockPushMetricsManager) RegisterPushSessionClosed(reason PushSessionCloseReason) {
+	m.closed = append(m.closed, reason)
+}
+
+func TestWSPushSession_metrics(t *testing.T) {
+
+	Convey("Given I have a session with a push metrics manager", t, func() {
+
+		req, _ := http.NewRequest("GET", "bla", nil)
+		mm := newMockPushMetricsManager()
+		cfg := config{}
+		cfg.healthServer.metricsManager = mm
+		s := newWSPushSession(req, cfg, nil, elemental.EncodingTypeMSGPACK, elemental.EncodingTypeMSGPACK)
+
+		Convey("When I call directPush but event is filtered", func() {
+
+			f := elemental.NewPushConfig()
+			f.FilterIdentity("not-list")
+			s.setCurrentPushConfig(f)
+
+			s.DirectPush(elemental.NewEvent(elemental.EventCreate, testmodel.NewList()))
+
+			Convey("Then the filtered event should be reported", func() {
+				So(mm.filtered[PushFilterReasonPushConfig], ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I overflow the session queue", func() {
+
+			for i := 0; i < 65; i++ {
+				s.send([]byte("hello"))
+			}
+
+			Convey("Then the queue depth and the dropped event should be reported", func() {
+				So(len(mm.queueDepths), ShouldEqual, 64)
+				So(mm.queueDepths[63], ShouldEqual, 64)
+				So(mm.dropped, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When I register the closing of the session", func() {
+
+			s.registerClosed(PushSessionCloseReasonServerShutdown)
+
+			Convey("Then the closing should be reported", func() {
+				So(mm.closed, ShouldResemble, []PushSessionCloseReason{PushSessionCloseReasonServerShutdown})
+			})
+		})
+	})
+}
+
+func TestWSPushSession_closeWithReason(t *testing.T) {
+
+	Convey("Given I have a session connected to a websocket", t, func() {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, _ := http.NewRequest("GET", "bla", nil)
+		s := newWSPushSession(req, config{}, nil, elemental.EncodingTypeMSGPACK, elemental.EncodingTypeMSGPACK)
+
+		upgraded := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			conn, err := wsc.Accept(ctx, ws, wsc.Config{})
+			if err != nil {
+				return
+			}
+			s.setConn(conn)
+			s.setRawConn(ws)
+			close(upgraded)
+		}))
+		defer ts.Close()
+
+		client, _, err := websocket.DefaultDialer.Dial(strings.Replace(ts.URL, "http://", "ws://", 1), nil)
+		So(err, ShouldBeNil)
+		defer client.Close() // nolint
+
+		<-upgraded
+
+		Convey("When I close it with a reason", func() {
+
+			s.closeWithReason(websocket.CloseServiceRestart, "server draining, reconnect after 2s")
+
+			_, _, err := client.ReadMessage()
+
+			Convey("Then the client should receive the close code and the reason", func() {
+				So(websocket.IsCloseError(err, websocket.CloseServiceRestart), ShouldBeTrue)
+				So(err.(*websocket.CloseError).Text, ShouldEqual, "server draining, reconnect after 2s")
+			})
+		})
+
+		Convey("When I close it with a reason and read the raw frames", func() {
+
+			s.closeWithReason(websocket.CloseServiceRestart, "server draining, reconnect after 2s")
+
+			// The server frames are not masked and the close
+			// payloads are smaller than 126 bytes.
+			raw := client.UnderlyingConn()
+			_ = raw.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+			var closeFrames int
+			var rerr error
+			for {
+				header := make([]byte, 2)
+				if _, rerr = io.ReadFull(raw, header); rerr != nil {
+					break
+				}
+				if _, rerr = io.ReadFull(raw, make([]byte, header[1]&0x7f)); rerr != nil {
+					break
+				}
+				if int(header[0]&0x0f) == websocket.CloseMessage {
+					closeFrames++
+				}
+			}
+
+			Convey("Then the client should receive exactly one close frame", func() {
+				So(rerr, ShouldEqual, io.EOF)
+				So(closeFrames, ShouldEqual, 1)
 			})
 		})
 	})
@@ -159,10 +315,11 @@ func TestWSPushSession_send(t *testing.T) {
 		Convey("When I call directPush and pull from the event channel", func() {
 
 			s.send([]byte("hello"))
-			data := <-s.dataCh
+			msg := <-s.dataCh
 
 			Convey("Then data should be correct", func() {
-				So(string(data), ShouldEqual, "hello")
+				So(string(msg.data), ShouldEqual, "hello")
+				So(msg.timestamp.IsZero(), ShouldBeTrue)
 			})
 		})
 
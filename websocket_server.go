@@ -34,6 +34,7 @@ type pushServer struct {
 	sessionsLock    sync.RWMutex
 	mainContext     context.Context
 	publications    chan *Publication
+	metrics         PushMetricsManager
 }
 
 func newPushServer(cfg config, multiplexer *bone.Mux, processorFinder processorFinderFunc) *pushServer {
@@ -47,6 +48,8 @@ func newPushServer(cfg config, multiplexer *bone.Mux, processorFinder processorF
 		publications:    make(chan *Publication, 24000),
 	}
 
+	srv.metrics, _ = cfg.healthServer.metricsManager.(PushMetricsManager)
+
 	endpoint := cfg.pushServer.endpoint
 	if endpoint == "" {
 		endpoint = "/events"
@@ -148,7 +151,7 @@ func (n *pushServer) initPushSession(session *wsPushSession) error {
 	return nil
 }
 
-func (n *pushServer) pushEvents(events ...*elemental.Event) {
+func (n *pushServer) pushEvents(ctx context.Context, events ...*elemental.Event) {
 
 	// If we don't have a service or publication is explicitly disabled, we do nothing.
 	if n.cfg.pushServer.service == nil || !n.cfg.pushServer.enabled {
@@ -193,12 +196,18 @@ func (n *pushServer) pushEvents(events ...*elemental.Event) {
 		}
 
 		publication := NewPublication(topic)
+		publication.StartOTelTracingFromContext(ctx, "bahamut.push.publish")
+
 		if err = publication.Encode(event); err != nil {
 			zap.L().Error("Unable to encode event", zap.Error(err))
+			publication.finishOTelTracing(err)
 			break
 		}
 
 		for i := 0; i < 3; i++ {
+			if i > 0 && n.metrics != nil {
+				n.metrics.RegisterEventPublishRetry(event.Identity, event.Type)
+			}
 			err = n.cfg.pushServer.service.Publish(publication)
 			if err != nil {
 				zap.L().Warn("Unable to publish event", zap.String("topic", publication.Topic), zap.Stringer("event", event), zap.Error(err))
@@ -206,6 +215,16 @@ func (n *pushServer) pushEvents(events ...*elemental.Event) {
 			}
 			break
 		}
+
+		if n.metrics != nil {
+			if err != nil {
+				n.metrics.RegisterEventPublishFailure(event.Identity, event.Type)
+			} else {
+				n.metrics.RegisterEventPublished(event.Identity, event.Type)
+			}
+		}
+
+		publication.finishOTelTracing(err)
 	}
 }
 
@@ -215,7 +234,10 @@ func (n *pushServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 		CheckOrigin: func(r *http.Request) bool { return true },
 	}
 
-	r = r.WithContext(n.mainContext)
+	ctx := traceWSSessionOTel(n.mainContext, r, n.cfg.opentelemetry.tracerProvider)
+	defer finishTracing(ctx)
+
+	r = r.WithContext(ctx)
 
 	readEncodingType, writeEncodingType, err := elemental.EncodingFromHeaders(r.Header)
 	if err != nil {
@@ -259,6 +281,7 @@ func (n *pushServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	session.setConn(conn)
+	session.setRawConn(ws)
 
 	n.registerSession(session)
 
@@ -305,15 +328,27 @@ func (n *pushServer) start(ctx context.Context) {
 
 			go func(publication *Publication) {
 
+				if n.metrics != nil {
+					n.metrics.RegisterPublicationReceived()
+				}
+
 				event := &elemental.Event{}
 				if err := publication.Decode(event); err != nil {
 					zap.L().Error("Unable to decode event",
 						zap.Stringer("event", event),
 						zap.Error(err),
 					)
+					if n.metrics != nil {
+						n.metrics.RegisterPublicationDecodeFailure()
+					}
 					return
 				}
 
+				if _, ok := n.cfg.opentracing.excludedIdentities[event.Identity]; !ok {
+					publication.StartOTelTracing(ctx, n.cfg.opentelemetry.tracerProvider, "bahamut.push.dispatch")
+					defer publication.finishOTelTracing(nil)
+				}
+
 				// We prepare the event data in both json and msgpack
 				// once for all.
 				dataMSGPACK, dataJSON, err := prepareEventData(event)
@@ -380,6 +415,9 @@ func (n *pushServer) start(ctx context.Context) {
 						}
 
 						if !ok {
+							if n.metrics != nil {
+								n.metrics.RegisterEventFiltered(event.Identity, PushFilterReasonPushConfig)
+							}
 							continue
 						}
 					}
@@ -396,15 +434,18 @@ func (n *pushServer) start(ctx context.Context) {
 						}
 
 						if !dispatch {
+							if n.metrics != nil {
+								n.metrics.RegisterEventFiltered(event.Identity, PushFilterReasonShouldDispatch)
+							}
 							continue
 						}
 					}
 
 					switch session.encodingWrite {
 					case elemental.EncodingTypeMSGPACK:
-						session.send(dataMSGPACK)
+						session.sendMessage(wsPushMessage{data: dataMSGPACK, identity: event.Identity, timestamp: event.Timestamp})
 					case elemental.EncodingTypeJSON:
-						session.send(dataJSON)
+						session.sendMessage(wsPushMessage{data: dataJSON, identity: event.Identity, timestamp: event.Timestamp})
 					}
 				}
 			}(p)
@@ -422,7 +422,7 @@ func TestWebsocketServer_pushEvents(t *testing.T) {
 			cfg := config{}
 
 			wss := newPushServer(cfg, mux, pf)
-			wss.pushEvents(nil)
+			wss.pushEvents(context.Background(), nil)
 
 			Convey("Then nothing special should happen", func() {})
 		})
@@ -439,7 +439,7 @@ func TestWebsocketServer_pushEvents(t *testing.T) {
 
 			wss := newPushServer(cfg, mux, pf)
 			evtin := elemental.NewEvent(elemental.EventCreate, testmodel.NewList())
-			wss.pushEvents(evtin)
+			wss.pushEvents(context.Background(), evtin)
 
 			Convey("Then I should find one publication", func() {
 				evtout := elemental.NewEvent(elemental.EventCreate, testmodel.NewList())
@@ -465,7 +465,7 @@ func TestWebsocketServer_pushEvents(t *testing.T) {
 
 			wss := newPushServer(cfg, mux, pf)
 			evtin := elemental.NewEvent(elemental.EventCreate, testmodel.NewList())
-			wss.pushEvents(evtin)
+			wss.pushEvents(context.Background(), evtin)
 
 			Convey("Then I should find one publication", func() {
 				evtout := elemental.NewEvent(elemental.EventCreate, testmodel.NewList())
@@ -490,7 +490,7 @@ func TestWebsocketServer_pushEvents(t *testing.T) {
 			cfg.pushServer.publishHandler = h
 
 			wss := newPushServer(cfg, mux, pf)
-			wss.pushEvents(elemental.NewEvent(elemental.EventCreate, testmodel.NewList()))
+			wss.pushEvents(context.Background(), elemental.NewEvent(elemental.EventCreate, testmodel.NewList()))
 
 			Convey("Then I should find one publication", func() {
 				So(len(srv.publications), ShouldEqual, 0)
@@ -512,7 +512,7 @@ func TestWebsocketServer_pushEvents(t *testing.T) {
 			cfg.pushServer.publishHandler = h
 
 			wss := newPushServer(cfg, mux, pf)
-			wss.pushEvents(elemental.NewEvent(elemental.EventCreate, testmodel.NewList()))
+			wss.pushEvents(context.Background(), elemental.NewEvent(elemental.EventCreate, testmodel.NewList()))
 
 			Convey("Then I should find one publication", func() {
 				So(len(srv.publications), ShouldEqual, 0)
@@ -536,7 +536,7 @@ func TestWebsocketServer_pushEvents(t *testing.T) {
 
 			wss := newPushServer(cfg, mux, pf)
 			testEvent := elemental.NewEvent(elemental.EventCreate, testmodel.NewList())
-			wss.pushEvents(testEvent)
+			wss.pushEvents(context.Background(), testEvent)
 
 			Convey("Then I should find one publication sent to the correct topic", func() {
 